@@ -0,0 +1,21 @@
+export interface IMotionRecord {
+  mac: string;
+  zone?: string;
+  hopCount: number;
+  data: string;
+  receivedAt: string;
+}
+
+export interface IMotionBucket {
+  start: string;
+  total: number;
+  nodes: Record<string, number>;
+  zones: Record<string, number>;
+}
+
+export interface IMotionStats {
+  bucket: string;
+  from: string;
+  to: string;
+  buckets: IMotionBucket[];
+}
@@ -1,30 +1,24 @@
-const HOST_URL = "http://localhost:8080/";
+const HOST_URL = "http://localhost:8080/api/v1";
+// Viewer API key sent with every request. It is compiled into the public
+// bundle, so the dashboard only reads: never build it with a higher role.
+const API_KEY: string | undefined = import.meta.env.VITE_API_KEY;
 
 type ServiceName =
   | "getNodes"
   | "getNode"
-  | "configureNode"
-  | "configureAllNodes"
-  | "requestHealth"
   | "getStatus"
-  | "broadcastData"
-  | "startServer"
-  | "stopServer";
+  | "getMotion"
+  | "getMotionStats";
 
 const endpoints: Record<ServiceName, string | ((mac: string) => string)> = {
   // node management
   getNodes: "/nodes",
   getNode: (mac: string) => `/nodes/${mac}`,
-  configureNode: (mac: string) => `/nodes/${mac}/configure`,
-  configureAllNodes: "/nodes/configure-all",
   // health and monitoring
-  requestHealth: "/health/request",
   getStatus: "/status",
-  // Data broadcasting
-  broadcastData: "/broadcast",
-  // server control
-  startServer: "/server/start",
-  stopServer: "/server/stop",
+  // motion history
+  getMotion: "/motion",
+  getMotionStats: "/motion/stats",
 };
 
 export default async function ApiService<ApiResponse>(
@@ -32,7 +26,11 @@ export default async function ApiService<ApiResponse>(
   options?: RequestInit
 ): Promise<ApiResponse> {
   const url = `${HOST_URL}${endpoints[service]}`;
-  const response: Response = await fetch(url, options);
+  const headers = new Headers(options?.headers);
+  if (API_KEY) {
+    headers.set("X-API-Key", API_KEY);
+  }
+  const response: Response = await fetch(url, { ...options, headers });
   if (!response.ok) {
     throw new Error(
       `API error: ${response.status ?? "Unknown Error occurred"}`
@@ -88,19 +86,7 @@ export async function dev_ApiService<ApiResponse>(
         adapterType: 1,
       },
     },
-    configureNode: {
-      success: true,
-      message: "Node AA:BB:CC:DD:EE:FF configured to adapter type WiFi",
-    },
-    configureAllNodes: {
-      success: true,
-      message: "All nodes configured to adapter type Bluetooth",
-    },
     // health and monitoring
-    requestHealth: {
-      success: true,
-      message: "Health reports requested",
-    },
     getStatus: {
       success: true,
       data: {
@@ -110,19 +96,40 @@ export async function dev_ApiService<ApiResponse>(
         timestamp: 1693400100,
       },
     },
-    // Data broadcasting
-    broadcastData: {
+    // motion history
+    getMotion: {
       success: true,
-      message: "Data broadcasted to all nodes (type: WiFi, length: 128)",
-    },
-    // server control
-    startServer: {
-      success: true,
-      message: "Mesh server started",
+      data: [
+        {
+          mac: "aa:bb:cc:dd:ee:ff",
+          zone: "Hallway",
+          hopCount: 1,
+          data: "01",
+          receivedAt: "2023-08-30T13:05:00Z",
+        },
+      ],
     },
-    stopServer: {
+    getMotionStats: {
       success: true,
-      message: "Mesh server stopped",
+      data: {
+        bucket: "1h0m0s",
+        from: "2023-08-30T12:00:00Z",
+        to: "2023-08-30T13:30:00Z",
+        buckets: [
+          {
+            start: "2023-08-30T12:00:00Z",
+            total: 3,
+            nodes: { "aa:bb:cc:dd:ee:ff": 2, "11:22:33:44:55:66": 1 },
+            zones: { Hallway: 2 },
+          },
+          {
+            start: "2023-08-30T13:00:00Z",
+            total: 1,
+            nodes: { "aa:bb:cc:dd:ee:ff": 1 },
+            zones: { Hallway: 1 },
+          },
+        ],
+      },
     },
     errorResponse: {
       success: false,
@@ -135,4 +142,4 @@ export async function dev_ApiService<ApiResponse>(
 
 // Usage examples:
 // callService('service_one');
-// callService('service_two', { method: 'POST', body: JSON.stringify(data) });
+// callService('service_two', { headers: { Accept: 'application/json' } });
@@ -0,0 +1,33 @@
+package atomicfile
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "keys.json")
+	if err := os.WriteFile(path, []byte("old"), 0644); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if err := Write(path, []byte("new")); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "new" {
+		t.Errorf("Expected the file replaced, got %q (%v)", data, err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the file to be private, got %v", info.Mode())
+	}
+	if entries, _ := os.ReadDir(dir); len(entries) != 1 {
+		t.Errorf("Expected the temporary file to be gone, got %d entries", len(entries))
+	}
+
+	if err := Write(filepath.Join(dir, "missing", "keys.json"), []byte("new")); err == nil {
+		t.Error("Expected an error for a missing directory")
+	}
+}
@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Role is the access level granted to a caller
+type Role string
+
+const (
+	RolePublic   Role = ""         // No authentication required
+	RoleViewer   Role = "viewer"   // Read-only access
+	RoleOperator Role = "operator" // Viewer plus node configuration and broadcasts
+	RoleAdmin    Role = "admin"    // Operator plus server control and key management
+)
+
+var roleRank = map[Role]int{
+	RolePublic:   0,
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole parses a role name
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := roleRank[role]; !ok || role == RolePublic {
+		return "", fmt.Errorf("unknown role %q (expected viewer, operator or admin)", name)
+	}
+	return role, nil
+}
+
+// Allows reports whether the role grants at least the required role
+func (r Role) Allows(required Role) bool {
+	return roleRank[r] >= roleRank[required]
+}
+
+// Principal identifies an authenticated caller
+type Principal struct {
+	ID     string `json:"id"`
+	Name   string `json:"name"`
+	Role   Role   `json:"role"`
+	Method string `json:"method"` // "api-key", "jwt" or "none"
+}
+
+// Anonymous is the principal used when authentication is disabled
+var Anonymous = &Principal{ID: "anonymous", Name: "anonymous", Role: RoleAdmin, Method: "none"}
+
+var (
+	// ErrNoCredentials is returned when a request carries no credentials
+	ErrNoCredentials = errors.New("authentication required")
+	// ErrInvalidCredentials is returned for unknown keys and invalid tokens
+	ErrInvalidCredentials = errors.New("invalid credentials")
+)
+
+// Authenticator resolves request credentials to principals
+type Authenticator struct {
+	keys   *KeyStore
+	tokens *TokenVerifier
+}
+
+// NewAuthenticator creates an authenticator for API keys and, when tokens is
+// not nil, bearer JWTs
+func NewAuthenticator(keys *KeyStore, tokens *TokenVerifier) *Authenticator {
+	return &Authenticator{keys: keys, tokens: tokens}
+}
+
+// Keys returns the API key store
+func (a *Authenticator) Keys() *KeyStore {
+	return a.keys
+}
+
+// Authenticate reads credentials from the X-API-Key header, an
+// "Authorization: Bearer" header (API key or JWT), or, for GET requests such
+// as browser event streams that cannot set headers, the access_token query
+// parameter.
+func (a *Authenticator) Authenticate(r *http.Request) (*Principal, error) {
+	credential := r.Header.Get("X-API-Key")
+	if credential == "" {
+		if scheme, value, ok := strings.Cut(r.Header.Get("Authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			credential = strings.TrimSpace(value)
+		}
+	}
+	if credential == "" && r.Method == http.MethodGet {
+		credential = r.URL.Query().Get("access_token")
+	}
+	return a.AuthenticateCredential(credential)
+}
+
+// AuthenticateCredential resolves an API key or, when JWTs are enabled, a
+// token to a principal
+func (a *Authenticator) AuthenticateCredential(credential string) (*Principal, error) {
+	if credential == "" {
+		return nil, ErrNoCredentials
+	}
+
+	if a.tokens != nil && looksLikeJWT(credential) {
+		return a.tokens.Verify(credential)
+	}
+	if key, ok := a.keys.Lookup(credential); ok {
+		return &Principal{ID: key.ID, Name: key.Name, Role: key.Role, Method: "api-key"}, nil
+	}
+	return nil, ErrInvalidCredentials
+}
+
+// looksLikeJWT reports whether a credential has the three dot-separated
+// segments of a compact JWT; generated API keys never contain dots
+func looksLikeJWT(credential string) bool {
+	return strings.Count(credential, ".") == 2
+}
+
+type contextKey struct{}
+
+// WithPrincipal returns a context carrying the authenticated principal
+func WithPrincipal(ctx context.Context, principal *Principal) context.Context {
+	return context.WithValue(ctx, contextKey{}, principal)
+}
+
+// FromContext returns the principal stored by WithPrincipal
+func FromContext(ctx context.Context) (*Principal, bool) {
+	principal, ok := ctx.Value(contextKey{}).(*Principal)
+	return principal, ok
+}
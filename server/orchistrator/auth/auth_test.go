@@ -0,0 +1,133 @@
+package auth
+
+import (
+	"errors"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRoles(t *testing.T) {
+	if !RoleAdmin.Allows(RoleOperator) || !RoleOperator.Allows(RoleViewer) {
+		t.Error("Expected higher roles to include lower ones")
+	}
+	if RoleViewer.Allows(RoleOperator) {
+		t.Error("Expected viewer not to be allowed operator routes")
+	}
+	if role, err := ParseRole(" Operator "); err != nil || role != RoleOperator {
+		t.Errorf("Expected operator, got %q (%v)", role, err)
+	}
+	for _, name := range []string{"", "root"} {
+		if _, err := ParseRole(name); err == nil {
+			t.Errorf("Expected error for role %q", name)
+		}
+	}
+}
+
+func TestKeyStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.json")
+	keys, err := NewKeyStore(path)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	secret, created, err := keys.Create("dashboard", RoleViewer)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if created.Hash != "" {
+		t.Error("Expected key hash not to be returned")
+	}
+	keys.AddStatic("admin", RoleAdmin, "static-secret")
+
+	t.Run("PersistedWithoutStaticKeys", func(t *testing.T) {
+		reloaded, err := NewKeyStore(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if reloaded.Len() != 1 {
+			t.Fatalf("Expected 1 persisted key, got %d", reloaded.Len())
+		}
+		if key, ok := reloaded.Lookup(secret); !ok || key.ID != created.ID || key.Role != RoleViewer {
+			t.Errorf("Expected reloaded key to match, got %+v", key)
+		}
+	})
+
+	t.Run("Revoke", func(t *testing.T) {
+		if _, err := keys.Revoke("static-admin"); !errors.Is(err, ErrStaticKey) {
+			t.Errorf("Expected ErrStaticKey, got %v", err)
+		}
+		if exists, err := keys.Revoke(created.ID); !exists || err != nil {
+			t.Fatalf("Expected key to be revoked, got %v %v", exists, err)
+		}
+		if _, ok := keys.Lookup(secret); ok {
+			t.Error("Expected revoked key to be rejected")
+		}
+	})
+}
+
+func TestAuthenticate(t *testing.T) {
+	keys, _ := NewKeyStore("")
+	secret, _, _ := keys.Create("operator", RoleOperator)
+	signingKey := []byte("0123456789abcdef0123456789abcdef")
+	authenticator := NewAuthenticator(keys, NewTokenVerifier(signingKey, "mesh"))
+
+	sign := func(method jwt.SigningMethod, claims TokenClaims) string {
+		token, err := jwt.NewWithClaims(method, claims).SignedString(signingKey)
+		if err != nil {
+			t.Fatalf("Failed to sign token: %v", err)
+		}
+		return token
+	}
+	valid := TokenClaims{Role: "viewer", RegisteredClaims: jwt.RegisteredClaims{
+		Subject:   "alice",
+		Issuer:    "mesh",
+		ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+	}}
+	expired := valid
+	expired.ExpiresAt = jwt.NewNumericDate(time.Now().Add(-time.Minute))
+	wrongIssuer := valid
+	wrongIssuer.Issuer = "other"
+
+	testCases := []struct {
+		name   string
+		header string
+		value  string
+		query  string
+		role   Role
+		err    error
+	}{
+		{"APIKeyHeader", "X-API-Key", secret, "", RoleOperator, nil},
+		{"BearerAPIKey", "Authorization", "Bearer " + secret, "", RoleOperator, nil},
+		{"QueryParameter", "", "", "?access_token=" + secret, RoleOperator, nil},
+		{"JWT", "Authorization", "Bearer " + sign(jwt.SigningMethodHS256, valid), "", RoleViewer, nil},
+		{"ExpiredJWT", "Authorization", "Bearer " + sign(jwt.SigningMethodHS256, expired), "", "", ErrInvalidCredentials},
+		{"WrongIssuer", "Authorization", "Bearer " + sign(jwt.SigningMethodHS256, wrongIssuer), "", "", ErrInvalidCredentials},
+		{"WrongAlgorithm", "Authorization", "Bearer " + sign(jwt.SigningMethodHS512, valid), "", "", ErrInvalidCredentials},
+		{"UnknownKey", "X-API-Key", "mk_nope", "", "", ErrInvalidCredentials},
+		{"Missing", "", "", "", "", ErrNoCredentials},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/nodes"+tc.query, nil)
+			if tc.header != "" {
+				r.Header.Set(tc.header, tc.value)
+			}
+
+			principal, err := authenticator.Authenticate(r)
+			if tc.err != nil {
+				if !errors.Is(err, tc.err) {
+					t.Errorf("Expected %v, got %v", tc.err, err)
+				}
+				return
+			}
+			if err != nil || principal.Role != tc.role {
+				t.Errorf("Expected role %s, got %+v (%v)", tc.role, principal, err)
+			}
+		})
+	}
+}
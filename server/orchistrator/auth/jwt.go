@@ -0,0 +1,70 @@
+package auth
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// TokenClaims are the claims accepted in bearer JWTs. The subject identifies
+// the caller and role grants access; exp is required.
+type TokenClaims struct {
+	Role string `json:"role"`
+	jwt.RegisteredClaims
+}
+
+// TokenVerifier validates HS256 JWTs signed with a local key
+type TokenVerifier struct {
+	key    []byte
+	issuer string
+}
+
+// NewTokenVerifier creates a verifier for tokens signed with key. A non-empty
+// issuer must match the iss claim.
+func NewTokenVerifier(key []byte, issuer string) *TokenVerifier {
+	return &TokenVerifier{key: key, issuer: issuer}
+}
+
+// LoadTokenVerifier reads the signing key from a file
+func LoadTokenVerifier(path string, issuer string) (*TokenVerifier, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read JWT key: %w", err)
+	}
+	key := []byte(strings.TrimSpace(string(data)))
+	if len(key) < 32 {
+		return nil, fmt.Errorf("JWT key in %s is too short (need at least 32 bytes)", path)
+	}
+	return NewTokenVerifier(key, issuer), nil
+}
+
+// Verify validates a token and returns its principal
+func (v *TokenVerifier) Verify(token string) (*Principal, error) {
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithExpirationRequired(),
+	}
+	if v.issuer != "" {
+		options = append(options, jwt.WithIssuer(v.issuer))
+	}
+
+	var claims TokenClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(*jwt.Token) (interface{}, error) {
+		return v.key, nil
+	}, options...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+
+	role, err := ParseRole(claims.Role)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidCredentials, err)
+	}
+	if claims.Subject == "" {
+		return nil, fmt.Errorf("%w: token has no subject", ErrInvalidCredentials)
+	}
+
+	return &Principal{ID: claims.Subject, Name: claims.Subject, Role: role, Method: "jwt"}, nil
+}
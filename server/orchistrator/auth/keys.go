@@ -0,0 +1,227 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/superbrobenji/motionServer/atomicfile"
+)
+
+// keyPrefix marks generated API keys: mk_<id>_<secret>
+const keyPrefix = "mk_"
+
+// APIKey describes an API key. Only a SHA-256 hash of the secret is kept.
+type APIKey struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Role      Role      `json:"role"`
+	Hash      string    `json:"hash,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	Static    bool      `json:"static,omitempty"` // Configured at startup, not persisted
+}
+
+// ErrStaticKey is returned when revoking a key configured at startup
+var ErrStaticKey = errors.New("static keys cannot be revoked")
+
+// KeyStore holds API keys, optionally persisted to a JSON file
+type KeyStore struct {
+	mu     sync.RWMutex
+	path   string
+	keys   map[string]*APIKey
+	byHash map[string]*APIKey
+}
+
+// NewKeyStore creates a key store backed by path, loading existing keys.
+// An empty path keeps keys in memory only.
+func NewKeyStore(path string) (*KeyStore, error) {
+	ks := &KeyStore{
+		path:   path,
+		keys:   make(map[string]*APIKey),
+		byHash: make(map[string]*APIKey),
+	}
+	if path == "" {
+		return ks, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ks, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read API keys: %w", err)
+	}
+
+	var keys []*APIKey
+	if err := json.Unmarshal(data, &keys); err != nil {
+		return nil, fmt.Errorf("invalid API key file %s: %w", path, err)
+	}
+	for _, key := range keys {
+		if _, err := ParseRole(string(key.Role)); err != nil {
+			return nil, fmt.Errorf("API key %s: %w", key.ID, err)
+		}
+		ks.keys[key.ID] = key
+		ks.byHash[key.Hash] = key
+	}
+	return ks, nil
+}
+
+// Create generates a new key and returns it with its secret. The secret
+// cannot be recovered later.
+func (ks *KeyStore) Create(name string, role Role) (string, APIKey, error) {
+	id, err := randomHex(4)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return "", APIKey{}, err
+	}
+	token := keyPrefix + id + "_" + secret
+
+	key := &APIKey{
+		ID:        id,
+		Name:      name,
+		Role:      role,
+		Hash:      hashKey(token),
+		CreatedAt: time.Now().UTC(),
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	ks.keys[key.ID] = key
+	ks.byHash[key.Hash] = key
+	if err := ks.save(); err != nil {
+		delete(ks.keys, key.ID)
+		delete(ks.byHash, key.Hash)
+		return "", APIKey{}, err
+	}
+	return token, key.public(), nil
+}
+
+// AddStatic registers a key given on the command line. Static keys are not
+// written to the key file.
+func (ks *KeyStore) AddStatic(name string, role Role, token string) {
+	key := &APIKey{
+		ID:        "static-" + name,
+		Name:      name,
+		Role:      role,
+		Hash:      hashKey(token),
+		CreatedAt: time.Now().UTC(),
+		Static:    true,
+	}
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	ks.keys[key.ID] = key
+	ks.byHash[key.Hash] = key
+}
+
+// Lookup returns the key matching a presented secret
+func (ks *KeyStore) Lookup(token string) (APIKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	key, ok := ks.byHash[hashKey(token)]
+	if !ok {
+		return APIKey{}, false
+	}
+	return key.public(), true
+}
+
+// List returns all keys without their hashes, ordered by creation time
+func (ks *KeyStore) List() []APIKey {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+
+	keys := make([]APIKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		keys = append(keys, key.public())
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys
+}
+
+// Revoke deletes a key, reporting whether it existed
+func (ks *KeyStore) Revoke(id string) (bool, error) {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	key, ok := ks.keys[id]
+	if !ok {
+		return false, nil
+	}
+	if key.Static {
+		return true, ErrStaticKey
+	}
+
+	delete(ks.keys, id)
+	delete(ks.byHash, key.Hash)
+	if err := ks.save(); err != nil {
+		ks.keys[id] = key
+		ks.byHash[key.Hash] = key
+		return true, err
+	}
+	return true, nil
+}
+
+// Len returns the number of keys
+func (ks *KeyStore) Len() int {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	return len(ks.keys)
+}
+
+// save writes every key but the static ones to the key file; callers hold
+// the write lock
+func (ks *KeyStore) save() error {
+	if ks.path == "" {
+		return nil
+	}
+
+	keys := make([]*APIKey, 0, len(ks.keys))
+	for _, key := range ks.keys {
+		if !key.Static {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(keys, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := atomicfile.Write(ks.path, data); err != nil {
+		return fmt.Errorf("failed to write API keys: %w", err)
+	}
+	return nil
+}
+
+// public returns a copy of the key without its hash
+func (key *APIKey) public() APIKey {
+	public := *key
+	public.Hash = ""
+	return public
+}
+
+func hashKey(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate key: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,35 @@
+package eventstore
+
+import (
+	"encoding/base64"
+	"strings"
+)
+
+// ContentTypeWriter is implemented by stores that record a content type with
+// each message (e.g. as a Kafka header)
+type ContentTypeWriter interface {
+	WriteMessageWithContentType(event string, topic string, contentType string) error
+}
+
+// WriteWithContentType writes an event with its content type when the store
+// supports it, and falls back to WriteMessage otherwise
+func WriteWithContentType(store EventStore_interface, event string, topic string, contentType string) error {
+	if writer, ok := store.(ContentTypeWriter); ok {
+		return writer.WriteMessageWithContentType(event, topic, contentType)
+	}
+	return store.WriteMessage(event, topic)
+}
+
+// isJSONContentType reports whether a content type carries JSON text
+func isJSONContentType(contentType string) bool {
+	return contentType == "" || strings.HasSuffix(contentType, "json")
+}
+
+// textValue returns a JSON-safe form of an event value: JSON events are kept
+// as-is and anything else is base64 encoded
+func textValue(event string, contentType string) (value string, encoding string) {
+	if isJSONContentType(contentType) {
+		return event, ""
+	}
+	return base64.StdEncoding.EncodeToString([]byte(event)), "base64"
+}
@@ -1,7 +1,18 @@
 package eventstore
 
+import (
+	"context"
+	"errors"
+)
+
 type EventStore_interface interface {
 	Connect() error
 	WriteMessage(event string, topic string) error
-	SubscribeToEvents(topic string) error
+	// SubscribeToEvents delivers messages from topics to handler until ctx is
+	// cancelled, then returns nil
+	SubscribeToEvents(ctx context.Context, topics []string, handler Handler) error
+	Close() error
 }
+
+// ErrSubscribeUnsupported is returned by stores that can only be written to
+var ErrSubscribeUnsupported = errors.New("event store does not support subscriptions")
@@ -0,0 +1,283 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestMemoryStore(t *testing.T) {
+	store := NewMemory(3)
+
+	for _, event := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`} {
+		if err := store.WriteMessage(event, "motion-trigger"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+	store.WriteMessage(`{"n":5}`, "mesh-messages")
+
+	q, ok := FindQueryable(NewFanout(NewStdout(&bytes.Buffer{}), store))
+	if !ok {
+		t.Fatal("Expected fan-out to expose the memory store")
+	}
+
+	t.Run("OldestEventsEvicted", func(t *testing.T) {
+		events := q.Query("", 0, 0)
+		if len(events) != 3 {
+			t.Fatalf("Expected 3 retained events, got %d", len(events))
+		}
+		if events[0].Offset != 3 || events[2].Offset != 5 {
+			t.Errorf("Expected offsets 3..5, got %d..%d", events[0].Offset, events[2].Offset)
+		}
+	})
+
+	t.Run("FilterByTopicAndOffset", func(t *testing.T) {
+		events := q.Query("motion-trigger", 3, 10)
+		if len(events) != 1 || events[0].Value != `{"n":4}` {
+			t.Errorf("Expected only event 4, got %+v", events)
+		}
+	})
+}
+
+func TestFileStoreRotation(t *testing.T) {
+	dir := t.TempDir()
+	store := NewFile(dir, 64, 2)
+	if err := store.Connect(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer store.Close()
+
+	event := `{"type": "pir_motion", "mac": "aa:bb:cc:dd:ee:ff"}`
+	for i := 0; i < 6; i++ {
+		if err := store.WriteMessage(event, "motion-trigger"); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	if err := store.WriteMessage("not json", "motion-trigger"); err == nil {
+		t.Error("Expected invalid JSON to be rejected")
+	}
+	if err := store.WriteMessage(event, "../escape"); err == nil {
+		t.Error("Expected path-like topic to be rejected")
+	}
+
+	rotated, _ := filepath.Glob(filepath.Join(dir, "motion-trigger.*.jsonl"))
+	if len(rotated) != 2 {
+		t.Errorf("Expected 2 rotated files, got %d", len(rotated))
+	}
+
+	file, err := os.Open(filepath.Join(dir, "motion-trigger.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected active file, got %v", err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), " ") {
+			t.Errorf("Expected compacted JSON line, got %q", scanner.Text())
+		}
+	}
+}
+
+func TestOpen(t *testing.T) {
+	testCases := []struct {
+		spec     string
+		hasError bool
+	}{
+		{"kafka://kafka:9092", false},
+		{"kafka://kafka:9092,file:///var/lib/mesh/events", false},
+		{"stdout://, memory://?size=50", false},
+		{"memory://?size=abc", true},
+		{"kafka://", true},
+		{"ftp://example", true},
+		{"", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.spec, func(t *testing.T) {
+			store, err := Open(tc.spec, "1")
+			if tc.hasError {
+				if err == nil {
+					t.Errorf("Expected error for spec %q", tc.spec)
+				}
+				return
+			}
+			if err != nil || store == nil {
+				t.Errorf("Unexpected error for spec %q: %v", tc.spec, err)
+			}
+		})
+	}
+}
+
+func TestFanoutConnect(t *testing.T) {
+	blocker := filepath.Join(t.TempDir(), "blocker")
+	if err := os.WriteFile(blocker, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+	memory := NewMemory(10)
+	store := NewFanout(memory, NewFile(filepath.Join(blocker, "events"), 0, 0))
+
+	for i := 0; i < 2; i++ {
+		if err := store.Connect(); err == nil {
+			t.Fatal("Expected the file sink under a regular file to fail")
+		}
+	}
+
+	kept, failures := DropUnconnected(store, store.Connect())
+	if kept != memory || len(failures) != 1 {
+		t.Fatalf("Expected only the memory sink kept and one failure, got %T and %v", kept, failures)
+	}
+	if err := kept.WriteMessage(`{"n":1}`, "motion-trigger"); err != nil {
+		t.Errorf("Expected the kept sink to accept writes, got %v", err)
+	}
+
+	if kept, failures := DropUnconnected(NewMemory(1), errors.New("down")); kept != nil || len(failures) != 1 {
+		t.Errorf("Expected a failed single sink to be dropped, got %T and %v", kept, failures)
+	}
+}
+
+func TestMemorySubscription(t *testing.T) {
+	store := NewMemory(10).(*memoryStore)
+	store.retry = RetryPolicy{MaxRetries: 1, Backoff: time.Millisecond, DeadLetter: true}
+	subscribed := make(chan struct{})
+	var once sync.Once
+
+	ctx, cancel := context.WithCancel(context.Background())
+	received := make(chan Message, 10)
+	attempts := 0
+
+	done := make(chan error, 1)
+	go func() {
+		done <- store.SubscribeToEvents(ctx, []string{"motion-trigger", "probe"}, func(ctx context.Context, msg Message) error {
+			if msg.Topic == "probe" {
+				once.Do(func() { close(subscribed) })
+				return nil
+			}
+			if string(msg.Value) == `{"poison":true}` {
+				attempts++
+				return errors.New("cannot handle")
+			}
+			received <- msg
+			return nil
+		})
+	}()
+
+	// Only events written after the subscription starts are delivered, so
+	// probe until one arrives
+	for probing := true; probing; {
+		store.WriteMessage(`{}`, "probe")
+		select {
+		case <-subscribed:
+			probing = false
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	store.WriteMessage(`{"poison":true}`, "motion-trigger")
+	store.WriteMessage(`{"n":1}`, "mesh-messages")
+	store.WriteMessageWithContentType("\x08\x01", "motion-trigger", "application/cloudevents+protobuf")
+
+	select {
+	case msg := <-received:
+		if string(msg.Value) != "\x08\x01" || msg.ContentType != "application/cloudevents+protobuf" {
+			t.Errorf("Unexpected message: %+v", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Timed out waiting for message")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Expected clean shutdown, got %v", err)
+	}
+
+	if attempts != 2 {
+		t.Errorf("Expected 2 attempts for the failing message, got %d", attempts)
+	}
+	if dlq := store.Query(DeadLetterTopic("motion-trigger"), 0, 0); len(dlq) != 1 {
+		t.Errorf("Expected failing message to be dead-lettered, got %d", len(dlq))
+	}
+}
+
+func TestReplay(t *testing.T) {
+	t.Run("ParseReplayFrom", func(t *testing.T) {
+		from, err := ParseReplayFrom("offset:42")
+		if err != nil || from.Offset != 42 {
+			t.Errorf("Expected offset 42, got %+v (%v)", from, err)
+		}
+		from, err = ParseReplayFrom("2024-01-01T12:00:00Z")
+		if err != nil || !from.Time.Equal(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)) {
+			t.Errorf("Expected RFC3339 time, got %+v (%v)", from, err)
+		}
+		from, err = ParseReplayFrom("1h")
+		if err != nil || time.Since(from.Time) < time.Hour {
+			t.Errorf("Expected time an hour ago, got %+v (%v)", from, err)
+		}
+		for _, spec := range []string{"offset:-1", "yesterday", "-1h"} {
+			if _, err := ParseReplayFrom(spec); err == nil {
+				t.Errorf("Expected error for %q", spec)
+			}
+		}
+	})
+
+	replay := func(t *testing.T, store EventStore_interface, from ReplayFrom) []string {
+		r, ok := FindReplayer(store)
+		if !ok {
+			t.Fatal("Expected store to support replay")
+		}
+		var values []string
+		err := r.Replay(context.Background(), "mesh-messages", from, func(ctx context.Context, msg Message) error {
+			values = append(values, string(msg.Value))
+			return nil
+		})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return values
+	}
+
+	t.Run("Memory", func(t *testing.T) {
+		store := NewMemory(10)
+		store.WriteMessage(`{"n":1}`, "mesh-messages")
+		store.WriteMessage(`{"n":2}`, "motion-trigger")
+		store.WriteMessage(`{"n":3}`, "mesh-messages")
+
+		if values := replay(t, NewFanout(NewStdout(&bytes.Buffer{}), store), ReplayFrom{}); len(values) != 2 {
+			t.Errorf("Expected 2 events, got %v", values)
+		}
+		if values := replay(t, store, ReplayFrom{Offset: 2}); len(values) != 1 || values[0] != `{"n":3}` {
+			t.Errorf("Expected only event 3, got %v", values)
+		}
+	})
+
+	t.Run("FileAcrossRotation", func(t *testing.T) {
+		store := NewFile(t.TempDir(), 32, 5)
+		if err := store.Connect(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer store.Close()
+
+		for _, event := range []string{`{"n":1}`, `{"n":2}`, `{"n":3}`, `{"n":4}`, `{"n":5}`} {
+			store.WriteMessage(event, "mesh-messages")
+		}
+		WriteWithContentType(store, "\x08\x01", "mesh-messages", "application/cloudevents+protobuf")
+
+		values := replay(t, store, ReplayFrom{})
+		if len(values) != 6 || values[0] != `{"n":1}` || values[5] != "\x08\x01" {
+			t.Errorf("Expected all 6 events in order, got %q", values)
+		}
+		err := store.(Replayer).Replay(context.Background(), "mesh-messages", ReplayFrom{Offset: 4}, func(ctx context.Context, msg Message) error {
+			return nil
+		})
+		if !errors.Is(err, ErrReplayOffsetUnsupported) {
+			t.Errorf("Expected offsets to be rejected since they shift on rotation, got %v", err)
+		}
+	})
+}
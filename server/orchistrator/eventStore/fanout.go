@@ -0,0 +1,126 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+)
+
+// fanoutStore writes every event to several stores
+type fanoutStore struct {
+	stores    []EventStore_interface
+	connected []bool  // Stores whose Connect succeeded
+	errs      []error // Last Connect error of each store that has not connected
+}
+
+// NewFanout creates an event store that forwards writes to all given stores
+func NewFanout(stores ...EventStore_interface) EventStore_interface {
+	return &fanoutStore{stores: stores, connected: make([]bool, len(stores)), errs: make([]error, len(stores))}
+}
+
+// Connect connects the stores that are not connected yet, so a retry after a
+// partial failure leaves the connected ones alone
+func (store *fanoutStore) Connect() error {
+	for i, s := range store.stores {
+		if store.connected[i] {
+			continue
+		}
+		store.errs[i] = s.Connect()
+		store.connected[i] = store.errs[i] == nil
+	}
+	return errors.Join(store.errs...)
+}
+
+// DropUnconnected removes the stores of a fan-out that have not connected,
+// closing them and returning their errors, and returns nil if none is left.
+// Any other store is kept unless connectErr, its Connect error, is set.
+func DropUnconnected(store EventStore_interface, connectErr error) (EventStore_interface, []error) {
+	fanout, ok := store.(*fanoutStore)
+	if !ok {
+		if connectErr != nil {
+			store.Close()
+			return nil, []error{connectErr}
+		}
+		return store, nil
+	}
+
+	var kept []EventStore_interface
+	var errs []error
+	for i, s := range fanout.stores {
+		if fanout.connected[i] {
+			kept = append(kept, s)
+			continue
+		}
+		errs = append(errs, fanout.errs[i])
+		s.Close()
+	}
+
+	switch len(kept) {
+	case 0:
+		return nil, errs
+	case 1:
+		return kept[0], errs
+	default:
+		pruned := &fanoutStore{stores: kept, connected: make([]bool, len(kept)), errs: make([]error, len(kept))}
+		for i := range pruned.connected {
+			pruned.connected[i] = true
+		}
+		return pruned, errs
+	}
+}
+
+// WriteMessage writes to every store, even if some of them fail
+func (store *fanoutStore) WriteMessage(event string, topic string) error {
+	var errs []error
+	for _, s := range store.stores {
+		if err := s.WriteMessage(event, topic); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (store *fanoutStore) WriteMessageWithContentType(event string, topic string, contentType string) error {
+	var errs []error
+	for _, s := range store.stores {
+		if err := WriteWithContentType(s, event, topic, contentType); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// SubscribeToEvents subscribes through the first store that supports it
+func (store *fanoutStore) SubscribeToEvents(ctx context.Context, topics []string, handler Handler) error {
+	for _, s := range store.stores {
+		err := s.SubscribeToEvents(ctx, topics, handler)
+		if !errors.Is(err, ErrSubscribeUnsupported) {
+			return err
+		}
+	}
+	return ErrSubscribeUnsupported
+}
+
+func (store *fanoutStore) Close() error {
+	var errs []error
+	for _, s := range store.stores {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// FindQueryable returns the first queryable store, looking inside fan-outs
+func FindQueryable(store EventStore_interface) (Queryable, bool) {
+	switch s := store.(type) {
+	case Queryable:
+		return s, true
+	case *fanoutStore:
+		for _, child := range s.stores {
+			if q, ok := FindQueryable(child); ok {
+				return q, true
+			}
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,291 @@
+package eventstore
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultFileMaxBytes = 10 * 1024 * 1024 // Rotate topic files after 10 MiB
+	defaultFileMaxFiles = 5                // Rotated files kept per topic
+)
+
+var validTopicName = regexp.MustCompile(`^[A-Za-z0-9._-]+$`)
+
+// binaryLine is how non-JSON events are stored in a JSON-lines file
+type binaryLine struct {
+	ContentType   string `json:"contentType"`
+	ValueEncoding string `json:"valueEncoding"`
+	Value         string `json:"value"`
+}
+
+// fileStore writes each topic to its own JSON-lines file and rotates it by size
+type fileStore struct {
+	dir      string
+	maxBytes int64
+	maxFiles int
+
+	mu    sync.Mutex
+	files map[string]*os.File
+	sizes map[string]int64
+}
+
+// NewFile creates an event store writing <dir>/<topic>.jsonl files.
+// Files are rotated once they grow past maxBytes, keeping maxFiles rotated
+// files per topic. Zero values select the defaults.
+func NewFile(dir string, maxBytes int64, maxFiles int) EventStore_interface {
+	if maxBytes <= 0 {
+		maxBytes = defaultFileMaxBytes
+	}
+	if maxFiles <= 0 {
+		maxFiles = defaultFileMaxFiles
+	}
+	return &fileStore{
+		dir:      dir,
+		maxBytes: maxBytes,
+		maxFiles: maxFiles,
+		files:    make(map[string]*os.File),
+		sizes:    make(map[string]int64),
+	}
+}
+
+func (store *fileStore) Connect() error {
+	if err := os.MkdirAll(store.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create event directory %s: %w", store.dir, err)
+	}
+	return nil
+}
+
+// Ping checks the event directory still exists
+func (store *fileStore) Ping(ctx context.Context) error {
+	info, err := os.Stat(store.dir)
+	if err != nil {
+		return fmt.Errorf("event directory %s is unavailable: %w", store.dir, err)
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("event directory %s is not a directory", store.dir)
+	}
+	return nil
+}
+
+func (store *fileStore) WriteMessage(event string, topic string) error {
+	return store.WriteMessageWithContentType(event, topic, "")
+}
+
+// WriteMessageWithContentType writes JSON events as-is; other content types
+// are wrapped in a JSON line with a base64 value
+func (store *fileStore) WriteMessageWithContentType(event string, topic string, contentType string) error {
+	if !validTopicName.MatchString(topic) {
+		return fmt.Errorf("invalid topic name for file store: %q", topic)
+	}
+
+	var line bytes.Buffer
+	if isJSONContentType(contentType) {
+		if err := json.Compact(&line, []byte(event)); err != nil {
+			return fmt.Errorf("event is not valid JSON: %w", err)
+		}
+	} else {
+		value, encoding := textValue(event, contentType)
+		wrapped, err := json.Marshal(binaryLine{ContentType: contentType, ValueEncoding: encoding, Value: value})
+		if err != nil {
+			return fmt.Errorf("failed to encode event: %w", err)
+		}
+		line.Write(wrapped)
+	}
+	line.WriteByte('\n')
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	file, err := store.open(topic)
+	if err != nil {
+		return err
+	}
+
+	if store.sizes[topic] > 0 && store.sizes[topic]+int64(line.Len()) > store.maxBytes {
+		if err := store.rotate(topic); err != nil {
+			return err
+		}
+		if file, err = store.open(topic); err != nil {
+			return err
+		}
+	}
+
+	n, err := file.Write(line.Bytes())
+	store.sizes[topic] += int64(n)
+	if err != nil {
+		return fmt.Errorf("failed to write event to %s: %w", file.Name(), err)
+	}
+	return nil
+}
+
+func (store *fileStore) SubscribeToEvents(ctx context.Context, topics []string, handler Handler) error {
+	return ErrSubscribeUnsupported
+}
+
+func (store *fileStore) Close() error {
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	var errs []error
+	for topic, file := range store.files {
+		errs = append(errs, file.Close())
+		delete(store.files, topic)
+	}
+	return errors.Join(errs...)
+}
+
+// Replay reads the rotated and active files of a topic, oldest first. Line
+// offsets shift as files rotate, so replaying from an offset is rejected; time
+// filtering is left to the handler because lines do not record when they
+// were written.
+func (store *fileStore) Replay(ctx context.Context, topic string, from ReplayFrom, handler Handler) error {
+	if !validTopicName.MatchString(topic) {
+		return fmt.Errorf("invalid topic name for file store: %q", topic)
+	}
+	if from.Offset > 0 && from.Time.IsZero() {
+		return fmt.Errorf("%w: file offsets change when files rotate; replay from a time instead", ErrReplayOffsetUnsupported)
+	}
+
+	store.mu.Lock()
+	files, err := store.rotatedFiles(topic)
+	if active, ok := store.files[topic]; ok {
+		active.Sync()
+	}
+	store.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	files = append(files, store.activePath(topic))
+
+	var offset int64
+	for _, path := range files {
+		if err := replayFile(ctx, path, topic, &offset, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayFile delivers the lines of one JSON-lines file, advancing offset
+func replayFile(ctx context.Context, path string, topic string, offset *int64, handler Handler) error {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil // Rotated away or never written
+	}
+	if err != nil {
+		return fmt.Errorf("failed to open event file %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lineOffset := *offset
+		*offset++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		msg := Message{Topic: topic, Offset: lineOffset, Value: append([]byte(nil), scanner.Bytes()...)}
+
+		var wrapped binaryLine
+		if json.Unmarshal(scanner.Bytes(), &wrapped) == nil && wrapped.ValueEncoding == "base64" {
+			value, err := base64.StdEncoding.DecodeString(wrapped.Value)
+			if err != nil {
+				return fmt.Errorf("corrupt event at %s:%d: %w", path, lineOffset, err)
+			}
+			msg.Value = value
+			msg.ContentType = wrapped.ContentType
+		}
+
+		if err := handler(ctx, msg); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// open returns the active file for a topic, opening it if needed
+func (store *fileStore) open(topic string) (*os.File, error) {
+	if file, ok := store.files[topic]; ok {
+		return file, nil
+	}
+
+	path := store.activePath(topic)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open event file %s: %w", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, fmt.Errorf("failed to stat event file %s: %w", path, err)
+	}
+
+	store.files[topic] = file
+	store.sizes[topic] = info.Size()
+	return file, nil
+}
+
+// rotate moves the active topic file aside and prunes old rotations
+func (store *fileStore) rotate(topic string) error {
+	if file, ok := store.files[topic]; ok {
+		file.Close()
+		delete(store.files, topic)
+	}
+
+	rotated := filepath.Join(store.dir, fmt.Sprintf("%s.%s.jsonl", topic, time.Now().UTC().Format("20060102T150405.000000000")))
+	if err := os.Rename(store.activePath(topic), rotated); err != nil {
+		return fmt.Errorf("failed to rotate event file for topic %s: %w", topic, err)
+	}
+	store.sizes[topic] = 0
+
+	rotations, err := store.rotatedFiles(topic)
+	if err != nil {
+		return err
+	}
+	for len(rotations) > store.maxFiles {
+		if err := os.Remove(rotations[0]); err != nil {
+			return fmt.Errorf("failed to prune event file %s: %w", rotations[0], err)
+		}
+		rotations = rotations[1:]
+	}
+	return nil
+}
+
+// rotatedFiles lists the rotated files of a topic, oldest first
+func (store *fileStore) rotatedFiles(topic string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(store.dir, topic+".*.jsonl"))
+	if err != nil {
+		return nil, err
+	}
+
+	// Other topics may share this prefix (e.g. "mesh" and "mesh.dlq")
+	rotations := matches[:0]
+	for _, match := range matches {
+		stamp := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(match), topic+"."), ".jsonl")
+		if _, err := time.Parse("20060102T150405.000000000", stamp); err == nil {
+			rotations = append(rotations, match)
+		}
+	}
+	sort.Strings(rotations)
+	return rotations, nil
+}
+
+func (store *fileStore) activePath(topic string) string {
+	return filepath.Join(store.dir, topic+".jsonl")
+}
@@ -2,7 +2,10 @@ package eventstore
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"strconv"
+	"sync"
 
 	"github.com/segmentio/kafka-go"
 )
@@ -10,15 +13,21 @@ import (
 type store struct {
 	broker string
 	groupId string
+	retry  RetryPolicy
 	writer *kafka.Writer
-	reader *kafka.Reader
 }
 
 func New(broker string, groupId string) EventStore_interface {
+	return NewWithRetryPolicy(broker, groupId, DefaultRetryPolicy)
+}
+
+// NewWithRetryPolicy creates a Kafka store whose subscriptions use the given retry policy
+func NewWithRetryPolicy(broker string, groupId string, retry RetryPolicy) EventStore_interface {
 	var eventStore EventStore_interface
 	eventStore = &store{
 		broker:  broker,
 		groupId: groupId,
+		retry:   retry,
 	}
 	return eventStore
 }
@@ -35,22 +44,36 @@ func (store *store) Connect() error {
 	// Since we have health checks in docker-compose, we can skip the connection test
 	// The health check already verifies Kafka is ready
 	
-	// Don't create reader here - it will be created when subscribing to specific topics
-	store.reader = nil
+	// Readers are created per topic when subscribing
 	
 	fmt.Printf("connection successful with Kafka: %v\n", store.broker)
 	return nil
 }
 
 func (store *store) WriteMessage(event string, topic string) error {
-	fmt.Printf("Delivering %v to topic %v\n", event, topic)
+	return store.WriteMessageWithContentType(event, topic, "")
+}
+
+func (store *store) WriteMessageWithContentType(event string, topic string, contentType string) error {
+	if store.writer == nil {
+		return fmt.Errorf("kafka store is not connected")
+	}
+
+	if isJSONContentType(contentType) {
+		fmt.Printf("Delivering %v to topic %v\n", event, topic)
+	} else {
+		fmt.Printf("Delivering %d bytes of %v to topic %v\n", len(event), contentType, topic)
+	}
 	
-	err := store.writer.WriteMessages(context.Background(),
-		kafka.Message{
-			Topic: topic,
-			Value: []byte(event),
-		},
-	)
+	message := kafka.Message{
+		Topic: topic,
+		Value: []byte(event),
+	}
+	if contentType != "" {
+		message.Headers = []kafka.Header{{Key: "content-type", Value: []byte(contentType)}}
+	}
+
+	err := store.writer.WriteMessages(context.Background(), message)
 	
 	if err != nil {
 		fmt.Printf("Delivery failed: %v\n", err)
@@ -61,29 +84,206 @@ func (store *store) WriteMessage(event string, topic string) error {
 	return nil
 }
 
-func (store *store) SubscribeToEvents(topic string) error {
-	// Close existing reader if any
-	if store.reader != nil {
-		store.reader.Close()
+// SubscribeToEvents consumes every topic concurrently in the store's consumer
+// group until ctx is cancelled. Offsets are committed only after the handler
+// succeeds or the message has been dead-lettered. A fatal error on one topic
+// stops the others and is returned once they have closed.
+func (store *store) SubscribeToEvents(ctx context.Context, topics []string, handler Handler) error {
+	if store.writer == nil {
+		return fmt.Errorf("kafka store is not connected")
 	}
-	
-	// Configure reader for specific topic
-	store.reader = kafka.NewReader(kafka.ReaderConfig{
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var wg sync.WaitGroup
+	errs := make([]error, len(topics))
+	for i, topic := range topics {
+		wg.Add(1)
+		go func(i int, topic string) {
+			defer wg.Done()
+			if errs[i] = store.consume(ctx, topic, handler); errs[i] != nil {
+				cancel()
+			}
+		}(i, topic)
+	}
+	wg.Wait()
+
+	return errors.Join(errs...)
+}
+
+// consume reads one topic until ctx is cancelled or a fatal error occurs
+func (store *store) consume(ctx context.Context, topic string, handler Handler) error {
+	reader := kafka.NewReader(kafka.ReaderConfig{
 		Brokers: []string{store.broker},
 		Topic:   topic,
 		GroupID: store.groupId,
 	})
+	defer reader.Close()
 
 	fmt.Printf("Subscribed to topic: %s\n", topic)
 
 	for {
-		msg, err := store.reader.ReadMessage(context.Background())
-		if err == nil {
-			fmt.Printf("Message on topic %s: %s\n", msg.Topic, string(msg.Value))
-		} else {
-			fmt.Printf("Consumer error: %v\n", err)
-			store.reader.Close()
+		msg, err := reader.FetchMessage(ctx)
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Printf("Unsubscribed from topic: %s\n", topic)
+				return nil
+			}
+			fmt.Printf("Consumer error on topic %s: %v\n", topic, err)
+			return fmt.Errorf("failed to fetch from topic %s: %w", topic, err)
+		}
+
+		err = deliver(ctx, kafkaToMessage(msg), handler, store.retry, func(failed Message, cause error) error {
+			return store.deadLetter(ctx, msg, cause)
+		})
+		if err != nil {
+			if ctx.Err() != nil {
+				fmt.Printf("Unsubscribed from topic: %s\n", topic)
+				return nil
+			}
+			return err
+		}
+
+		if err := reader.CommitMessages(ctx, msg); err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("failed to commit offset %d on topic %s: %w", msg.Offset, topic, err)
+		}
+	}
+}
+
+// deadLetter republishes a failed message to <topic>.dlq with its origin in headers
+func (store *store) deadLetter(ctx context.Context, msg kafka.Message, cause error) error {
+	headers := append([]kafka.Header{}, msg.Headers...)
+	headers = append(headers,
+		kafka.Header{Key: "dlq-original-topic", Value: []byte(msg.Topic)},
+		kafka.Header{Key: "dlq-original-partition", Value: []byte(strconv.Itoa(msg.Partition))},
+		kafka.Header{Key: "dlq-original-offset", Value: []byte(strconv.FormatInt(msg.Offset, 10))},
+		kafka.Header{Key: "dlq-error", Value: []byte(cause.Error())},
+	)
+
+	return store.writer.WriteMessages(ctx, kafka.Message{
+		Topic:   DeadLetterTopic(msg.Topic),
+		Key:     msg.Key,
+		Value:   msg.Value,
+		Headers: headers,
+	})
+}
+
+// Replay reads every partition of topic from the requested position up to the
+// current end of the log, without joining the consumer group
+func (store *store) Replay(ctx context.Context, topic string, from ReplayFrom, handler Handler) error {
+	conn, err := kafka.DialContext(ctx, "tcp", store.broker)
+	if err != nil {
+		return fmt.Errorf("failed to connect to Kafka for replay: %w", err)
+	}
+	partitions, err := conn.ReadPartitions(topic)
+	conn.Close()
+	if err != nil {
+		return fmt.Errorf("failed to read partitions of topic %s: %w", topic, err)
+	}
+
+	for _, partition := range partitions {
+		if err := store.replayPartition(ctx, topic, partition.ID, from, handler); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// replayPartition replays one partition up to its last offset at call time
+func (store *store) replayPartition(ctx context.Context, topic string, partition int, from ReplayFrom, handler Handler) error {
+	leader, err := kafka.DialLeader(ctx, "tcp", store.broker, topic, partition)
+	if err != nil {
+		return fmt.Errorf("failed to connect to leader of %s/%d: %w", topic, partition, err)
+	}
+	defer leader.Close()
+
+	first, end, err := leader.ReadOffsets()
+	if err != nil {
+		return fmt.Errorf("failed to read offsets of %s/%d: %w", topic, partition, err)
+	}
+
+	start := first
+	switch {
+	case !from.Time.IsZero():
+		if start, err = leader.ReadOffset(from.Time); err != nil {
+			return fmt.Errorf("failed to find offset of %s/%d at %s: %w", topic, partition, from.Time, err)
+		}
+	case from.Offset > first:
+		start = from.Offset
+	}
+	if start >= end {
+		return nil
+	}
+
+	reader := kafka.NewReader(kafka.ReaderConfig{
+		Brokers:   []string{store.broker},
+		Topic:     topic,
+		Partition: partition,
+	})
+	defer reader.Close()
+
+	if err := reader.SetOffset(start); err != nil {
+		return fmt.Errorf("failed to seek %s/%d to %d: %w", topic, partition, start, err)
+	}
+
+	for {
+		msg, err := reader.ReadMessage(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to replay %s/%d: %w", topic, partition, err)
+		}
+		if err := handler(ctx, kafkaToMessage(msg)); err != nil {
 			return err
 		}
+		if msg.Offset+1 >= end {
+			return nil
+		}
+	}
+}
+
+// Ping dials the broker and reads the cluster metadata
+func (store *store) Ping(ctx context.Context) error {
+	conn, err := kafka.DialContext(ctx, "tcp", store.broker)
+	if err != nil {
+		return fmt.Errorf("kafka broker %s is unreachable: %w", store.broker, err)
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	if _, err := conn.Brokers(); err != nil {
+		return fmt.Errorf("kafka broker %s did not return metadata: %w", store.broker, err)
+	}
+	return nil
+}
+
+// kafkaToMessage converts a kafka-go message for handlers
+func kafkaToMessage(msg kafka.Message) Message {
+	message := Message{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       msg.Key,
+		Value:     msg.Value,
+		Time:      msg.Time,
+	}
+	for _, header := range msg.Headers {
+		if header.Key == "content-type" {
+			message.ContentType = string(header.Value)
+		}
+	}
+	return message
+}
+
+func (store *store) Close() error {
+	if store.writer == nil {
+		return nil
 	}
+	err := store.writer.Close()
+	store.writer = nil
+	return err
 }
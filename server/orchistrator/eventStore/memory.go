@@ -0,0 +1,199 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/base64"
+	"sync"
+	"time"
+)
+
+const defaultMemoryCapacity = 1000
+
+// StoredEvent is an event retained by a queryable store
+type StoredEvent struct {
+	Offset        uint64    `json:"offset"`
+	Topic         string    `json:"topic"`
+	Time          time.Time `json:"time"`
+	ContentType   string    `json:"contentType,omitempty"`
+	ValueEncoding string    `json:"valueEncoding,omitempty"` // "base64" for non-JSON content
+	Value         string    `json:"value"`
+}
+
+// Queryable is implemented by stores that can return recently written events
+type Queryable interface {
+	// Query returns up to limit events with an offset greater than after,
+	// oldest first. An empty topic matches every topic.
+	Query(topic string, after uint64, limit int) []StoredEvent
+}
+
+// memoryStore keeps the most recent events in a fixed-size ring buffer
+type memoryStore struct {
+	mu         sync.RWMutex
+	events     []StoredEvent
+	next       int
+	full       bool
+	nextOffset uint64
+	written    chan struct{} // closed and replaced on every write
+	retry      RetryPolicy
+}
+
+// NewMemory creates an event store that retains the last capacity events
+func NewMemory(capacity int) EventStore_interface {
+	if capacity <= 0 {
+		capacity = defaultMemoryCapacity
+	}
+	return &memoryStore{
+		events:     make([]StoredEvent, capacity),
+		nextOffset: 1,
+		written:    make(chan struct{}),
+		retry:      DefaultRetryPolicy,
+	}
+}
+
+func (store *memoryStore) Connect() error {
+	return nil
+}
+
+func (store *memoryStore) WriteMessage(event string, topic string) error {
+	return store.WriteMessageWithContentType(event, topic, "")
+}
+
+func (store *memoryStore) WriteMessageWithContentType(event string, topic string, contentType string) error {
+	value, encoding := textValue(event, contentType)
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+
+	store.events[store.next] = StoredEvent{
+		Offset:        store.nextOffset,
+		Topic:         topic,
+		Time:          time.Now(),
+		ContentType:   contentType,
+		ValueEncoding: encoding,
+		Value:         value,
+	}
+	store.nextOffset++
+	store.next = (store.next + 1) % len(store.events)
+	if store.next == 0 {
+		store.full = true
+	}
+
+	close(store.written)
+	store.written = make(chan struct{})
+	return nil
+}
+
+// SubscribeToEvents delivers events written after the call. Failed events are
+// retried and then dead-lettered within the store.
+func (store *memoryStore) SubscribeToEvents(ctx context.Context, topics []string, handler Handler) error {
+	wanted := make(map[string]bool, len(topics))
+	for _, topic := range topics {
+		wanted[topic] = true
+	}
+
+	store.mu.RLock()
+	after := store.nextOffset - 1
+	store.mu.RUnlock()
+
+	for {
+		store.mu.RLock()
+		written := store.written
+		pending := make([]StoredEvent, 0)
+		for _, event := range store.ordered() {
+			if event.Offset > after && wanted[event.Topic] {
+				pending = append(pending, event)
+			}
+		}
+		latest := store.nextOffset - 1
+		store.mu.RUnlock()
+
+		for _, event := range pending {
+			err := deliver(ctx, event.message(), handler, store.retry, func(failed Message, cause error) error {
+				return store.WriteMessageWithContentType(string(failed.Value), DeadLetterTopic(failed.Topic), failed.ContentType)
+			})
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return err
+			}
+		}
+		after = latest
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-written:
+		}
+	}
+}
+
+func (store *memoryStore) Close() error {
+	return nil
+}
+
+func (store *memoryStore) Query(topic string, after uint64, limit int) []StoredEvent {
+	store.mu.RLock()
+	defer store.mu.RUnlock()
+
+	results := make([]StoredEvent, 0)
+	for _, event := range store.ordered() {
+		if limit > 0 && len(results) >= limit {
+			break
+		}
+		if event.Offset <= after || (topic != "" && event.Topic != topic) {
+			continue
+		}
+		results = append(results, event)
+	}
+	return results
+}
+
+// Replay delivers retained events of a topic with an offset of at least
+// from.Offset, or written at or after from.Time
+func (store *memoryStore) Replay(ctx context.Context, topic string, from ReplayFrom, handler Handler) error {
+	store.mu.RLock()
+	pending := make([]StoredEvent, 0)
+	for _, event := range store.ordered() {
+		if event.Topic != topic || event.Offset < uint64(from.Offset) {
+			continue
+		}
+		if !from.Time.IsZero() && event.Time.Before(from.Time) {
+			continue
+		}
+		pending = append(pending, event)
+	}
+	store.mu.RUnlock()
+
+	for _, event := range pending {
+		if err := handler(ctx, event.message()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// message converts a stored event for subscription handlers
+func (event StoredEvent) message() Message {
+	value := []byte(event.Value)
+	if event.ValueEncoding == "base64" {
+		value, _ = base64.StdEncoding.DecodeString(event.Value)
+	}
+	return Message{
+		Topic:       event.Topic,
+		Offset:      int64(event.Offset),
+		Value:       value,
+		ContentType: event.ContentType,
+		Time:        event.Time,
+	}
+}
+
+// ordered returns the retained events oldest first; callers must hold mu
+func (store *memoryStore) ordered() []StoredEvent {
+	if !store.full {
+		return store.events[:store.next]
+	}
+	ordered := make([]StoredEvent, 0, len(store.events))
+	ordered = append(ordered, store.events[store.next:]...)
+	return append(ordered, store.events[:store.next]...)
+}
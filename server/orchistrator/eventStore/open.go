@@ -0,0 +1,137 @@
+package eventstore
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Open builds an event store from a comma-separated list of sink URIs:
+//
+//	kafka://kafka:9092?group=1&retries=3&backoff=500ms&dlq=true
+//	file:///var/lib/mesh/events?maxSize=10485760&maxFiles=5
+//	stdout://
+//	memory://?size=1000
+//
+// A single URI returns that store directly; several are combined with NewFanout.
+// defaultGroupId is used for Kafka sinks that do not set a group.
+func Open(spec string, defaultGroupId string) (EventStore_interface, error) {
+	var stores []EventStore_interface
+	for _, raw := range strings.Split(spec, ",") {
+		raw = strings.TrimSpace(raw)
+		if raw == "" {
+			continue
+		}
+		store, err := openURI(raw, defaultGroupId)
+		if err != nil {
+			return nil, err
+		}
+		stores = append(stores, store)
+	}
+
+	switch len(stores) {
+	case 0:
+		return nil, fmt.Errorf("no event sinks configured")
+	case 1:
+		return stores[0], nil
+	default:
+		return NewFanout(stores...), nil
+	}
+}
+
+func openURI(raw string, defaultGroupId string) (EventStore_interface, error) {
+	uri, err := url.Parse(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid event sink %q: %w", raw, err)
+	}
+	query := uri.Query()
+
+	switch uri.Scheme {
+	case "kafka":
+		if uri.Host == "" {
+			return nil, fmt.Errorf("kafka sink %q has no broker address", raw)
+		}
+		groupId := query.Get("group")
+		if groupId == "" {
+			groupId = defaultGroupId
+		}
+		retry, err := retryParams(query)
+		if err != nil {
+			return nil, fmt.Errorf("kafka sink %q: %w", raw, err)
+		}
+		return NewWithRetryPolicy(uri.Host, groupId, retry), nil
+
+	case "file":
+		if uri.Path == "" {
+			return nil, fmt.Errorf("file sink %q has no directory", raw)
+		}
+		maxBytes, err := intParam(query, "maxSize")
+		if err != nil {
+			return nil, fmt.Errorf("file sink %q: %w", raw, err)
+		}
+		maxFiles, err := intParam(query, "maxFiles")
+		if err != nil {
+			return nil, fmt.Errorf("file sink %q: %w", raw, err)
+		}
+		return NewFile(uri.Path, int64(maxBytes), maxFiles), nil
+
+	case "stdout":
+		return NewStdout(nil), nil
+
+	case "memory":
+		size, err := intParam(query, "size")
+		if err != nil {
+			return nil, fmt.Errorf("memory sink %q: %w", raw, err)
+		}
+		return NewMemory(size), nil
+
+	default:
+		return nil, fmt.Errorf("unknown event sink scheme %q in %q", uri.Scheme, raw)
+	}
+}
+
+// retryParams reads the subscription retry policy from retries, backoff and dlq
+func retryParams(query url.Values) (RetryPolicy, error) {
+	retry := DefaultRetryPolicy
+
+	if query.Has("retries") {
+		retries, err := intParam(query, "retries")
+		if err != nil {
+			return retry, err
+		}
+		retry.MaxRetries = retries
+	}
+
+	if value := query.Get("backoff"); value != "" {
+		backoff, err := time.ParseDuration(value)
+		if err != nil || backoff < 0 {
+			return retry, fmt.Errorf("invalid backoff %q", value)
+		}
+		retry.Backoff = backoff
+	}
+
+	if value := query.Get("dlq"); value != "" {
+		deadLetter, err := strconv.ParseBool(value)
+		if err != nil {
+			return retry, fmt.Errorf("invalid dlq %q", value)
+		}
+		retry.DeadLetter = deadLetter
+	}
+
+	return retry, nil
+}
+
+// intParam reads an optional non-negative integer query parameter
+func intParam(query url.Values, name string) (int, error) {
+	value := query.Get(name)
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid %s %q", name, value)
+	}
+	return n, nil
+}
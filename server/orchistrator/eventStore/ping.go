@@ -0,0 +1,29 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+)
+
+// Pinger is implemented by stores that can check their backend is reachable
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+// Ping checks every store that implements Pinger, looking inside fan-outs.
+// Stores without a backend, such as memory and stdout, are always reachable.
+func Ping(ctx context.Context, store EventStore_interface) error {
+	switch s := store.(type) {
+	case Pinger:
+		return s.Ping(ctx)
+	case *fanoutStore:
+		var errs []error
+		for _, child := range s.stores {
+			if err := Ping(ctx, child); err != nil {
+				errs = append(errs, err)
+			}
+		}
+		return errors.Join(errs...)
+	}
+	return nil
+}
@@ -0,0 +1,72 @@
+package eventstore
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrReplayOffsetUnsupported is returned by stores whose offsets are not
+// stable enough to replay from
+var ErrReplayOffsetUnsupported = errors.New("event store cannot replay from an offset")
+
+// ReplayFrom selects where a replay starts. A non-zero Time takes precedence
+// over Offset; the zero value replays everything retained.
+type ReplayFrom struct {
+	Offset int64     `json:"offset,omitempty"`
+	Time   time.Time `json:"time,omitempty"`
+}
+
+// Replayer is implemented by stores that can re-read their event log
+type Replayer interface {
+	// Replay delivers the retained messages of a topic, oldest first, starting
+	// at from. It returns once the end of the log is reached.
+	Replay(ctx context.Context, topic string, from ReplayFrom, handler Handler) error
+}
+
+// FindReplayer returns the first store that can replay, looking inside fan-outs
+func FindReplayer(store EventStore_interface) (Replayer, bool) {
+	switch s := store.(type) {
+	case Replayer:
+		return s, true
+	case *fanoutStore:
+		for _, child := range s.stores {
+			if r, ok := FindReplayer(child); ok {
+				return r, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// ParseReplayFrom parses a replay start position:
+//
+//	earliest                  everything retained
+//	offset:42                 from offset 42
+//	2024-01-01T12:00:00Z      from an RFC3339 time
+//	24h                       from a duration ago
+func ParseReplayFrom(spec string) (ReplayFrom, error) {
+	spec = strings.TrimSpace(spec)
+	switch {
+	case spec == "" || spec == "earliest":
+		return ReplayFrom{}, nil
+
+	case strings.HasPrefix(spec, "offset:"):
+		offset, err := strconv.ParseInt(strings.TrimPrefix(spec, "offset:"), 10, 64)
+		if err != nil || offset < 0 {
+			return ReplayFrom{}, fmt.Errorf("invalid replay offset %q", spec)
+		}
+		return ReplayFrom{Offset: offset}, nil
+	}
+
+	if t, err := time.Parse(time.RFC3339Nano, spec); err == nil {
+		return ReplayFrom{Time: t}, nil
+	}
+	if d, err := time.ParseDuration(spec); err == nil && d > 0 {
+		return ReplayFrom{Time: time.Now().Add(-d)}, nil
+	}
+	return ReplayFrom{}, fmt.Errorf("invalid replay position %q (expected earliest, offset:N, RFC3339 time or duration)", spec)
+}
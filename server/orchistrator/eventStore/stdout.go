@@ -0,0 +1,71 @@
+package eventstore
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// stdoutStore prints every event as a JSON line tagged with its topic
+type stdoutStore struct {
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewStdout creates an event store that prints events to out (os.Stdout if nil)
+func NewStdout(out io.Writer) EventStore_interface {
+	if out == nil {
+		out = os.Stdout
+	}
+	return &stdoutStore{out: out}
+}
+
+func (store *stdoutStore) Connect() error {
+	return nil
+}
+
+func (store *stdoutStore) WriteMessage(event string, topic string) error {
+	return store.WriteMessageWithContentType(event, topic, "")
+}
+
+func (store *stdoutStore) WriteMessageWithContentType(event string, topic string, contentType string) error {
+	line := struct {
+		Time          time.Time       `json:"time"`
+		Topic         string          `json:"topic"`
+		ContentType   string          `json:"contentType,omitempty"`
+		Event         json.RawMessage `json:"event,omitempty"`
+		ValueEncoding string          `json:"valueEncoding,omitempty"`
+		Value         string          `json:"value,omitempty"`
+	}{
+		Time:        time.Now().UTC(),
+		Topic:       topic,
+		ContentType: contentType,
+	}
+	if isJSONContentType(contentType) {
+		line.Event = json.RawMessage(event)
+	} else {
+		line.Value, line.ValueEncoding = textValue(event, contentType)
+	}
+
+	data, err := json.Marshal(line)
+	if err != nil {
+		return fmt.Errorf("failed to encode event for stdout: %w", err)
+	}
+
+	store.mu.Lock()
+	defer store.mu.Unlock()
+	_, err = fmt.Fprintln(store.out, string(data))
+	return err
+}
+
+func (store *stdoutStore) SubscribeToEvents(ctx context.Context, topics []string, handler Handler) error {
+	return ErrSubscribeUnsupported
+}
+
+func (store *stdoutStore) Close() error {
+	return nil
+}
@@ -0,0 +1,81 @@
+package eventstore
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+)
+
+// Message is an event delivered to a subscription handler
+type Message struct {
+	Topic       string
+	Partition   int
+	Offset      int64
+	Key         []byte
+	Value       []byte
+	ContentType string
+	Time        time.Time
+}
+
+// Handler processes one message. Returning an error retries the message and,
+// once the retries are exhausted, moves it to the dead-letter topic.
+type Handler func(ctx context.Context, msg Message) error
+
+// RetryPolicy controls how failed messages are retried and dead-lettered
+type RetryPolicy struct {
+	MaxRetries int           // Retries after the first failed attempt
+	Backoff    time.Duration // Delay before the first retry, doubled on each retry
+	DeadLetter bool          // Publish exhausted messages to <topic>.dlq; otherwise skip them
+}
+
+// DefaultRetryPolicy retries three times and then dead-letters the message
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 3,
+	Backoff:    500 * time.Millisecond,
+	DeadLetter: true,
+}
+
+// DeadLetterTopic returns the topic failed messages from topic are moved to
+func DeadLetterTopic(topic string) string {
+	return topic + ".dlq"
+}
+
+// deliver runs handler on msg with retries. It returns nil once the message
+// is handled or dead-lettered (so its offset can be committed) and an error
+// if the context was cancelled or dead-lettering failed.
+func deliver(ctx context.Context, msg Message, handler Handler, policy RetryPolicy, deadLetter func(Message, error) error) error {
+	backoff := policy.Backoff
+	var err error
+
+	for attempt := 0; attempt <= policy.MaxRetries; attempt++ {
+		if attempt > 0 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(backoff):
+			}
+			backoff *= 2
+		}
+
+		if err = handler(ctx, msg); err == nil {
+			return nil
+		}
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		log.Printf("[SUBSCRIBE] Handler failed for %s offset %d (attempt %d/%d): %v",
+			msg.Topic, msg.Offset, attempt+1, policy.MaxRetries+1, err)
+	}
+
+	if !policy.DeadLetter {
+		log.Printf("[SUBSCRIBE] Skipping %s offset %d after %d attempts", msg.Topic, msg.Offset, policy.MaxRetries+1)
+		return nil
+	}
+
+	if dlqErr := deadLetter(msg, err); dlqErr != nil {
+		return fmt.Errorf("failed to dead-letter %s offset %d: %w", msg.Topic, msg.Offset, dlqErr)
+	}
+	log.Printf("[SUBSCRIBE] Moved %s offset %d to %s", msg.Topic, msg.Offset, DeadLetterTopic(msg.Topic))
+	return nil
+}
@@ -0,0 +1,153 @@
+package events
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/superbrobenji/motionServer/events/eventspb"
+	"google.golang.org/protobuf/proto"
+)
+
+// Decode parses an event written by Marshal, returning its envelope
+// attributes and typed payload. An empty content type is treated as JSON.
+// The returned envelope's Data is only set for JSON events.
+func Decode(data []byte, contentType string) (*Envelope, Event, error) {
+	if contentType == ContentTypeProtobuf {
+		return decodeProto(data)
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, nil, fmt.Errorf("invalid event envelope: %w", err)
+	}
+	if envelope.SpecVersion != SpecVersion {
+		return nil, nil, fmt.Errorf("unsupported event specversion %q", envelope.SpecVersion)
+	}
+
+	var event Event
+	switch envelope.Type {
+	case TypeMeshMessage:
+		event = &MeshMessageEvent{}
+	case TypeMotion:
+		event = &MotionEvent{}
+	case TypeHealth:
+		event = &HealthEvent{}
+	case TypeNodeLifecycle:
+		event = &NodeLifecycleEvent{}
+	case TypeAudit:
+		event = &AuditEvent{}
+	default:
+		return &envelope, nil, fmt.Errorf("unknown event type %q", envelope.Type)
+	}
+
+	if err := json.Unmarshal(envelope.Data, event); err != nil {
+		return &envelope, nil, fmt.Errorf("invalid %s payload: %w", envelope.Type, err)
+	}
+	return &envelope, event, nil
+}
+
+// decodeProto is the protobuf counterpart of Decode
+func decodeProto(data []byte) (*Envelope, Event, error) {
+	var pb eventspb.Envelope
+	if err := proto.Unmarshal(data, &pb); err != nil {
+		return nil, nil, fmt.Errorf("invalid protobuf event envelope: %w", err)
+	}
+
+	envelope := &Envelope{
+		SpecVersion:     pb.Specversion,
+		ID:              pb.Id,
+		Source:          pb.Source,
+		Type:            pb.Type,
+		Subject:         pb.Subject,
+		Time:            Timestamp(pb.Time.AsTime()),
+		DataContentType: ContentTypeProtobuf,
+		SchemaVersion:   pb.Schemaversion,
+	}
+
+	switch d := pb.Data.(type) {
+	case *eventspb.Envelope_MeshMessage:
+		m := d.MeshMessage
+		return envelope, &MeshMessageEvent{
+			Direction:    m.Direction,
+			MessageType:  m.MessageType,
+			DataType:     m.DataType,
+			DataTypeName: m.DataTypeName,
+			Origin:       m.Origin,
+			Target:       m.Target,
+			LastHop:      m.LastHop,
+			HopCount:     m.HopCount,
+			Data:         hex.EncodeToString(m.Data),
+			DataLength:   len(m.Data),
+			HealthReport: healthReportFromProto(m.HealthReport),
+		}, nil
+	case *eventspb.Envelope_Motion:
+		m := d.Motion
+		event := &MotionEvent{
+			MAC:        m.Mac,
+			HopCount:   m.HopCount,
+			LastHop:    m.LastHop,
+			Data:       hex.EncodeToString(m.Data),
+			ReceivedAt: Timestamp(m.ReceivedAt.AsTime()),
+		}
+		if m.DeviceTime != nil {
+			deviceTime, offsetMs := Timestamp(m.DeviceTime.AsTime()), m.OffsetMs
+			event.DeviceTime, event.OffsetMs = &deviceTime, &offsetMs
+		}
+		return envelope, event, nil
+	case *eventspb.Envelope_Health:
+		h := d.Health
+		event := &HealthEvent{
+			HopCount:   h.HopCount,
+			Origin:     h.Origin,
+			ReceivedAt: Timestamp(h.ReceivedAt.AsTime()),
+		}
+		if report := healthReportFromProto(h.Report); report != nil {
+			event.HealthReport = *report
+		}
+		return envelope, event, nil
+	case *eventspb.Envelope_NodeLifecycle:
+		n := d.NodeLifecycle
+		return envelope, &NodeLifecycleEvent{
+			MAC:            n.Mac,
+			State:          n.State,
+			FirstSeen:      n.FirstSeen,
+			LastSeen:       Timestamp(n.LastSeen.AsTime()),
+			OfflineAfterMs: n.OfflineAfterMs,
+		}, nil
+	case *eventspb.Envelope_Audit:
+		a := d.Audit
+		return envelope, &AuditEvent{
+			Action:     a.Action,
+			Interface:  a.Interface,
+			Actor:      a.Actor,
+			ActorName:  a.ActorName,
+			Role:       a.Role,
+			AuthMethod: a.AuthMethod,
+			SourceIP:   a.SourceIp,
+			Method:     a.Method,
+			Path:       a.Path,
+			Target:     a.Target,
+			Params:     a.Params.AsMap(),
+			Outcome:    a.Outcome,
+			Status:     int(a.Status),
+			Error:      a.Error,
+			Time:       Timestamp(a.Time.AsTime()),
+			DurationMs: a.DurationMs,
+		}, nil
+	default:
+		return envelope, nil, fmt.Errorf("unknown protobuf event payload for type %q", pb.Type)
+	}
+}
+
+func healthReportFromProto(report *eventspb.HealthReport) *HealthReport {
+	if report == nil {
+		return nil
+	}
+	return &HealthReport{
+		MAC:             report.Mac,
+		AdapterType:     report.AdapterType,
+		AdapterTypeName: report.AdapterTypeName,
+		Uptime:          report.Uptime,
+	}
+}
@@ -0,0 +1,229 @@
+package events
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/superbrobenji/motionServer/events/eventspb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Encoding selects how events are serialized on a topic
+type Encoding string
+
+const (
+	EncodingJSON     Encoding = "json"
+	EncodingProtobuf Encoding = "protobuf"
+)
+
+// Content types sent alongside each event (Kafka "content-type" header)
+const (
+	ContentTypeJSON     = "application/cloudevents+json"
+	ContentTypeProtobuf = "application/cloudevents+protobuf"
+)
+
+// ContentType returns the content type of events in this encoding
+func (e Encoding) ContentType() string {
+	if e == EncodingProtobuf {
+		return ContentTypeProtobuf
+	}
+	return ContentTypeJSON
+}
+
+// Encodings maps topics to encodings, falling back to Default
+type Encodings struct {
+	Default Encoding
+	Topics  map[string]Encoding
+}
+
+// ParseEncodings parses a default encoding optionally followed by per-topic
+// overrides, e.g. "json", "protobuf" or "json,motion-trigger=protobuf"
+func ParseEncodings(spec string) (Encodings, error) {
+	encodings := Encodings{Default: EncodingJSON, Topics: make(map[string]Encoding)}
+
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+
+		topic, value, perTopic := strings.Cut(part, "=")
+		if !perTopic {
+			value = topic
+		}
+
+		encoding := Encoding(strings.TrimSpace(value))
+		if encoding != EncodingJSON && encoding != EncodingProtobuf {
+			return Encodings{}, fmt.Errorf("unknown event encoding %q (expected json or protobuf)", value)
+		}
+
+		if perTopic {
+			encodings.Topics[strings.TrimSpace(topic)] = encoding
+		} else {
+			encodings.Default = encoding
+		}
+	}
+
+	return encodings, nil
+}
+
+// For returns the encoding to use on a topic
+func (e Encodings) For(topic string) Encoding {
+	if encoding, ok := e.Topics[topic]; ok {
+		return encoding
+	}
+	if e.Default == "" {
+		return EncodingJSON
+	}
+	return e.Default
+}
+
+// Marshal wraps an event in an envelope and serializes it in the given encoding,
+// returning the bytes and their content type
+func Marshal(encoding Encoding, source string, event Event, at time.Time) ([]byte, string, error) {
+	envelope, err := NewEnvelope(source, event, at)
+	if err != nil {
+		return nil, "", err
+	}
+	return envelope.Marshal(encoding, event)
+}
+
+// Marshal serializes the envelope in the given encoding. event must be the
+// payload the envelope was created from; it is needed for protobuf.
+func (e *Envelope) Marshal(encoding Encoding, event Event) ([]byte, string, error) {
+	if encoding == EncodingProtobuf {
+		data, err := e.MarshalProto(event)
+		return data, ContentTypeProtobuf, err
+	}
+	data, err := json.Marshal(e)
+	return data, ContentTypeJSON, err
+}
+
+// EncodeProto wraps an event in a protobuf envelope and returns its binary encoding
+func EncodeProto(source string, event Event, at time.Time) ([]byte, error) {
+	envelope, err := NewEnvelope(source, event, at)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.MarshalProto(event)
+}
+
+// MarshalProto encodes the envelope attributes with event as the protobuf payload
+func (e *Envelope) MarshalProto(event Event) ([]byte, error) {
+	envelope, err := e.ToProto(event)
+	if err != nil {
+		return nil, err
+	}
+	return proto.Marshal(envelope)
+}
+
+// ToProto converts the envelope attributes and event to a protobuf envelope
+func (e *Envelope) ToProto(event Event) (*eventspb.Envelope, error) {
+	envelope := &eventspb.Envelope{
+		Specversion:   e.SpecVersion,
+		Id:            e.ID,
+		Source:        e.Source,
+		Type:          e.Type,
+		Subject:       e.Subject,
+		Time:          timestamppb.New(e.Time.Time()),
+		Schemaversion: e.SchemaVersion,
+	}
+
+	switch e := event.(type) {
+	case *MeshMessageEvent:
+		envelope.Data = &eventspb.Envelope_MeshMessage{MeshMessage: &eventspb.MeshMessageEvent{
+			Direction:    e.Direction,
+			MessageType:  e.MessageType,
+			DataType:     e.DataType,
+			DataTypeName: e.DataTypeName,
+			Origin:       e.Origin,
+			Target:       e.Target,
+			LastHop:      e.LastHop,
+			HopCount:     e.HopCount,
+			Data:         decodeHex(e.Data),
+			HealthReport: healthReportToProto(e.HealthReport),
+		}}
+	case *MotionEvent:
+		motion := &eventspb.MotionEvent{
+			Mac:        e.MAC,
+			HopCount:   e.HopCount,
+			LastHop:    e.LastHop,
+			Data:       decodeHex(e.Data),
+			ReceivedAt: timestamppb.New(e.ReceivedAt.Time()),
+		}
+		if e.DeviceTime != nil && e.OffsetMs != nil {
+			motion.DeviceTime = timestamppb.New(e.DeviceTime.Time())
+			motion.OffsetMs = *e.OffsetMs
+		}
+		envelope.Data = &eventspb.Envelope_Motion{Motion: motion}
+	case *HealthEvent:
+		envelope.Data = &eventspb.Envelope_Health{Health: &eventspb.HealthEvent{
+			Report:     healthReportToProto(&e.HealthReport),
+			HopCount:   e.HopCount,
+			Origin:     e.Origin,
+			ReceivedAt: timestamppb.New(e.ReceivedAt.Time()),
+		}}
+	case *NodeLifecycleEvent:
+		envelope.Data = &eventspb.Envelope_NodeLifecycle{NodeLifecycle: &eventspb.NodeLifecycleEvent{
+			Mac:            e.MAC,
+			State:          e.State,
+			FirstSeen:      e.FirstSeen,
+			LastSeen:       timestamppb.New(e.LastSeen.Time()),
+			OfflineAfterMs: e.OfflineAfterMs,
+		}}
+	case *AuditEvent:
+		params, err := structpb.NewStruct(e.Params)
+		if err != nil {
+			return nil, fmt.Errorf("failed to encode audit params: %w", err)
+		}
+		if e.Params == nil {
+			params = nil
+		}
+		envelope.Data = &eventspb.Envelope_Audit{Audit: &eventspb.AuditEvent{
+			Action:     e.Action,
+			Interface:  e.Interface,
+			Actor:      e.Actor,
+			ActorName:  e.ActorName,
+			Role:       e.Role,
+			AuthMethod: e.AuthMethod,
+			SourceIp:   e.SourceIP,
+			Method:     e.Method,
+			Path:       e.Path,
+			Target:     e.Target,
+			Params:     params,
+			Outcome:    e.Outcome,
+			Status:     int32(e.Status),
+			Error:      e.Error,
+			Time:       timestamppb.New(e.Time.Time()),
+			DurationMs: e.DurationMs,
+		}}
+	default:
+		return nil, fmt.Errorf("no protobuf encoding for event type %s", event.EventType())
+	}
+
+	return envelope, nil
+}
+
+func healthReportToProto(report *HealthReport) *eventspb.HealthReport {
+	if report == nil {
+		return nil
+	}
+	return &eventspb.HealthReport{
+		Mac:             report.MAC,
+		AdapterType:     report.AdapterType,
+		AdapterTypeName: report.AdapterTypeName,
+		Uptime:          report.Uptime,
+	}
+}
+
+// decodeHex converts a hex payload back to bytes; payloads are always produced
+// by hex.EncodeToString so invalid input only yields an empty payload
+func decodeHex(s string) []byte {
+	data, _ := hex.DecodeString(s)
+	return data
+}
@@ -0,0 +1,107 @@
+package events
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// SpecVersion is the CloudEvents specification version of the envelope
+const SpecVersion = "1.0"
+
+// TimeFormat is RFC3339 with millisecond precision, used for all event timestamps
+const TimeFormat = "2006-01-02T15:04:05.000Z07:00"
+
+// Timestamp is a time that marshals to JSON in TimeFormat (UTC)
+type Timestamp time.Time
+
+// MarshalJSON implements json.Marshaler
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Time(t).UTC().Format(TimeFormat))
+}
+
+// UnmarshalJSON implements json.Unmarshaler
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	parsed, err := time.Parse(time.RFC3339Nano, s)
+	if err != nil {
+		return err
+	}
+	*t = Timestamp(parsed)
+	return nil
+}
+
+// Time returns the timestamp as a time.Time
+func (t Timestamp) Time() time.Time {
+	return time.Time(t)
+}
+
+// Envelope is a CloudEvents 1.0 structured-mode event.
+// CloudEvents requires extension attribute names to be lowercase, so the
+// schema version is carried as "schemaversion".
+type Envelope struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Subject         string          `json:"subject,omitempty"`
+	Time            Timestamp       `json:"time"`
+	DataContentType string          `json:"datacontenttype"`
+	SchemaVersion   string          `json:"schemaversion"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// Event is implemented by every typed event payload
+type Event interface {
+	// EventType returns the CloudEvents type, e.g. "com.planetopia.mesh.motion"
+	EventType() string
+	// SchemaVersion returns the version of the payload's JSON Schema
+	SchemaVersion() string
+	// Subject returns the MAC address the event is about, if any
+	Subject() string
+}
+
+// NewEnvelope wraps an event payload in a CloudEvents envelope
+func NewEnvelope(source string, event Event, at time.Time) (*Envelope, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s event: %w", event.EventType(), err)
+	}
+
+	return &Envelope{
+		SpecVersion:     SpecVersion,
+		ID:              newEventID(),
+		Source:          source,
+		Type:            event.EventType(),
+		Subject:         event.Subject(),
+		Time:            Timestamp(at),
+		DataContentType: "application/json",
+		SchemaVersion:   event.SchemaVersion(),
+		Data:            data,
+	}, nil
+}
+
+// Encode wraps an event in an envelope and returns its JSON encoding
+func Encode(source string, event Event, at time.Time) ([]byte, error) {
+	envelope, err := NewEnvelope(source, event, at)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(envelope)
+}
+
+// newEventID returns a random RFC 4122 version 4 UUID
+func newEventID() string {
+	var b [16]byte
+	if _, err := rand.Read(b[:]); err != nil {
+		// crypto/rand does not fail on supported platforms; fall back to the clock
+		return fmt.Sprintf("%d", time.Now().UnixNano())
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
@@ -0,0 +1,219 @@
+package events
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/superbrobenji/motionServer/events/eventspb"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestEnvelope(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 123456789, time.FixedZone("CAT", 2*3600))
+	event := &MotionEvent{MAC: "aa:bb:cc:dd:ee:ff", ReceivedAt: Timestamp(at)}
+
+	data, err := Encode("/orchistrator/dev/ttyUSB0", event, at)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	var raw map[string]interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		t.Fatalf("Expected valid JSON, got %v", err)
+	}
+
+	if raw["time"] != "2024-01-01T10:00:00.123Z" {
+		t.Errorf("Expected UTC RFC3339 time with ms, got %v", raw["time"])
+	}
+	if raw["type"] != TypeMotion || raw["subject"] != "aa:bb:cc:dd:ee:ff" || raw["schemaversion"] != "1.0" {
+		t.Errorf("Unexpected envelope attributes: %v", raw)
+	}
+	if id, _ := raw["id"].(string); len(id) != 36 || strings.Count(id, "-") != 4 {
+		t.Errorf("Expected UUID event id, got %v", raw["id"])
+	}
+
+	var envelope Envelope
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Expected envelope to round-trip, got %v", err)
+	}
+	if !envelope.Time.Time().Equal(at.Truncate(time.Millisecond)) {
+		t.Errorf("Expected time %v, got %v", at, envelope.Time.Time())
+	}
+}
+
+// TestSchemasMatchTypes keeps the published schemas in sync with the Go structs
+func TestSchemasMatchTypes(t *testing.T) {
+	testCases := []Event{
+		&MeshMessageEvent{HealthReport: &HealthReport{}},
+		&MotionEvent{},
+		&HealthEvent{},
+		&NodeLifecycleEvent{},
+		&AuditEvent{
+			ActorName:  "dashboard",
+			Role:       "operator",
+			AuthMethod: "api-key",
+			SourceIP:   "192.168.1.10",
+			Method:     "POST",
+			Path:       "/nodes/configure-all",
+			Target:     "aa:bb:cc:dd:ee:ff",
+			Params:     map[string]interface{}{"adapterType": 0},
+			Status:     500,
+			Error:      "serial port closed",
+		},
+	}
+
+	for _, event := range testCases {
+		t.Run(event.EventType(), func(t *testing.T) {
+			raw, ok := Schema(event.EventType())
+			if !ok {
+				t.Fatalf("No schema published for %s", event.EventType())
+			}
+
+			var schema struct {
+				Properties struct {
+					SchemaVersion struct {
+						Const string `json:"const"`
+					} `json:"schemaversion"`
+					Data struct {
+						Required   []string                   `json:"required"`
+						Properties map[string]json.RawMessage `json:"properties"`
+					} `json:"data"`
+				} `json:"properties"`
+			}
+			if err := json.Unmarshal(raw, &schema); err != nil {
+				t.Fatalf("Schema is not valid JSON: %v", err)
+			}
+			if schema.Properties.SchemaVersion.Const != event.SchemaVersion() {
+				t.Errorf("Schema version %q does not match %q", schema.Properties.SchemaVersion.Const, event.SchemaVersion())
+			}
+
+			encoded, _ := json.Marshal(event)
+			var fields map[string]interface{}
+			json.Unmarshal(encoded, &fields)
+
+			for name := range fields {
+				if _, ok := schema.Properties.Data.Properties[name]; !ok {
+					t.Errorf("Field %q is missing from the schema", name)
+				}
+			}
+			for _, name := range schema.Properties.Data.Required {
+				if _, ok := fields[name]; !ok {
+					t.Errorf("Required field %q is not produced by the struct", name)
+				}
+			}
+		})
+	}
+}
+
+func TestParseEncodings(t *testing.T) {
+	encodings, err := ParseEncodings("json, motion-trigger=protobuf")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if encodings.For(TopicMotion) != EncodingProtobuf || encodings.For(TopicMeshMessages) != EncodingJSON {
+		t.Errorf("Unexpected encodings: %+v", encodings)
+	}
+
+	if _, err := ParseEncodings("avro"); err == nil {
+		t.Error("Expected unknown encoding to be rejected")
+	}
+	if (Encodings{}).For(TopicMotion) != EncodingJSON {
+		t.Error("Expected zero value to default to JSON")
+	}
+}
+
+func TestEncodeProto(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := &HealthEvent{
+		HealthReport: HealthReport{MAC: "11:22:33:44:55:66", AdapterType: -1, Uptime: 4112},
+		HopCount:     2,
+		ReceivedAt:   Timestamp(at),
+	}
+
+	data, contentType, err := Marshal(EncodingProtobuf, "/orchistrator/dev/ttyUSB0", event, at)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if contentType != ContentTypeProtobuf {
+		t.Errorf("Expected content type %s, got %s", ContentTypeProtobuf, contentType)
+	}
+
+	var envelope eventspb.Envelope
+	if err := proto.Unmarshal(data, &envelope); err != nil {
+		t.Fatalf("Expected valid protobuf, got %v", err)
+	}
+
+	health := envelope.GetHealth()
+	if envelope.Type != TypeHealth || envelope.Subject != "11:22:33:44:55:66" || health == nil {
+		t.Fatalf("Unexpected envelope: %v", &envelope)
+	}
+	if health.Report.AdapterType != -1 || health.Report.Uptime != 4112 || health.HopCount != 2 {
+		t.Errorf("Unexpected health payload: %v", health)
+	}
+	if !envelope.Time.AsTime().Equal(at) {
+		t.Errorf("Expected time %v, got %v", at, envelope.Time.AsTime())
+	}
+}
+
+func TestDecode(t *testing.T) {
+	at := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	event := &MeshMessageEvent{
+		Direction:    "incoming",
+		DataType:     3,
+		Origin:       "11:22:33:44:55:66",
+		HopCount:     2,
+		Data:         "0a0b",
+		DataLength:   2,
+		HealthReport: &HealthReport{MAC: "11:22:33:44:55:66", Uptime: 4112},
+	}
+
+	for _, encoding := range []Encoding{EncodingJSON, EncodingProtobuf} {
+		t.Run(string(encoding), func(t *testing.T) {
+			data, contentType, err := Marshal(encoding, "/orchistrator/dev/ttyUSB0", event, at)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+
+			envelope, decoded, err := Decode(data, contentType)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if envelope.Type != TypeMeshMessage || !envelope.Time.Time().Equal(at) {
+				t.Errorf("Unexpected envelope: %+v", envelope)
+			}
+
+			message, ok := decoded.(*MeshMessageEvent)
+			if !ok {
+				t.Fatalf("Expected *MeshMessageEvent, got %T", decoded)
+			}
+			if message.Data != "0a0b" || message.HopCount != 2 || message.HealthReport == nil || message.HealthReport.Uptime != 4112 {
+				t.Errorf("Unexpected payload: %+v", message)
+			}
+		})
+	}
+
+	deviceTime, offsetMs := Timestamp(at.Add(-350*time.Millisecond)), int64(350)
+	motion := &MotionEvent{MAC: "11:22:33:44:55:66", ReceivedAt: Timestamp(at), DeviceTime: &deviceTime, OffsetMs: &offsetMs}
+	for _, encoding := range []Encoding{EncodingJSON, EncodingProtobuf} {
+		t.Run(string(encoding)+"Motion", func(t *testing.T) {
+			data, contentType, err := Marshal(encoding, "/orchistrator/dev/ttyUSB0", motion, at)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			_, decoded, err := Decode(data, contentType)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			event, ok := decoded.(*MotionEvent)
+			if !ok || event.DeviceTime == nil || !event.DeviceTime.Time().Equal(deviceTime.Time()) || event.OffsetMs == nil || *event.OffsetMs != 350 {
+				t.Errorf("Expected the device time and offset to round-trip, got %+v", decoded)
+			}
+		})
+	}
+
+	if _, _, err := Decode([]byte(`{"specversion":"0.3"}`), ""); err == nil {
+		t.Error("Expected error for unsupported specversion")
+	}
+}
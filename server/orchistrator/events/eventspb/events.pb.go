@@ -0,0 +1,964 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        v6.32.0
+// source: mesh/events.proto
+
+package eventspb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// CloudEvents 1.0 attributes plus the typed payload
+type Envelope struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Specversion   string                 `protobuf:"bytes,1,opt,name=specversion,proto3" json:"specversion,omitempty"` // always "1.0"
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`                   // UUID v4
+	Source        string                 `protobuf:"bytes,3,opt,name=source,proto3" json:"source,omitempty"`           // e.g. "/orchistrator/dev/ttyUSB0"
+	Type          string                 `protobuf:"bytes,4,opt,name=type,proto3" json:"type,omitempty"`               // e.g. "com.planetopia.mesh.motion"
+	Subject       string                 `protobuf:"bytes,5,opt,name=subject,proto3" json:"subject,omitempty"`         // MAC address the event is about
+	Time          *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=time,proto3" json:"time,omitempty"`
+	Schemaversion string                 `protobuf:"bytes,7,opt,name=schemaversion,proto3" json:"schemaversion,omitempty"` // version of the payload schema
+	// Types that are valid to be assigned to Data:
+	//
+	//	*Envelope_MeshMessage
+	//	*Envelope_Motion
+	//	*Envelope_Health
+	//	*Envelope_NodeLifecycle
+	//	*Envelope_Audit
+	Data          isEnvelope_Data `protobuf_oneof:"data"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Envelope) Reset() {
+	*x = Envelope{}
+	mi := &file_mesh_events_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Envelope) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Envelope) ProtoMessage() {}
+
+func (x *Envelope) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_events_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Envelope.ProtoReflect.Descriptor instead.
+func (*Envelope) Descriptor() ([]byte, []int) {
+	return file_mesh_events_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Envelope) GetSpecversion() string {
+	if x != nil {
+		return x.Specversion
+	}
+	return ""
+}
+
+func (x *Envelope) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Envelope) GetSource() string {
+	if x != nil {
+		return x.Source
+	}
+	return ""
+}
+
+func (x *Envelope) GetType() string {
+	if x != nil {
+		return x.Type
+	}
+	return ""
+}
+
+func (x *Envelope) GetSubject() string {
+	if x != nil {
+		return x.Subject
+	}
+	return ""
+}
+
+func (x *Envelope) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *Envelope) GetSchemaversion() string {
+	if x != nil {
+		return x.Schemaversion
+	}
+	return ""
+}
+
+func (x *Envelope) GetData() isEnvelope_Data {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Envelope) GetMeshMessage() *MeshMessageEvent {
+	if x != nil {
+		if x, ok := x.Data.(*Envelope_MeshMessage); ok {
+			return x.MeshMessage
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetMotion() *MotionEvent {
+	if x != nil {
+		if x, ok := x.Data.(*Envelope_Motion); ok {
+			return x.Motion
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetHealth() *HealthEvent {
+	if x != nil {
+		if x, ok := x.Data.(*Envelope_Health); ok {
+			return x.Health
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetNodeLifecycle() *NodeLifecycleEvent {
+	if x != nil {
+		if x, ok := x.Data.(*Envelope_NodeLifecycle); ok {
+			return x.NodeLifecycle
+		}
+	}
+	return nil
+}
+
+func (x *Envelope) GetAudit() *AuditEvent {
+	if x != nil {
+		if x, ok := x.Data.(*Envelope_Audit); ok {
+			return x.Audit
+		}
+	}
+	return nil
+}
+
+type isEnvelope_Data interface {
+	isEnvelope_Data()
+}
+
+type Envelope_MeshMessage struct {
+	MeshMessage *MeshMessageEvent `protobuf:"bytes,10,opt,name=meshMessage,proto3,oneof"`
+}
+
+type Envelope_Motion struct {
+	Motion *MotionEvent `protobuf:"bytes,11,opt,name=motion,proto3,oneof"`
+}
+
+type Envelope_Health struct {
+	Health *HealthEvent `protobuf:"bytes,12,opt,name=health,proto3,oneof"`
+}
+
+type Envelope_NodeLifecycle struct {
+	NodeLifecycle *NodeLifecycleEvent `protobuf:"bytes,13,opt,name=nodeLifecycle,proto3,oneof"`
+}
+
+type Envelope_Audit struct {
+	Audit *AuditEvent `protobuf:"bytes,14,opt,name=audit,proto3,oneof"`
+}
+
+func (*Envelope_MeshMessage) isEnvelope_Data() {}
+
+func (*Envelope_Motion) isEnvelope_Data() {}
+
+func (*Envelope_Health) isEnvelope_Data() {}
+
+func (*Envelope_NodeLifecycle) isEnvelope_Data() {}
+
+func (*Envelope_Audit) isEnvelope_Data() {}
+
+// Decoded content of an OP_HEALTH_REPORT frame
+type HealthReport struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Mac             string                 `protobuf:"bytes,1,opt,name=mac,proto3" json:"mac,omitempty"`
+	AdapterType     int32                  `protobuf:"zigzag32,2,opt,name=adapterType,proto3" json:"adapterType,omitempty"`
+	AdapterTypeName string                 `protobuf:"bytes,3,opt,name=adapterTypeName,proto3" json:"adapterTypeName,omitempty"`
+	Uptime          uint32                 `protobuf:"varint,4,opt,name=uptime,proto3" json:"uptime,omitempty"` // seconds since node boot
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *HealthReport) Reset() {
+	*x = HealthReport{}
+	mi := &file_mesh_events_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthReport) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthReport) ProtoMessage() {}
+
+func (x *HealthReport) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_events_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthReport.ProtoReflect.Descriptor instead.
+func (*HealthReport) Descriptor() ([]byte, []int) {
+	return file_mesh_events_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *HealthReport) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+func (x *HealthReport) GetAdapterType() int32 {
+	if x != nil {
+		return x.AdapterType
+	}
+	return 0
+}
+
+func (x *HealthReport) GetAdapterTypeName() string {
+	if x != nil {
+		return x.AdapterTypeName
+	}
+	return ""
+}
+
+func (x *HealthReport) GetUptime() uint32 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+// com.planetopia.mesh.message (topic mesh-messages)
+type MeshMessageEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Direction     string                 `protobuf:"bytes,1,opt,name=direction,proto3" json:"direction,omitempty"` // "incoming" or "outgoing"
+	MessageType   uint32                 `protobuf:"varint,2,opt,name=messageType,proto3" json:"messageType,omitempty"`
+	DataType      int32                  `protobuf:"zigzag32,3,opt,name=dataType,proto3" json:"dataType,omitempty"`
+	DataTypeName  string                 `protobuf:"bytes,4,opt,name=dataTypeName,proto3" json:"dataTypeName,omitempty"`
+	Origin        string                 `protobuf:"bytes,5,opt,name=origin,proto3" json:"origin,omitempty"`
+	Target        string                 `protobuf:"bytes,6,opt,name=target,proto3" json:"target,omitempty"`
+	LastHop       string                 `protobuf:"bytes,7,opt,name=lastHop,proto3" json:"lastHop,omitempty"`
+	HopCount      uint32                 `protobuf:"varint,8,opt,name=hopCount,proto3" json:"hopCount,omitempty"`
+	Data          []byte                 `protobuf:"bytes,9,opt,name=data,proto3" json:"data,omitempty"`
+	HealthReport  *HealthReport          `protobuf:"bytes,10,opt,name=healthReport,proto3" json:"healthReport,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MeshMessageEvent) Reset() {
+	*x = MeshMessageEvent{}
+	mi := &file_mesh_events_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MeshMessageEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MeshMessageEvent) ProtoMessage() {}
+
+func (x *MeshMessageEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_events_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MeshMessageEvent.ProtoReflect.Descriptor instead.
+func (*MeshMessageEvent) Descriptor() ([]byte, []int) {
+	return file_mesh_events_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *MeshMessageEvent) GetDirection() string {
+	if x != nil {
+		return x.Direction
+	}
+	return ""
+}
+
+func (x *MeshMessageEvent) GetMessageType() uint32 {
+	if x != nil {
+		return x.MessageType
+	}
+	return 0
+}
+
+func (x *MeshMessageEvent) GetDataType() int32 {
+	if x != nil {
+		return x.DataType
+	}
+	return 0
+}
+
+func (x *MeshMessageEvent) GetDataTypeName() string {
+	if x != nil {
+		return x.DataTypeName
+	}
+	return ""
+}
+
+func (x *MeshMessageEvent) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *MeshMessageEvent) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *MeshMessageEvent) GetLastHop() string {
+	if x != nil {
+		return x.LastHop
+	}
+	return ""
+}
+
+func (x *MeshMessageEvent) GetHopCount() uint32 {
+	if x != nil {
+		return x.HopCount
+	}
+	return 0
+}
+
+func (x *MeshMessageEvent) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *MeshMessageEvent) GetHealthReport() *HealthReport {
+	if x != nil {
+		return x.HealthReport
+	}
+	return nil
+}
+
+// com.planetopia.mesh.motion (topic motion-trigger)
+type MotionEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mac           string                 `protobuf:"bytes,1,opt,name=mac,proto3" json:"mac,omitempty"`
+	HopCount      uint32                 `protobuf:"varint,2,opt,name=hopCount,proto3" json:"hopCount,omitempty"`
+	LastHop       string                 `protobuf:"bytes,3,opt,name=lastHop,proto3" json:"lastHop,omitempty"`
+	Data          []byte                 `protobuf:"bytes,4,opt,name=data,proto3" json:"data,omitempty"`
+	ReceivedAt    *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=receivedAt,proto3" json:"receivedAt,omitempty"`
+	DeviceTime    *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=deviceTime,proto3" json:"deviceTime,omitempty"` // unset unless the payload carries the node's clock
+	OffsetMs      int64                  `protobuf:"zigzag64,7,opt,name=offsetMs,proto3" json:"offsetMs,omitempty"`  // receivedAt minus deviceTime, set with deviceTime
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *MotionEvent) Reset() {
+	*x = MotionEvent{}
+	mi := &file_mesh_events_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *MotionEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*MotionEvent) ProtoMessage() {}
+
+func (x *MotionEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_events_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use MotionEvent.ProtoReflect.Descriptor instead.
+func (*MotionEvent) Descriptor() ([]byte, []int) {
+	return file_mesh_events_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *MotionEvent) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+func (x *MotionEvent) GetHopCount() uint32 {
+	if x != nil {
+		return x.HopCount
+	}
+	return 0
+}
+
+func (x *MotionEvent) GetLastHop() string {
+	if x != nil {
+		return x.LastHop
+	}
+	return ""
+}
+
+func (x *MotionEvent) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *MotionEvent) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+func (x *MotionEvent) GetDeviceTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.DeviceTime
+	}
+	return nil
+}
+
+func (x *MotionEvent) GetOffsetMs() int64 {
+	if x != nil {
+		return x.OffsetMs
+	}
+	return 0
+}
+
+// com.planetopia.mesh.health (topic node-health)
+type HealthEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Report        *HealthReport          `protobuf:"bytes,1,opt,name=report,proto3" json:"report,omitempty"`
+	HopCount      uint32                 `protobuf:"varint,2,opt,name=hopCount,proto3" json:"hopCount,omitempty"`
+	Origin        string                 `protobuf:"bytes,3,opt,name=origin,proto3" json:"origin,omitempty"`
+	ReceivedAt    *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=receivedAt,proto3" json:"receivedAt,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *HealthEvent) Reset() {
+	*x = HealthEvent{}
+	mi := &file_mesh_events_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *HealthEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*HealthEvent) ProtoMessage() {}
+
+func (x *HealthEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_events_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use HealthEvent.ProtoReflect.Descriptor instead.
+func (*HealthEvent) Descriptor() ([]byte, []int) {
+	return file_mesh_events_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *HealthEvent) GetReport() *HealthReport {
+	if x != nil {
+		return x.Report
+	}
+	return nil
+}
+
+func (x *HealthEvent) GetHopCount() uint32 {
+	if x != nil {
+		return x.HopCount
+	}
+	return 0
+}
+
+func (x *HealthEvent) GetOrigin() string {
+	if x != nil {
+		return x.Origin
+	}
+	return ""
+}
+
+func (x *HealthEvent) GetReceivedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ReceivedAt
+	}
+	return nil
+}
+
+// com.planetopia.mesh.node (topic node-lifecycle)
+type NodeLifecycleEvent struct {
+	state          protoimpl.MessageState `protogen:"open.v1"`
+	Mac            string                 `protobuf:"bytes,1,opt,name=mac,proto3" json:"mac,omitempty"`
+	State          string                 `protobuf:"bytes,2,opt,name=state,proto3" json:"state,omitempty"`          // "online" or "offline"
+	FirstSeen      bool                   `protobuf:"varint,3,opt,name=firstSeen,proto3" json:"firstSeen,omitempty"` // true the first time the server sees this node
+	LastSeen       *timestamppb.Timestamp `protobuf:"bytes,4,opt,name=lastSeen,proto3" json:"lastSeen,omitempty"`
+	OfflineAfterMs int64                  `protobuf:"varint,5,opt,name=offlineAfterMs,proto3" json:"offlineAfterMs,omitempty"` // health timeout used to decide the state
+	unknownFields  protoimpl.UnknownFields
+	sizeCache      protoimpl.SizeCache
+}
+
+func (x *NodeLifecycleEvent) Reset() {
+	*x = NodeLifecycleEvent{}
+	mi := &file_mesh_events_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *NodeLifecycleEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*NodeLifecycleEvent) ProtoMessage() {}
+
+func (x *NodeLifecycleEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_events_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use NodeLifecycleEvent.ProtoReflect.Descriptor instead.
+func (*NodeLifecycleEvent) Descriptor() ([]byte, []int) {
+	return file_mesh_events_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *NodeLifecycleEvent) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+func (x *NodeLifecycleEvent) GetState() string {
+	if x != nil {
+		return x.State
+	}
+	return ""
+}
+
+func (x *NodeLifecycleEvent) GetFirstSeen() bool {
+	if x != nil {
+		return x.FirstSeen
+	}
+	return false
+}
+
+func (x *NodeLifecycleEvent) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *NodeLifecycleEvent) GetOfflineAfterMs() int64 {
+	if x != nil {
+		return x.OfflineAfterMs
+	}
+	return 0
+}
+
+// com.planetopia.mesh.audit (topic audit)
+type AuditEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Action        string                 `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`       // e.g. "configureNode"
+	Interface     string                 `protobuf:"bytes,2,opt,name=interface,proto3" json:"interface,omitempty"` // e.g. "http"
+	Actor         string                 `protobuf:"bytes,3,opt,name=actor,proto3" json:"actor,omitempty"`         // principal ID, empty if unauthenticated
+	ActorName     string                 `protobuf:"bytes,4,opt,name=actorName,proto3" json:"actorName,omitempty"`
+	Role          string                 `protobuf:"bytes,5,opt,name=role,proto3" json:"role,omitempty"`
+	AuthMethod    string                 `protobuf:"bytes,6,opt,name=authMethod,proto3" json:"authMethod,omitempty"`
+	SourceIp      string                 `protobuf:"bytes,7,opt,name=sourceIp,proto3" json:"sourceIp,omitempty"`
+	Method        string                 `protobuf:"bytes,8,opt,name=method,proto3" json:"method,omitempty"`
+	Path          string                 `protobuf:"bytes,9,opt,name=path,proto3" json:"path,omitempty"`
+	Target        string                 `protobuf:"bytes,10,opt,name=target,proto3" json:"target,omitempty"` // MAC of the targeted node
+	Params        *structpb.Struct       `protobuf:"bytes,11,opt,name=params,proto3" json:"params,omitempty"`
+	Outcome       string                 `protobuf:"bytes,12,opt,name=outcome,proto3" json:"outcome,omitempty"` // "success", "failure" or "denied"
+	Status        int32                  `protobuf:"varint,13,opt,name=status,proto3" json:"status,omitempty"`
+	Error         string                 `protobuf:"bytes,14,opt,name=error,proto3" json:"error,omitempty"`
+	Time          *timestamppb.Timestamp `protobuf:"bytes,15,opt,name=time,proto3" json:"time,omitempty"`
+	DurationMs    int64                  `protobuf:"varint,16,opt,name=durationMs,proto3" json:"durationMs,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuditEvent) Reset() {
+	*x = AuditEvent{}
+	mi := &file_mesh_events_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuditEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuditEvent) ProtoMessage() {}
+
+func (x *AuditEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_events_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuditEvent.ProtoReflect.Descriptor instead.
+func (*AuditEvent) Descriptor() ([]byte, []int) {
+	return file_mesh_events_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *AuditEvent) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetInterface() string {
+	if x != nil {
+		return x.Interface
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetActor() string {
+	if x != nil {
+		return x.Actor
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetActorName() string {
+	if x != nil {
+		return x.ActorName
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetRole() string {
+	if x != nil {
+		return x.Role
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetAuthMethod() string {
+	if x != nil {
+		return x.AuthMethod
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetSourceIp() string {
+	if x != nil {
+		return x.SourceIp
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetMethod() string {
+	if x != nil {
+		return x.Method
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetPath() string {
+	if x != nil {
+		return x.Path
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetTarget() string {
+	if x != nil {
+		return x.Target
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetParams() *structpb.Struct {
+	if x != nil {
+		return x.Params
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetStatus() int32 {
+	if x != nil {
+		return x.Status
+	}
+	return 0
+}
+
+func (x *AuditEvent) GetError() string {
+	if x != nil {
+		return x.Error
+	}
+	return ""
+}
+
+func (x *AuditEvent) GetTime() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Time
+	}
+	return nil
+}
+
+func (x *AuditEvent) GetDurationMs() int64 {
+	if x != nil {
+		return x.DurationMs
+	}
+	return 0
+}
+
+var File_mesh_events_proto protoreflect.FileDescriptor
+
+const file_mesh_events_proto_rawDesc = "" +
+	"\n" +
+	"\x11mesh/events.proto\x12\vmesh.events\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\x85\x04\n" +
+	"\bEnvelope\x12 \n" +
+	"\vspecversion\x18\x01 \x01(\tR\vspecversion\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x16\n" +
+	"\x06source\x18\x03 \x01(\tR\x06source\x12\x12\n" +
+	"\x04type\x18\x04 \x01(\tR\x04type\x12\x18\n" +
+	"\asubject\x18\x05 \x01(\tR\asubject\x12.\n" +
+	"\x04time\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12$\n" +
+	"\rschemaversion\x18\a \x01(\tR\rschemaversion\x12A\n" +
+	"\vmeshMessage\x18\n" +
+	" \x01(\v2\x1d.mesh.events.MeshMessageEventH\x00R\vmeshMessage\x122\n" +
+	"\x06motion\x18\v \x01(\v2\x18.mesh.events.MotionEventH\x00R\x06motion\x122\n" +
+	"\x06health\x18\f \x01(\v2\x18.mesh.events.HealthEventH\x00R\x06health\x12G\n" +
+	"\rnodeLifecycle\x18\r \x01(\v2\x1f.mesh.events.NodeLifecycleEventH\x00R\rnodeLifecycle\x12/\n" +
+	"\x05audit\x18\x0e \x01(\v2\x17.mesh.events.AuditEventH\x00R\x05auditB\x06\n" +
+	"\x04data\"\x84\x01\n" +
+	"\fHealthReport\x12\x10\n" +
+	"\x03mac\x18\x01 \x01(\tR\x03mac\x12 \n" +
+	"\vadapterType\x18\x02 \x01(\x11R\vadapterType\x12(\n" +
+	"\x0fadapterTypeName\x18\x03 \x01(\tR\x0fadapterTypeName\x12\x16\n" +
+	"\x06uptime\x18\x04 \x01(\rR\x06uptime\"\xcb\x02\n" +
+	"\x10MeshMessageEvent\x12\x1c\n" +
+	"\tdirection\x18\x01 \x01(\tR\tdirection\x12 \n" +
+	"\vmessageType\x18\x02 \x01(\rR\vmessageType\x12\x1a\n" +
+	"\bdataType\x18\x03 \x01(\x11R\bdataType\x12\"\n" +
+	"\fdataTypeName\x18\x04 \x01(\tR\fdataTypeName\x12\x16\n" +
+	"\x06origin\x18\x05 \x01(\tR\x06origin\x12\x16\n" +
+	"\x06target\x18\x06 \x01(\tR\x06target\x12\x18\n" +
+	"\alastHop\x18\a \x01(\tR\alastHop\x12\x1a\n" +
+	"\bhopCount\x18\b \x01(\rR\bhopCount\x12\x12\n" +
+	"\x04data\x18\t \x01(\fR\x04data\x12=\n" +
+	"\fhealthReport\x18\n" +
+	" \x01(\v2\x19.mesh.events.HealthReportR\fhealthReport\"\xfd\x01\n" +
+	"\vMotionEvent\x12\x10\n" +
+	"\x03mac\x18\x01 \x01(\tR\x03mac\x12\x1a\n" +
+	"\bhopCount\x18\x02 \x01(\rR\bhopCount\x12\x18\n" +
+	"\alastHop\x18\x03 \x01(\tR\alastHop\x12\x12\n" +
+	"\x04data\x18\x04 \x01(\fR\x04data\x12:\n" +
+	"\n" +
+	"receivedAt\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"receivedAt\x12:\n" +
+	"\n" +
+	"deviceTime\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"deviceTime\x12\x1a\n" +
+	"\boffsetMs\x18\a \x01(\x12R\boffsetMs\"\xb0\x01\n" +
+	"\vHealthEvent\x121\n" +
+	"\x06report\x18\x01 \x01(\v2\x19.mesh.events.HealthReportR\x06report\x12\x1a\n" +
+	"\bhopCount\x18\x02 \x01(\rR\bhopCount\x12\x16\n" +
+	"\x06origin\x18\x03 \x01(\tR\x06origin\x12:\n" +
+	"\n" +
+	"receivedAt\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"receivedAt\"\xba\x01\n" +
+	"\x12NodeLifecycleEvent\x12\x10\n" +
+	"\x03mac\x18\x01 \x01(\tR\x03mac\x12\x14\n" +
+	"\x05state\x18\x02 \x01(\tR\x05state\x12\x1c\n" +
+	"\tfirstSeen\x18\x03 \x01(\bR\tfirstSeen\x126\n" +
+	"\blastSeen\x18\x04 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12&\n" +
+	"\x0eofflineAfterMs\x18\x05 \x01(\x03R\x0eofflineAfterMs\"\xd3\x03\n" +
+	"\n" +
+	"AuditEvent\x12\x16\n" +
+	"\x06action\x18\x01 \x01(\tR\x06action\x12\x1c\n" +
+	"\tinterface\x18\x02 \x01(\tR\tinterface\x12\x14\n" +
+	"\x05actor\x18\x03 \x01(\tR\x05actor\x12\x1c\n" +
+	"\tactorName\x18\x04 \x01(\tR\tactorName\x12\x12\n" +
+	"\x04role\x18\x05 \x01(\tR\x04role\x12\x1e\n" +
+	"\n" +
+	"authMethod\x18\x06 \x01(\tR\n" +
+	"authMethod\x12\x1a\n" +
+	"\bsourceIp\x18\a \x01(\tR\bsourceIp\x12\x16\n" +
+	"\x06method\x18\b \x01(\tR\x06method\x12\x12\n" +
+	"\x04path\x18\t \x01(\tR\x04path\x12\x16\n" +
+	"\x06target\x18\n" +
+	" \x01(\tR\x06target\x12/\n" +
+	"\x06params\x18\v \x01(\v2\x17.google.protobuf.StructR\x06params\x12\x18\n" +
+	"\aoutcome\x18\f \x01(\tR\aoutcome\x12\x16\n" +
+	"\x06status\x18\r \x01(\x05R\x06status\x12\x14\n" +
+	"\x05error\x18\x0e \x01(\tR\x05error\x12.\n" +
+	"\x04time\x18\x0f \x01(\v2\x1a.google.protobuf.TimestampR\x04time\x12\x1e\n" +
+	"\n" +
+	"durationMs\x18\x10 \x01(\x03R\n" +
+	"durationMsB7Z5github.com/superbrobenji/motionServer/events/eventspbb\x06proto3"
+
+var (
+	file_mesh_events_proto_rawDescOnce sync.Once
+	file_mesh_events_proto_rawDescData []byte
+)
+
+func file_mesh_events_proto_rawDescGZIP() []byte {
+	file_mesh_events_proto_rawDescOnce.Do(func() {
+		file_mesh_events_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_mesh_events_proto_rawDesc), len(file_mesh_events_proto_rawDesc)))
+	})
+	return file_mesh_events_proto_rawDescData
+}
+
+var file_mesh_events_proto_msgTypes = make([]protoimpl.MessageInfo, 7)
+var file_mesh_events_proto_goTypes = []any{
+	(*Envelope)(nil),              // 0: mesh.events.Envelope
+	(*HealthReport)(nil),          // 1: mesh.events.HealthReport
+	(*MeshMessageEvent)(nil),      // 2: mesh.events.MeshMessageEvent
+	(*MotionEvent)(nil),           // 3: mesh.events.MotionEvent
+	(*HealthEvent)(nil),           // 4: mesh.events.HealthEvent
+	(*NodeLifecycleEvent)(nil),    // 5: mesh.events.NodeLifecycleEvent
+	(*AuditEvent)(nil),            // 6: mesh.events.AuditEvent
+	(*timestamppb.Timestamp)(nil), // 7: google.protobuf.Timestamp
+	(*structpb.Struct)(nil),       // 8: google.protobuf.Struct
+}
+var file_mesh_events_proto_depIdxs = []int32{
+	7,  // 0: mesh.events.Envelope.time:type_name -> google.protobuf.Timestamp
+	2,  // 1: mesh.events.Envelope.meshMessage:type_name -> mesh.events.MeshMessageEvent
+	3,  // 2: mesh.events.Envelope.motion:type_name -> mesh.events.MotionEvent
+	4,  // 3: mesh.events.Envelope.health:type_name -> mesh.events.HealthEvent
+	5,  // 4: mesh.events.Envelope.nodeLifecycle:type_name -> mesh.events.NodeLifecycleEvent
+	6,  // 5: mesh.events.Envelope.audit:type_name -> mesh.events.AuditEvent
+	1,  // 6: mesh.events.MeshMessageEvent.healthReport:type_name -> mesh.events.HealthReport
+	7,  // 7: mesh.events.MotionEvent.receivedAt:type_name -> google.protobuf.Timestamp
+	7,  // 8: mesh.events.MotionEvent.deviceTime:type_name -> google.protobuf.Timestamp
+	1,  // 9: mesh.events.HealthEvent.report:type_name -> mesh.events.HealthReport
+	7,  // 10: mesh.events.HealthEvent.receivedAt:type_name -> google.protobuf.Timestamp
+	7,  // 11: mesh.events.NodeLifecycleEvent.lastSeen:type_name -> google.protobuf.Timestamp
+	8,  // 12: mesh.events.AuditEvent.params:type_name -> google.protobuf.Struct
+	7,  // 13: mesh.events.AuditEvent.time:type_name -> google.protobuf.Timestamp
+	14, // [14:14] is the sub-list for method output_type
+	14, // [14:14] is the sub-list for method input_type
+	14, // [14:14] is the sub-list for extension type_name
+	14, // [14:14] is the sub-list for extension extendee
+	0,  // [0:14] is the sub-list for field type_name
+}
+
+func init() { file_mesh_events_proto_init() }
+func file_mesh_events_proto_init() {
+	if File_mesh_events_proto != nil {
+		return
+	}
+	file_mesh_events_proto_msgTypes[0].OneofWrappers = []any{
+		(*Envelope_MeshMessage)(nil),
+		(*Envelope_Motion)(nil),
+		(*Envelope_Health)(nil),
+		(*Envelope_NodeLifecycle)(nil),
+		(*Envelope_Audit)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mesh_events_proto_rawDesc), len(file_mesh_events_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   7,
+			NumExtensions: 0,
+			NumServices:   0,
+		},
+		GoTypes:           file_mesh_events_proto_goTypes,
+		DependencyIndexes: file_mesh_events_proto_depIdxs,
+		MessageInfos:      file_mesh_events_proto_msgTypes,
+	}.Build()
+	File_mesh_events_proto = out.File
+	file_mesh_events_proto_goTypes = nil
+	file_mesh_events_proto_depIdxs = nil
+}
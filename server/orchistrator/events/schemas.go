@@ -0,0 +1,33 @@
+package events
+
+import (
+	"embed"
+	"path"
+	"sort"
+	"strings"
+)
+
+// JSON Schemas for every event type, named <type>.json
+//
+//go:embed schemas/*.json
+var schemaFS embed.FS
+
+// Schema returns the JSON Schema document for a CloudEvents type
+func Schema(eventType string) ([]byte, bool) {
+	data, err := schemaFS.ReadFile(path.Join("schemas", eventType+".json"))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// SchemaTypes lists the event types that have a published schema
+func SchemaTypes() []string {
+	entries, _ := schemaFS.ReadDir("schemas")
+	types := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		types = append(types, strings.TrimSuffix(entry.Name(), ".json"))
+	}
+	sort.Strings(types)
+	return types
+}
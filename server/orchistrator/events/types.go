@@ -0,0 +1,123 @@
+package events
+
+// Topics the orchestrator publishes to
+const (
+	TopicMeshMessages  = "mesh-messages"
+	TopicMotion        = "motion-trigger"
+	TopicHealth        = "node-health"
+	TopicNodeLifecycle = "node-lifecycle"
+	TopicAudit         = "audit"
+)
+
+// CloudEvents types of the published events
+const (
+	TypeMeshMessage   = "com.planetopia.mesh.message"
+	TypeMotion        = "com.planetopia.mesh.motion"
+	TypeHealth        = "com.planetopia.mesh.health"
+	TypeNodeLifecycle = "com.planetopia.mesh.node"
+	TypeAudit         = "com.planetopia.mesh.audit"
+)
+
+// Node lifecycle states
+const (
+	NodeOnline  = "online"
+	NodeOffline = "offline"
+)
+
+// Audit outcomes
+const (
+	AuditSuccess = "success" // The action was carried out
+	AuditFailure = "failure" // The action was attempted and failed
+	AuditDenied  = "denied"  // The caller was not authenticated or authorized
+)
+
+// MeshMessageEvent records a raw mesh frame sent or received over serial
+type MeshMessageEvent struct {
+	Direction    string        `json:"direction"` // "incoming" or "outgoing"
+	MessageType  uint32        `json:"messageType"`
+	DataType     int32         `json:"dataType"`
+	DataTypeName string        `json:"dataTypeName"`
+	Origin       string        `json:"origin"`
+	Target       string        `json:"target"`
+	LastHop      string        `json:"lastHop"`
+	HopCount     uint32        `json:"hopCount"`
+	Data         string        `json:"data"` // hex encoded payload
+	DataLength   int           `json:"dataLength"`
+	HealthReport *HealthReport `json:"healthReport,omitempty"`
+}
+
+func (e *MeshMessageEvent) EventType() string     { return TypeMeshMessage }
+func (e *MeshMessageEvent) SchemaVersion() string { return "1.0" }
+func (e *MeshMessageEvent) Subject() string       { return e.Origin }
+
+// MotionEvent is published when a PIR node reports motion
+type MotionEvent struct {
+	MAC        string     `json:"mac"`
+	HopCount   uint32     `json:"hopCount"`
+	LastHop    string     `json:"lastHop"`
+	Data       string     `json:"data"` // hex encoded PIR payload
+	ReceivedAt Timestamp  `json:"receivedAt"`
+	DeviceTime *Timestamp `json:"deviceTime,omitempty"` // Node clock at detection, when the payload carries it
+	OffsetMs   *int64     `json:"offsetMs,omitempty"`   // ReceivedAt minus DeviceTime
+}
+
+func (e *MotionEvent) EventType() string     { return TypeMotion }
+func (e *MotionEvent) SchemaVersion() string { return "1.0" }
+func (e *MotionEvent) Subject() string       { return e.MAC }
+
+// HealthReport is the decoded content of an OP_HEALTH_REPORT frame
+type HealthReport struct {
+	MAC             string `json:"mac"`
+	AdapterType     int32  `json:"adapterType"`
+	AdapterTypeName string `json:"adapterTypeName"`
+	Uptime          uint32 `json:"uptime"`
+}
+
+// HealthEvent is published for every health report received from a node
+type HealthEvent struct {
+	HealthReport
+	HopCount   uint32    `json:"hopCount"`
+	Origin     string    `json:"origin"`
+	ReceivedAt Timestamp `json:"receivedAt"`
+}
+
+func (e *HealthEvent) EventType() string     { return TypeHealth }
+func (e *HealthEvent) SchemaVersion() string { return "1.0" }
+func (e *HealthEvent) Subject() string       { return e.MAC }
+
+// NodeLifecycleEvent is published when a node comes online or goes offline
+type NodeLifecycleEvent struct {
+	MAC            string    `json:"mac"`
+	State          string    `json:"state"`
+	FirstSeen      bool      `json:"firstSeen"`
+	LastSeen       Timestamp `json:"lastSeen"`
+	OfflineAfterMs int64     `json:"offlineAfterMs"`
+}
+
+func (e *NodeLifecycleEvent) EventType() string     { return TypeNodeLifecycle }
+func (e *NodeLifecycleEvent) SchemaVersion() string { return "1.0" }
+func (e *NodeLifecycleEvent) Subject() string       { return e.MAC }
+
+// AuditEvent records a control action and who requested it
+type AuditEvent struct {
+	Action     string                 `json:"action"`    // e.g. "configureNode"
+	Interface  string                 `json:"interface"` // e.g. "http"
+	Actor      string                 `json:"actor"`     // Principal ID, empty if unauthenticated
+	ActorName  string                 `json:"actorName,omitempty"`
+	Role       string                 `json:"role,omitempty"`
+	AuthMethod string                 `json:"authMethod,omitempty"`
+	SourceIP   string                 `json:"sourceIp,omitempty"`
+	Method     string                 `json:"method,omitempty"`
+	Path       string                 `json:"path,omitempty"`
+	Target     string                 `json:"target,omitempty"` // MAC of the targeted node
+	Params     map[string]interface{} `json:"params,omitempty"`
+	Outcome    string                 `json:"outcome"`
+	Status     int                    `json:"status,omitempty"`
+	Error      string                 `json:"error,omitempty"`
+	Time       Timestamp              `json:"time"`
+	DurationMs int64                  `json:"durationMs"`
+}
+
+func (e *AuditEvent) EventType() string     { return TypeAudit }
+func (e *AuditEvent) SchemaVersion() string { return "1.0" }
+func (e *AuditEvent) Subject() string       { return e.Target }
@@ -1,20 +1,29 @@
 package main
 
 import (
+	"context"
 	"flag"
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
 	"syscall"
 	"time"
 
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/superbrobenji/motionServer/atomicfile"
 	EventStore "github.com/superbrobenji/motionServer/eventStore"
+	"github.com/superbrobenji/motionServer/events"
+	"github.com/superbrobenji/motionServer/auth"
 	"github.com/superbrobenji/motionServer/mesh"
 )
 
 var (
-	broker  = "kafka:9092" // Use docker compose service name - internal port
-	groupId = "1"
+	defaultEvents = "kafka://kafka:9092" // Use docker compose service name - internal port
+	groupId       = "1"
 )
 
 func main() {
@@ -22,49 +31,147 @@ func main() {
 	serialPort := flag.String("serial", "/dev/ttyUSB0", "Serial port for mesh communication")
 	baudRate := flag.Int("baud", 115200, "Serial baud rate")
 	apiPort := flag.Int("port", 8080, "HTTP API port")
+	grpcPort := flag.Int("grpc-port", 9090, "gRPC API port (0 disables the gRPC API)")
+	eventSinks := flag.String("events", defaultEvents, "Comma-separated event sinks (kafka://host:port, file:///dir, stdout://, memory://?size=N)")
+	subscribe := flag.String("subscribe", "", "Comma-separated topics to consume and log (e.g. motion-trigger,node-lifecycle)")
+	eventEncoding := flag.String("event-encoding", "json", "Event encoding: json or protobuf, optionally per topic (e.g. json,motion-trigger=protobuf)")
+	replay := flag.String("replay", "", "Rebuild node state from mesh-messages on startup: earliest, offset:N, RFC3339 time or duration (e.g. 24h)")
+	authEnabled := flag.Bool("auth", true, "Require API keys or JWTs on the HTTP and gRPC APIs")
+	apiKeysFile := flag.String("api-keys", "", "File to persist API keys in (keys are kept in memory if empty)")
+	adminKey := flag.String("admin-key", "", "Static admin API key")
+	jwtKeyFile := flag.String("jwt-key", "", "File holding the HS256 key for bearer JWTs (JWTs are rejected if empty)")
+	jwtIssuer := flag.String("jwt-issuer", "", "Required iss claim of bearer JWTs")
+	auditLogFile := flag.String("audit-log", "", "Append-only audit log file (entries are kept in memory if empty)")
+	motionLogFile := flag.String("motion-log", "", "File to persist motion history in (history is kept in memory only if empty)")
+	motionHistorySize := flag.Int("motion-history", 10000, "Number of recent motion detections kept for /motion queries")
+	webhooksFile := flag.String("webhooks", "", "File to persist webhooks in (webhooks are kept in memory if empty)")
+	meshKeysFile := flag.String("mesh-keys", "", "Mesh key file; signs commands and requires signed health and PIR frames (created if missing, security is off if empty)")
+	pingInterval := flag.Duration("ping-interval", 0, "Ping every online node this often to record its latency (0 disables)")
+	timeSyncInterval := flag.Duration("time-sync-interval", 10*time.Minute, "Broadcast the server's clock to the nodes this often (0 disables)")
+	readyWindow := flag.Duration("ready-window", 2*time.Minute, "Report not ready on /readyz if no serial frame arrives within this window")
+	tlsCert := flag.String("tls-cert", "", "TLS certificate file; serves the HTTP API over HTTPS together with -tls-key")
+	tlsKey := flag.String("tls-key", "", "TLS private key file")
+	corsOrigins := flag.String("cors-origins", "http://localhost:3000", "Comma-separated browser origins allowed to call the HTTP API (* allows any)")
+	readTimeout := flag.Duration("http-read-timeout", 30*time.Second, "Maximum time to read an HTTP request")
+	writeTimeout := flag.Duration("http-write-timeout", 30*time.Second, "Maximum time to write an HTTP response (event streams are exempt)")
+	idleTimeout := flag.Duration("http-idle-timeout", 2*time.Minute, "How long idle keep-alive connections stay open")
+	shutdownTimeout := flag.Duration("shutdown-timeout", 15*time.Second, "How long to wait for in-flight API requests on shutdown")
+	mqttBroker := flag.String("mqtt-broker", "", "MQTT broker for the Home Assistant bridge, e.g. tcp://localhost:1883 (the bridge is disabled if empty)")
+	mqttUsername := flag.String("mqtt-username", "", "MQTT username")
+	mqttPassword := flag.String("mqtt-password", "", "MQTT password")
+	mqttClientID := flag.String("mqtt-client-id", "planetopia-mesh", "MQTT client ID")
+	mqttTopicPrefix := flag.String("mqtt-topic-prefix", "planetopia", "Root of the bridge's state and command topics")
+	mqttDiscoveryPrefix := flag.String("mqtt-discovery-prefix", "homeassistant", "Home Assistant MQTT discovery prefix")
 	flag.Parse()
 
+	encodings, err := events.ParseEncodings(*eventEncoding)
+	if err != nil {
+		log.Fatalf("Invalid -event-encoding: %v", err)
+	}
+
+	replayFrom, err := EventStore.ParseReplayFrom(*replay)
+	if err != nil {
+		log.Fatalf("Invalid -replay: %v", err)
+	}
+
 	log.Printf("Starting Planetopia Motion Sensor Server")
 	log.Printf("Serial: %s @ %d baud", *serialPort, *baudRate)
 	log.Printf("API Port: %d", *apiPort)
-	log.Printf("Kafka Broker: %s", broker)
+	log.Printf("gRPC Port: %d", *grpcPort)
+	log.Printf("Event sinks: %s (encoding: %s)", *eventSinks, *eventEncoding)
 
 	// Setup event store with retry logic
-	var eventStore EventStore.EventStore_interface
+	eventStore, err := EventStore.Open(*eventSinks, groupId)
+	if err != nil {
+		log.Fatalf("Invalid -events configuration: %v", err)
+	}
 	maxRetries := 3
 	retryDelay := 1 * time.Second
 	
-	log.Printf("Attempting to connect to Kafka with %d retries...", maxRetries)
+	log.Printf("Attempting to connect event sinks with %d retries...", maxRetries)
 	for i := 0; i < maxRetries; i++ {
-		eventStore = EventStore.New(broker, groupId)
-		err := eventStore.Connect()
+		err = eventStore.Connect()
 		if err == nil {
-			log.Printf("Connected to Kafka successfully on attempt %d", i+1)
+			log.Printf("Connected event sinks successfully on attempt %d", i+1)
 			break
 		}
 		
-		log.Printf("Kafka connection attempt %d failed: %v", i+1, err)
+		log.Printf("Event sink connection attempt %d failed: %v", i+1, err)
 		if i < maxRetries-1 {
 			log.Printf("Retrying in %v...", retryDelay)
 			time.Sleep(retryDelay)
-			eventStore = nil
 		}
 	}
 	
-	if eventStore == nil {
-		log.Printf("Warning: Failed to connect to Kafka after %d attempts", maxRetries)
-		log.Printf("Continuing without Kafka integration...")
+	if err != nil {
+		// Keep the sinks that did connect, e.g. file sinks while Kafka is down
+		var failures []error
+		eventStore, failures = EventStore.DropUnconnected(eventStore, err)
+		for _, failure := range failures {
+			log.Printf("Warning: Dropping event sink after %d attempts: %v", maxRetries, failure)
+		}
+		if eventStore == nil {
+			log.Printf("Continuing without event integration...")
+		}
+	}
+
+	// Setup audit log
+	auditLog := mesh.NewAuditLog()
+	if *auditLogFile != "" {
+		if auditLog, err = mesh.OpenAuditLog(*auditLogFile); err != nil {
+			log.Fatalf("Failed to open audit log: %v", err)
+		}
+	}
+
+	// Setup motion history
+	motionHistory := mesh.NewMotionHistory(*motionHistorySize)
+	if *motionLogFile != "" {
+		if motionHistory, err = mesh.OpenMotionHistory(*motionLogFile, *motionHistorySize); err != nil {
+			log.Fatalf("Failed to open motion log: %v", err)
+		}
+	}
+
+	// Setup webhooks
+	webhooks, err := mesh.NewWebhookManager(mesh.WebhookConfig{Path: *webhooksFile})
+	if err != nil {
+		log.Fatalf("Failed to load webhooks: %v", err)
+	}
+
+	// Setup mesh security
+	var security *mesh.MeshSecurity
+	if *meshKeysFile != "" {
+		if security, err = mesh.OpenMeshSecurity(*meshKeysFile); err != nil {
+			log.Fatalf("Failed to open mesh keys: %v", err)
+		}
 	}
 
 	// Setup mesh server
 	meshConfig := mesh.MeshServerConfig{
-		SerialPort:    *serialPort,
-		BaudRate:      *baudRate,
-		HealthTimeout: 30 * time.Second,
-		EventStore:    eventStore,
+		SerialPort:     *serialPort,
+		BaudRate:       *baudRate,
+		HealthTimeout:  30 * time.Second,
+		EventStore:     eventStore,
+		EventEncodings: encodings,
+		AuditLog:       auditLog,
+		MotionHistory:  motionHistory,
+		Webhooks:       webhooks,
+		FrameWindow:    *readyWindow,
+		PingInterval:   *pingInterval,
+		TimeSyncInterval: *timeSyncInterval,
+		Security:       security,
 	}
 
 	meshServer := mesh.NewMeshServer(meshConfig)
+	prometheus.MustRegister(mesh.NewNodeCollector(meshServer.GetNodeRegistry()))
+
+	// Rebuild state from the event log before processing live frames
+	if *replay != "" && eventStore != nil {
+		replayCtx, replayCancel := context.WithTimeout(context.Background(), 5*time.Minute)
+		if _, err := meshServer.Replay(replayCtx, replayFrom); err != nil {
+			log.Printf("Warning: Failed to replay event log: %v", err)
+		}
+		replayCancel()
+	}
 
 	// Start mesh server
 	if err := meshServer.Start(); err != nil {
@@ -81,13 +188,79 @@ func main() {
 		})
 	}
 
+	// Start event subscribers
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var subscribers sync.WaitGroup
+	if topics := splitList(*subscribe); len(topics) > 0 && eventStore != nil {
+		subscribers.Add(1)
+		go func() {
+			defer subscribers.Done()
+			log.Printf("Subscribing to topics: %v", topics)
+			if err := eventStore.SubscribeToEvents(ctx, topics, logEvent); err != nil {
+				log.Printf("Event subscription stopped: %v", err)
+			}
+		}()
+	}
+
+	// Setup API authentication
+	var authenticator *auth.Authenticator
+	if *authEnabled {
+		authenticator, err = setupAuth(*apiKeysFile, *adminKey, *jwtKeyFile, *jwtIssuer)
+		if err != nil {
+			log.Fatalf("Failed to set up authentication: %v", err)
+		}
+	} else {
+		log.Printf("Warning: API authentication is disabled")
+	}
+
 	// Start HTTP API server
+	httpServer, err := mesh.NewHTTPServer(meshServer, authenticator, mesh.HTTPConfig{
+		Port:           *apiPort,
+		TLSCertFile:    *tlsCert,
+		TLSKeyFile:     *tlsKey,
+		AllowedOrigins: splitList(*corsOrigins),
+		ReadTimeout:    *readTimeout,
+		WriteTimeout:   *writeTimeout,
+		IdleTimeout:    *idleTimeout,
+	})
+	if err != nil {
+		log.Fatalf("Failed to configure API server: %v", err)
+	}
 	go func() {
-		if err := mesh.StartAPIServer(meshServer, *apiPort); err != nil {
+		if err := httpServer.ListenAndServe(); err != nil {
 			log.Printf("API server error: %v", err)
 		}
 	}()
 
+	// Start gRPC API server
+	var grpcServer *mesh.GRPCServer
+	if *grpcPort != 0 {
+		grpcServer = mesh.NewGRPCServer(meshServer, authenticator)
+		go func() {
+			if err := grpcServer.ListenAndServe(*grpcPort); err != nil {
+				log.Printf("gRPC server error: %v", err)
+			}
+		}()
+	}
+
+	// Start MQTT bridge
+	var mqttBridge *mesh.MQTTBridge
+	if *mqttBroker != "" {
+		mqttBridge = mesh.NewMQTTBridge(meshServer, mesh.MQTTConfig{
+			Broker:          *mqttBroker,
+			ClientID:        *mqttClientID,
+			Username:        *mqttUsername,
+			Password:        *mqttPassword,
+			TopicPrefix:     *mqttTopicPrefix,
+			DiscoveryPrefix: *mqttDiscoveryPrefix,
+		})
+		if err := mqttBridge.Start(); err != nil {
+			log.Printf("Warning: MQTT bridge: %v", err)
+		}
+	}
+
 	// Setup graceful shutdown
 
 	// Handle shutdown signals
@@ -100,6 +273,30 @@ func main() {
 	<-sigChan
 	log.Printf("Shutdown signal received, stopping services...")
 
+	// Drain API requests before stopping the mesh server they use
+	shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), *shutdownTimeout)
+	defer cancelShutdown()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Error shutting down API server: %v", err)
+	}
+	if grpcServer != nil {
+		if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+			log.Printf("Error shutting down gRPC server: %v", err)
+		}
+	}
+
+	// Stop taking MQTT commands before the mesh server stops
+	if mqttBridge != nil {
+		mqttBridge.Stop()
+	}
+
+	// Stop subscribers before closing the event store
+	cancel()
+	subscribers.Wait()
+
+	// Abort any firmware rollout, telling the node being updated
+	meshServer.GetOTA().Close()
+
 	// Stop mesh server
 	if meshServer.IsRunning() {
 		if err := meshServer.Stop(); err != nil {
@@ -107,5 +304,90 @@ func main() {
 		}
 	}
 
+	webhooks.Close()
+
+	// Save the counters accepted from each node
+	if security != nil {
+		if err := security.Close(); err != nil {
+			log.Printf("Error saving mesh keys: %v", err)
+		}
+	}
+
+	if eventStore != nil {
+		if err := eventStore.Close(); err != nil {
+			log.Printf("Error closing event sinks: %v", err)
+		}
+	}
+
+	if err := auditLog.Close(); err != nil {
+		log.Printf("Error closing audit log: %v", err)
+	}
+
+	if err := motionHistory.Close(); err != nil {
+		log.Printf("Error closing motion log: %v", err)
+	}
+
 	log.Printf("Server shutdown complete")
 }
+
+// splitList parses a comma-separated list, dropping empty entries
+func splitList(spec string) []string {
+	var items []string
+	for _, item := range strings.Split(spec, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// logEvent logs events received by the -subscribe consumer
+func logEvent(ctx context.Context, msg EventStore.Message) error {
+	if msg.ContentType == events.ContentTypeProtobuf {
+		log.Printf("[SUBSCRIBE] %s@%d: %d bytes of %s", msg.Topic, msg.Offset, len(msg.Value), msg.ContentType)
+		return nil
+	}
+	log.Printf("[SUBSCRIBE] %s@%d: %s", msg.Topic, msg.Offset, string(msg.Value))
+	return nil
+}
+
+// setupAuth loads API keys and the JWT key. If no key would grant access, an
+// admin key is generated and written to a private file next to the key file.
+func setupAuth(keysFile, adminKey, jwtKeyFile, jwtIssuer string) (*auth.Authenticator, error) {
+	keys, err := auth.NewKeyStore(keysFile)
+	if err != nil {
+		return nil, err
+	}
+	if adminKey != "" {
+		keys.AddStatic("admin", auth.RoleAdmin, adminKey)
+	}
+
+	var tokens *auth.TokenVerifier
+	if jwtKeyFile != "" {
+		if tokens, err = auth.LoadTokenVerifier(jwtKeyFile, jwtIssuer); err != nil {
+			return nil, err
+		}
+	}
+
+	if keys.Len() == 0 && tokens == nil {
+		secret, key, err := keys.Create("bootstrap", auth.RoleAdmin)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(os.TempDir(), "mesh-admin-key")
+		if keysFile != "" {
+			path = filepath.Join(filepath.Dir(keysFile), "admin-key")
+		}
+		if err := atomicfile.Write(path, []byte(secret+"\n")); err != nil {
+			keys.Revoke(key.ID)
+			return nil, fmt.Errorf("failed to write admin key: %w", err)
+		}
+		log.Printf("[AUTH] No API keys configured; generated admin key %s in %s", key.ID, path)
+		if keysFile == "" {
+			log.Printf("[AUTH] Set -api-keys to keep it across restarts")
+		}
+	}
+
+	log.Printf("[AUTH] API authentication enabled (%d keys, JWT: %v)", keys.Len(), tokens != nil)
+	return auth.NewAuthenticator(keys, tokens), nil
+}
@@ -2,53 +2,142 @@ package mesh
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
-	"log"
 	"net/http"
+	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/superbrobenji/motionServer/auth"
+	EventStore "github.com/superbrobenji/motionServer/eventStore"
+	"github.com/superbrobenji/motionServer/events"
 )
 
 // APIServer provides HTTP API for mesh network control
 type APIServer struct {
 	meshServer *MeshServer
 	router     *mux.Router
+	v1         *mux.Router // Routes under apiPrefix
+	auth       *auth.Authenticator
+	upgrader   websocket.Upgrader
+
+	allowedOrigins []string      // Cross-origin browser clients allowed in
+	confirmations  *confirmationStore
+	shutdown       chan struct{} // Closed to end event streams
+	shutdownOnce   sync.Once
 }
 
-// NewAPIServer creates a new API server
+// NewAPIServer creates a new API server without authentication
 func NewAPIServer(meshServer *MeshServer) *APIServer {
+	return NewAPIServerWithAuth(meshServer, nil)
+}
+
+// NewAPIServerWithAuth creates a new API server that authenticates requests
+// with authenticator. A nil authenticator disables authentication.
+func NewAPIServerWithAuth(meshServer *MeshServer, authenticator *auth.Authenticator) *APIServer {
 	api := &APIServer{
 		meshServer: meshServer,
 		router:     mux.NewRouter(),
+		auth:       authenticator,
+		shutdown:   make(chan struct{}),
+
+		confirmations: newConfirmationStore(),
+	}
+	api.v1 = api.router.PathPrefix(apiPrefix).Subrouter()
+	api.upgrader = websocket.Upgrader{
+		ReadBufferSize:  1024,
+		WriteBufferSize: 4096,
+		CheckOrigin:     api.checkOrigin,
 	}
 
 	api.setupRoutes()
+	api.addLegacyRoutes()
 	return api
 }
 
-// setupRoutes configures the HTTP routes
+// setupRoutes configures the HTTP routes and the role each one requires.
+// Route names are used as the audit log action and as the operationId of
+// the route in openapi.json, which must describe every route.
 func (api *APIServer) setupRoutes() {
 	// Node management
-	api.router.HandleFunc("/nodes", api.getNodes).Methods("GET")
-	api.router.HandleFunc("/nodes/{mac}", api.getNode).Methods("GET")
-	api.router.HandleFunc("/nodes/{mac}/configure", api.configureNode).Methods("POST")
-	api.router.HandleFunc("/nodes/configure-all", api.configureAllNodes).Methods("POST")
+	api.handle("/nodes", auth.RoleViewer, api.getNodes).Methods("GET").Name("getNodes")
+	api.handle("/nodes/{mac}", auth.RoleViewer, api.getNode).Methods("GET").Name("getNode")
+	api.handle("/nodes/{mac}/configure", auth.RoleOperator, api.configureNode).Methods("POST").Name("configureNode")
+	api.handle("/nodes/{mac}/metadata", auth.RoleOperator, api.setNodeMetadata).Methods("PUT").Name("setNodeMetadata")
+	api.handle("/nodes/{mac}/reboot", auth.RoleOperator, api.rebootNode).Methods("POST").Name("rebootNode")
+	api.handle("/nodes/{mac}/reset", auth.RoleAdmin, api.resetNode).Methods("POST").Name("resetNode")
+	api.handle("/nodes/{mac}/ping", auth.RoleOperator, api.pingNode).Methods("POST").Name("pingNode")
+	api.handle("/nodes/{mac}/info", auth.RoleOperator, api.requestNodeInfo).Methods("POST").Name("requestNodeInfo")
+	api.handle("/nodes/{mac}/restart", auth.RoleViewer, api.getNodeRestart).Methods("GET").Name("getNodeRestart")
+	api.handle("/nodes/configure-all", auth.RoleOperator, api.configureAllNodes).Methods("POST").Name("configureAllNodes")
 	
 	// Health and monitoring
-	api.router.HandleFunc("/health/request", api.requestHealth).Methods("POST")
-	api.router.HandleFunc("/status", api.getStatus).Methods("GET")
+	api.handle("/health/request", auth.RoleOperator, api.requestHealth).Methods("POST").Name("requestHealth")
+	api.handle("/status", auth.RolePublic, api.getStatus).Methods("GET").Name("getStatus")
+	api.handle("/healthz", auth.RolePublic, api.getLiveness).Methods("GET").Name("getLiveness")   // Container liveness probe
+	api.handle("/readyz", auth.RolePublic, api.getReadiness).Methods("GET").Name("getReadiness") // Container readiness probe
+	api.handle("/topology", auth.RoleViewer, api.getTopology).Methods("GET").Name("getTopology")
+	api.handle("/metrics", auth.RoleViewer, promhttp.Handler().ServeHTTP).Methods("GET").Name("getMetrics")
+	api.handle("/openapi.json", auth.RolePublic, api.getOpenAPI).Methods("GET").Name("getOpenAPI")
+	
+	// Event history (requires a memory:// event sink)
+	api.handle("/events", auth.RoleViewer, api.getEvents).Methods("GET").Name("getEvents")
+	api.handle("/events/stream", auth.RoleViewer, api.streamEvents).Methods("GET").Name("streamEvents")
+	api.handle("/events/ws", auth.RoleViewer, api.streamEventsWebSocket).Methods("GET").Name("streamEventsWebSocket")
+	api.handle("/schemas", auth.RoleViewer, api.getSchemas).Methods("GET").Name("getSchemas")
+	api.handle("/schemas/{type}", auth.RoleViewer, api.getSchema).Methods("GET").Name("getSchema")
+
+	// Motion history
+	api.handle("/motion", auth.RoleViewer, api.getMotion).Methods("GET").Name("getMotion")
+	api.handle("/motion/stats", auth.RoleViewer, api.getMotionStats).Methods("GET").Name("getMotionStats")
 	
 	// Data broadcasting
-	api.router.HandleFunc("/broadcast", api.broadcastData).Methods("POST")
+	api.handle("/broadcast", auth.RoleOperator, api.broadcastData).Methods("POST").Name("broadcastData")
 	
 	// Server control
-	api.router.HandleFunc("/server/start", api.startServer).Methods("POST")
-	api.router.HandleFunc("/server/stop", api.stopServer).Methods("POST")
+	api.handle("/server/start", auth.RoleAdmin, api.startServer).Methods("POST").Name("startServer")
+	api.handle("/server/stop", auth.RoleAdmin, api.stopServer).Methods("POST").Name("stopServer")
+	api.handle("/admin/replay", auth.RoleAdmin, api.replayEvents).Methods("POST").Name("replayEvents")
+
+	// Audit log
+	api.handle("/audit", auth.RoleAdmin, api.getAudit).Methods("GET").Name("getAudit")
+
+	// Webhooks
+	api.handle("/webhooks", auth.RoleAdmin, api.listWebhooks).Methods("GET").Name("listWebhooks")
+	api.handle("/webhooks", auth.RoleAdmin, api.createWebhook).Methods("POST").Name("createWebhook")
+	api.handle("/webhooks/{id}", auth.RoleAdmin, api.deleteWebhook).Methods("DELETE").Name("deleteWebhook")
+	api.handle("/webhooks/{id}/deliveries", auth.RoleAdmin, api.getWebhookDeliveries).Methods("GET").Name("getWebhookDeliveries")
+	api.handle("/webhooks/{id}/deliveries/{delivery}/redeliver", auth.RoleAdmin, api.redeliverWebhook).Methods("POST").Name("redeliverWebhook")
+
+	// Firmware updates
+	api.handle("/ota/firmware", auth.RoleViewer, api.listFirmware).Methods("GET").Name("listFirmware")
+	api.handle("/ota/firmware", auth.RoleAdmin, api.uploadFirmware).Methods("POST").Name("uploadFirmware")
+	api.handle("/ota/rollouts", auth.RoleViewer, api.listRollouts).Methods("GET").Name("listRollouts")
+	api.handle("/ota/rollouts", auth.RoleAdmin, api.createRollout).Methods("POST").Name("createRollout")
+	api.handle("/ota/rollouts/{id}", auth.RoleViewer, api.getRollout).Methods("GET").Name("getRollout")
+	api.handle("/ota/rollouts/{id}/abort", auth.RoleAdmin, api.abortRollout).Methods("POST").Name("abortRollout")
+
+	// Mesh security keys
+	api.handle("/security", auth.RoleAdmin, api.getSecurity).Methods("GET").Name("getSecurity")
+	api.handle("/security/keys", auth.RoleAdmin, api.createMeshKey).Methods("POST").Name("createMeshKey")
+	api.handle("/security/keys/{id}/activate", auth.RoleAdmin, api.activateMeshKey).Methods("POST").Name("activateMeshKey")
+	api.handle("/security/keys/{id}", auth.RoleAdmin, api.retireMeshKey).Methods("DELETE").Name("retireMeshKey")
+
+	// API key management
+	api.handle("/admin/keys", auth.RoleAdmin, api.listKeys).Methods("GET").Name("listKeys")
+	api.handle("/admin/keys", auth.RoleAdmin, api.createKey).Methods("POST").Name("createKey")
+	api.handle("/admin/keys/{id}", auth.RoleAdmin, api.revokeKey).Methods("DELETE").Name("revokeKey")
 }
 
 // ServeHTTP implements the http.Handler interface
 func (api *APIServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if api.cors(w, r) {
+		return
+	}
 	api.router.ServeHTTP(w, r)
 }
 
@@ -58,17 +147,28 @@ type APIResponse struct {
 	Message string      `json:"message,omitempty"`
 	Data    interface{} `json:"data,omitempty"`
 	Error   string      `json:"error,omitempty"`
+
+	NextCursor string `json:"nextCursor,omitempty"` // Set when more pages follow
 }
 
 type ConfigureRequest struct {
 	AdapterType int32 `json:"adapterType"`
 }
 
+type MetadataRequest struct {
+	Name string `json:"name"`
+	Zone string `json:"zone"`
+}
+
 type BroadcastRequest struct {
 	DataType int32  `json:"dataType"`
 	Data     []byte `json:"data"`
 }
 
+type ReplayRequest struct {
+	From string `json:"from"` // earliest, offset:N, RFC3339 time or duration
+}
+
 // writeJSON writes a JSON response
 func (api *APIServer) writeJSON(w http.ResponseWriter, status int, response APIResponse) {
 	w.Header().Set("Content-Type", "application/json")
@@ -84,13 +184,37 @@ func (api *APIServer) writeError(w http.ResponseWriter, status int, message stri
 	})
 }
 
-// getNodes returns all known nodes
+// getNodes returns the known nodes matching the query parameters, one page
+// at a time if a limit is given
 func (api *APIServer) getNodes(w http.ResponseWriter, r *http.Request) {
+	query, err := ParseNodeQuery(r.URL.Query())
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	now := time.Now()
 	nodes := api.meshServer.GetNodeRegistry().GetAllNodes()
-	
+	views := make([]NodeView, 0, len(nodes))
+	for _, node := range nodes {
+		views = append(views, api.meshServer.NodeView(node, now))
+	}
+
+	page, nextCursor := query.Apply(views)
+	data := make([]interface{}, 0, len(page))
+	for _, node := range page {
+		projected, err := query.Project(node)
+		if err != nil {
+			api.writeError(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		data = append(data, projected)
+	}
+
 	api.writeJSON(w, http.StatusOK, APIResponse{
-		Success: true,
-		Data:    nodes,
+		Success:    true,
+		Data:       data,
+		NextCursor: nextCursor,
 	})
 }
 
@@ -113,7 +237,7 @@ func (api *APIServer) getNode(w http.ResponseWriter, r *http.Request) {
 	
 	api.writeJSON(w, http.StatusOK, APIResponse{
 		Success: true,
-		Data:    node,
+		Data:    api.meshServer.NodeView(node, time.Now()),
 	})
 }
 
@@ -145,6 +269,35 @@ func (api *APIServer) configureNode(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// setNodeMetadata sets the name and zone of a node
+func (api *APIServer) setNodeMetadata(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	macStr := vars["mac"]
+
+	mac, err := StringToMAC(macStr)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid MAC address: %v", err))
+		return
+	}
+
+	var req MetadataRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	node, exists := api.meshServer.GetNodeRegistry().SetMetadata(mac, req.Name, req.Zone)
+	if !exists {
+		api.writeError(w, http.StatusNotFound, "Node not found")
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    node,
+	})
+}
+
 // configureAllNodes configures all nodes' adapter type
 func (api *APIServer) configureAllNodes(w http.ResponseWriter, r *http.Request) {
 	var req ConfigureRequest
@@ -196,6 +349,71 @@ func (api *APIServer) getStatus(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// getTopology returns the route each node last used to reach the master
+func (api *APIServer) getTopology(w http.ResponseWriter, r *http.Request) {
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    api.meshServer.GetTopology().Links(),
+	})
+}
+
+// getEvents returns recent events from the in-memory event store
+func (api *APIServer) getEvents(w http.ResponseWriter, r *http.Request) {
+	store, ok := EventStore.FindQueryable(api.meshServer.GetEventStore())
+	if !ok {
+		api.writeError(w, http.StatusNotFound, "No queryable event store configured (add a memory:// sink to -events)")
+		return
+	}
+
+	query := r.URL.Query()
+	after := uint64(0)
+	if value := query.Get("after"); value != "" {
+		parsed, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid after offset: %s", value))
+			return
+		}
+		after = parsed
+	}
+
+	limit := 100
+	if value := query.Get("limit"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit (1-1000): %s", value))
+			return
+		}
+		limit = parsed
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    store.Query(query.Get("topic"), after, limit),
+	})
+}
+
+// getSchemas lists the event types with a published JSON Schema
+func (api *APIServer) getSchemas(w http.ResponseWriter, r *http.Request) {
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    events.SchemaTypes(),
+	})
+}
+
+// getSchema returns the JSON Schema for an event type
+func (api *APIServer) getSchema(w http.ResponseWriter, r *http.Request) {
+	eventType := mux.Vars(r)["type"]
+	schema, ok := events.Schema(eventType)
+	if !ok {
+		api.writeError(w, http.StatusNotFound, fmt.Sprintf("No schema for event type %s", eventType))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/schema+json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(schema)
+}
+
 // broadcastData broadcasts data to all nodes
 func (api *APIServer) broadcastData(w http.ResponseWriter, r *http.Request) {
 	var req BroadcastRequest
@@ -252,10 +470,42 @@ func (api *APIServer) stopServer(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-// StartAPIServer starts the HTTP API server
-func StartAPIServer(meshServer *MeshServer, port int) error {
-	api := NewAPIServer(meshServer)
-	
-	log.Printf("Starting API server on port %d", port)
-	return http.ListenAndServe(fmt.Sprintf(":%d", port), api)
+// replayEvents rebuilds server state from the event log
+func (api *APIServer) replayEvents(w http.ResponseWriter, r *http.Request) {
+	var req ReplayRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+			return
+		}
+	}
+
+	// Replaying a long log can outlast the server's write timeout
+	http.NewResponseController(w).SetWriteDeadline(time.Time{})
+
+	from, err := EventStore.ParseReplayFrom(req.From)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	result, err := api.meshServer.Replay(r.Context(), from)
+	if errors.Is(err, ErrReplayInProgress) {
+		api.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if errors.Is(err, EventStore.ErrReplayOffsetUnsupported) {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to replay events: %v", err))
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Replayed %d frames", result.Applied),
+		Data:    result,
+	})
 }
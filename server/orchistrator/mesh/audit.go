@@ -0,0 +1,367 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/superbrobenji/motionServer/auth"
+	"github.com/superbrobenji/motionServer/events"
+)
+
+const (
+	defaultAuditMemorySize = 10000
+	maxAuditBody           = 64 * 1024
+	maxAuditRawBody        = 1024
+)
+
+// AuditFilter selects audit entries. Zero fields match everything.
+type AuditFilter struct {
+	Actor   string
+	Action  string
+	Outcome string
+	Target  string
+	From    time.Time
+	To      time.Time
+	Limit   int
+}
+
+// Match reports whether an entry passes the filter, ignoring Limit
+func (f AuditFilter) Match(event *events.AuditEvent) bool {
+	at := event.Time.Time()
+	return (f.Actor == "" || f.Actor == event.Actor || f.Actor == event.ActorName) &&
+		(f.Action == "" || f.Action == event.Action) &&
+		(f.Outcome == "" || f.Outcome == event.Outcome) &&
+		(f.Target == "" || f.Target == event.Target) &&
+		(f.From.IsZero() || !at.Before(f.From)) &&
+		(f.To.IsZero() || at.Before(f.To))
+}
+
+// AuditLog is an append-only record of control actions. With a path, entries
+// are appended to a JSON-lines file that is never rewritten; otherwise the
+// most recent entries are kept in memory.
+type AuditLog struct {
+	mu      sync.Mutex
+	file    *os.File
+	path    string
+	entries []events.AuditEvent
+}
+
+// NewAuditLog creates an in-memory audit log
+func NewAuditLog() *AuditLog {
+	return &AuditLog{}
+}
+
+// OpenAuditLog opens or creates an append-only audit file
+func OpenAuditLog(path string) (*AuditLog, error) {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open audit log: %w", err)
+	}
+	return &AuditLog{file: file, path: path}, nil
+}
+
+// Append records an entry, syncing it to disk before returning
+func (l *AuditLog) Append(event *events.AuditEvent) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		if len(l.entries) >= defaultAuditMemorySize {
+			l.entries = append(l.entries[:0], l.entries[1:]...)
+		}
+		l.entries = append(l.entries, *event)
+		return nil
+	}
+
+	line, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode audit entry: %w", err)
+	}
+	if _, err := l.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit entry: %w", err)
+	}
+	return l.file.Sync()
+}
+
+// Query returns matching entries, newest first
+func (l *AuditLog) Query(filter AuditFilter) ([]events.AuditEvent, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var matches []events.AuditEvent
+	keep := func(event events.AuditEvent) {
+		if !filter.Match(&event) {
+			return
+		}
+		matches = append(matches, event)
+		if filter.Limit > 0 && len(matches) > filter.Limit {
+			matches = matches[1:]
+		}
+	}
+
+	if l.file == nil {
+		for _, event := range l.entries {
+			keep(event)
+		}
+	} else {
+		file, err := os.Open(l.path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+		defer file.Close()
+
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+		for scanner.Scan() {
+			var event events.AuditEvent
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				continue // Torn final line after a crash
+			}
+			keep(event)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read audit log: %w", err)
+		}
+	}
+
+	for i, j := 0, len(matches)-1; i < j; i, j = i+1, j-1 {
+		matches[i], matches[j] = matches[j], matches[i]
+	}
+	return matches, nil
+}
+
+// Close closes the audit file
+func (l *AuditLog) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.file == nil {
+		return nil
+	}
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
+
+// RecordAudit appends a control action to the audit log and publishes it to
+// the audit topic
+func (ms *MeshServer) RecordAudit(event *events.AuditEvent) {
+	if event.Time.Time().IsZero() {
+		event.Time = events.Timestamp(time.Now())
+	}
+
+	if err := ms.auditLog.Append(event); err != nil {
+		log.Printf("[AUDIT] Failed to record %s: %v", event.Action, err)
+	}
+	if err := ms.publishEvent(events.TopicAudit, event, event.Time.Time()); err != nil {
+		log.Printf("[AUDIT] Failed to publish %s: %v", event.Action, err)
+	}
+
+	actor := event.ActorName
+	if actor == "" {
+		actor = "unauthenticated caller"
+	}
+	log.Printf("[AUDIT] %s %s from %s: %s", actor, event.Action, event.SourceIP, event.Outcome)
+}
+
+// GetAuditLog returns the audit log
+func (ms *MeshServer) GetAuditLog() *AuditLog {
+	return ms.auditLog
+}
+
+type auditContextKey struct{}
+
+// auditResponseWriter captures the status and error of an audited response
+type auditResponseWriter struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (w *auditResponseWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditResponseWriter) Write(p []byte) (int, error) {
+	if w.status >= 400 && w.body.Len() < maxAuditBody {
+		w.body.Write(p)
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+func (w *auditResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// audit records mutating requests once they complete. The action is the
+// route name.
+func (api *APIServer) audit(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next(w, r)
+			return
+		}
+
+		start := time.Now()
+		event := &events.AuditEvent{
+			Action:    r.Method + " " + r.URL.Path,
+			Interface: "http",
+			SourceIP:  sourceIP(r),
+			Method:    r.Method,
+			Path:      r.URL.Path,
+			Params:    requestParams(r),
+		}
+		if route := mux.CurrentRoute(r); route != nil && route.GetName() != "" {
+			event.Action = route.GetName()
+		}
+		if mac, err := StringToMAC(mux.Vars(r)["mac"]); err == nil {
+			event.Target = macToString(mac)
+		}
+
+		recorder := &auditResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, event)))
+
+		event.Status = recorder.status
+		event.DurationMs = time.Since(start).Milliseconds()
+		switch {
+		case recorder.status == http.StatusUnauthorized || recorder.status == http.StatusForbidden:
+			event.Outcome = events.AuditDenied
+		case recorder.status >= 400:
+			event.Outcome = events.AuditFailure
+		default:
+			event.Outcome = events.AuditSuccess
+		}
+		if recorder.status >= 400 {
+			var response APIResponse
+			if json.Unmarshal(recorder.body.Bytes(), &response) == nil {
+				event.Error = response.Error
+			}
+		}
+
+		api.meshServer.RecordAudit(event)
+	}
+}
+
+// setAuditActor records the authenticated caller on an audited request
+func setAuditActor(ctx context.Context, principal *auth.Principal) {
+	if event, ok := ctx.Value(auditContextKey{}).(*events.AuditEvent); ok {
+		event.Actor = principal.ID
+		event.ActorName = principal.Name
+		event.Role = string(principal.Role)
+		event.AuthMethod = principal.Method
+	}
+}
+
+// requestParams collects path variables, query parameters and JSON body
+// fields for the audit log, leaving the body readable by the handler
+func requestParams(r *http.Request) map[string]interface{} {
+	params := make(map[string]interface{})
+	for name, value := range mux.Vars(r) {
+		params[name] = value
+	}
+	for name, values := range r.URL.Query() {
+		if name != "access_token" {
+			params[name] = strings.Join(values, ",")
+		}
+	}
+
+	// Binary bodies such as firmware images are left out
+	if r.Body != nil && !strings.HasPrefix(r.Header.Get("Content-Type"), "application/octet-stream") {
+		body, _ := io.ReadAll(io.LimitReader(r.Body, maxAuditBody))
+		r.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), r.Body), r.Body}
+
+		var fields map[string]interface{}
+		if err := json.Unmarshal(body, &fields); err == nil {
+			for name, value := range fields {
+				params[name] = value
+			}
+		} else if len(body) > 0 {
+			if len(body) > maxAuditRawBody {
+				body = body[:maxAuditRawBody]
+			}
+			params["body"] = string(body)
+		}
+	}
+
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// sourceIP returns the address of the client that sent a request
+func sourceIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// getAudit returns audit entries, newest first
+func (api *APIServer) getAudit(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter := AuditFilter{
+		Actor:   query.Get("actor"),
+		Action:  query.Get("action"),
+		Outcome: query.Get("outcome"),
+		Limit:   100,
+	}
+
+	if value := query.Get("mac"); value != "" {
+		mac, err := StringToMAC(value)
+		if err != nil {
+			api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid MAC address: %v", err))
+			return
+		}
+		filter.Target = macToString(mac)
+	}
+
+	for name, field := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid %s time (RFC3339): %s", name, value))
+				return
+			}
+			*field = parsed
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 1000 {
+			api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid limit (1-1000): %s", value))
+			return
+		}
+		filter.Limit = limit
+	}
+
+	entries, err := api.meshServer.GetAuditLog().Query(filter)
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    entries,
+	})
+}
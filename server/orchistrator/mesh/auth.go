@@ -0,0 +1,148 @@
+package mesh
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/superbrobenji/motionServer/auth"
+)
+
+type CreateKeyRequest struct {
+	Name string `json:"name"`
+	Role string `json:"role"`
+}
+
+// CreateKeyResponse carries a new key's secret, which is only shown once
+type CreateKeyResponse struct {
+	Key string `json:"key"`
+	auth.APIKey
+}
+
+// handle registers a route under apiPrefix that requires at least the given
+// role. Mutating requests are recorded in the audit log, and latencies in
+// mesh_http_request_duration_seconds, under the route's name.
+func (api *APIServer) handle(path string, role auth.Role, handler http.HandlerFunc) *mux.Route {
+	return api.v1.HandleFunc(path, api.instrument(api.audit(api.authorize(role, handler))))
+}
+
+// authorize authenticates the caller and checks their role before calling
+// next. Without an authenticator every caller is treated as an admin.
+func (api *APIServer) authorize(required auth.Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if required == auth.RolePublic {
+			next(w, r)
+			return
+		}
+
+		principal := auth.Anonymous
+		if api.auth != nil {
+			var err error
+			principal, err = api.auth.Authenticate(r)
+			if err != nil {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="mesh"`)
+				api.writeError(w, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+
+		setAuditActor(r.Context(), principal)
+		if !principal.Role.Allows(required) {
+			api.writeError(w, http.StatusForbidden,
+				fmt.Sprintf("Role %s is not allowed to %s %s (requires %s)", principal.Role, r.Method, r.URL.Path, required))
+			return
+		}
+
+		next(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	}
+}
+
+// keyStore returns the API key store, replying with an error if auth is disabled
+func (api *APIServer) keyStore(w http.ResponseWriter) (*auth.KeyStore, bool) {
+	if api.auth == nil {
+		api.writeError(w, http.StatusNotFound, "Authentication is disabled")
+		return nil, false
+	}
+	return api.auth.Keys(), true
+}
+
+// listKeys lists the API keys without their secrets
+func (api *APIServer) listKeys(w http.ResponseWriter, r *http.Request) {
+	keys, ok := api.keyStore(w)
+	if !ok {
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    keys.List(),
+	})
+}
+
+// createKey generates an API key
+func (api *APIServer) createKey(w http.ResponseWriter, r *http.Request) {
+	keys, ok := api.keyStore(w)
+	if !ok {
+		return
+	}
+
+	var req CreateKeyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+	if req.Name == "" {
+		api.writeError(w, http.StatusBadRequest, "Key name is required")
+		return
+	}
+	role, err := auth.ParseRole(req.Role)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	secret, key, err := keys.Create(req.Name, role)
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create key: %v", err))
+		return
+	}
+
+	log.Printf("[AUTH] Created %s key %s (%s)", key.Role, key.ID, key.Name)
+	api.writeJSON(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Message: "Store this key now; it cannot be shown again",
+		Data:    CreateKeyResponse{Key: secret, APIKey: key},
+	})
+}
+
+// revokeKey deletes an API key
+func (api *APIServer) revokeKey(w http.ResponseWriter, r *http.Request) {
+	keys, ok := api.keyStore(w)
+	if !ok {
+		return
+	}
+
+	id := mux.Vars(r)["id"]
+	exists, err := keys.Revoke(id)
+	if !exists {
+		api.writeError(w, http.StatusNotFound, "Key not found")
+		return
+	}
+	if errors.Is(err, auth.ErrStaticKey) {
+		api.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to revoke key: %v", err))
+		return
+	}
+
+	log.Printf("[AUTH] Revoked key %s", id)
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Key %s revoked", id),
+	})
+}
@@ -5,6 +5,7 @@ const (
 	MessageTypeAdapterData      uint32 = 0 // Normal adapter-originated data
 	MessageTypeMasterBeacon     uint32 = 1 // Mesh-internal heartbeat from master
 	MessageTypeSerialCmdBroadcast uint32 = 3 // Server→device serial command to broadcast adapter data
+	MessageTypeFragment         uint32 = 4 // Part of a message too long for one frame
 )
 
 // Adapter Types (maps to firmware enum adapter_types)
@@ -19,8 +20,20 @@ const (
 // Serial Control Opcodes (only when dataType = SERIAL)
 const (
 	OpConfigSet    byte = 0xA0 // Set adapter type on one node or all nodes
+	OpReboot       byte = 0xA1 // Reboot one node or all nodes
+	OpFactoryReset byte = 0xA2 // Erase stored configuration and reboot
 	OpHealthReq    byte = 0xB0 // Request health reports
 	OpHealthReport byte = 0xB1 // Node → server health status
+	OpPing         byte = 0xB2 // Server → node echo request
+	OpPong         byte = 0xB3 // Node → server echo reply
+	OpNodeInfoReq  byte = 0xB4 // Request firmware and hardware info
+	OpNodeInfo     byte = 0xB5 // Node → server firmware and hardware info
+	OpTimeSync     byte = 0xB6 // Set node clocks to the server's time
+	OpOTABegin     byte = 0xC0 // Open a firmware update session
+	OpOTAChunk     byte = 0xC1 // Firmware image chunk
+	OpOTAStatus    byte = 0xC2 // Node → server update progress
+	OpOTACommit    byte = 0xC3 // Verify the image and boot it
+	OpOTAAbort     byte = 0xC4 // Abandon a firmware update session
 )
 
 // Broadcast MAC address (all FF bytes)
@@ -0,0 +1,667 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.7
+// 	protoc        v6.32.0
+// source: mesh/control.proto
+
+package mesh
+
+import (
+	eventspb "github.com/superbrobenji/motionServer/events/eventspb"
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Node struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	Mac             string                 `protobuf:"bytes,1,opt,name=mac,proto3" json:"mac,omitempty"`
+	AdapterType     int32                  `protobuf:"varint,2,opt,name=adapterType,proto3" json:"adapterType,omitempty"`
+	AdapterTypeName string                 `protobuf:"bytes,3,opt,name=adapterTypeName,proto3" json:"adapterTypeName,omitempty"`
+	Uptime          uint32                 `protobuf:"varint,4,opt,name=uptime,proto3" json:"uptime,omitempty"` // seconds
+	LastSeen        *timestamppb.Timestamp `protobuf:"bytes,5,opt,name=lastSeen,proto3" json:"lastSeen,omitempty"`
+	HopCount        uint32                 `protobuf:"varint,6,opt,name=hopCount,proto3" json:"hopCount,omitempty"`
+	Online          bool                   `protobuf:"varint,7,opt,name=online,proto3" json:"online,omitempty"`
+	Name            string                 `protobuf:"bytes,8,opt,name=name,proto3" json:"name,omitempty"`
+	Zone            string                 `protobuf:"bytes,9,opt,name=zone,proto3" json:"zone,omitempty"`
+	unknownFields   protoimpl.UnknownFields
+	sizeCache       protoimpl.SizeCache
+}
+
+func (x *Node) Reset() {
+	*x = Node{}
+	mi := &file_mesh_control_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Node) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Node) ProtoMessage() {}
+
+func (x *Node) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Node.ProtoReflect.Descriptor instead.
+func (*Node) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Node) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+func (x *Node) GetAdapterType() int32 {
+	if x != nil {
+		return x.AdapterType
+	}
+	return 0
+}
+
+func (x *Node) GetAdapterTypeName() string {
+	if x != nil {
+		return x.AdapterTypeName
+	}
+	return ""
+}
+
+func (x *Node) GetUptime() uint32 {
+	if x != nil {
+		return x.Uptime
+	}
+	return 0
+}
+
+func (x *Node) GetLastSeen() *timestamppb.Timestamp {
+	if x != nil {
+		return x.LastSeen
+	}
+	return nil
+}
+
+func (x *Node) GetHopCount() uint32 {
+	if x != nil {
+		return x.HopCount
+	}
+	return 0
+}
+
+func (x *Node) GetOnline() bool {
+	if x != nil {
+		return x.Online
+	}
+	return false
+}
+
+func (x *Node) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Node) GetZone() string {
+	if x != nil {
+		return x.Zone
+	}
+	return ""
+}
+
+type ListNodesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNodesRequest) Reset() {
+	*x = ListNodesRequest{}
+	mi := &file_mesh_control_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNodesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNodesRequest) ProtoMessage() {}
+
+func (x *ListNodesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNodesRequest.ProtoReflect.Descriptor instead.
+func (*ListNodesRequest) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{1}
+}
+
+type ListNodesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Nodes         []*Node                `protobuf:"bytes,1,rep,name=nodes,proto3" json:"nodes,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ListNodesResponse) Reset() {
+	*x = ListNodesResponse{}
+	mi := &file_mesh_control_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ListNodesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ListNodesResponse) ProtoMessage() {}
+
+func (x *ListNodesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ListNodesResponse.ProtoReflect.Descriptor instead.
+func (*ListNodesResponse) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *ListNodesResponse) GetNodes() []*Node {
+	if x != nil {
+		return x.Nodes
+	}
+	return nil
+}
+
+type GetNodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mac           string                 `protobuf:"bytes,1,opt,name=mac,proto3" json:"mac,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetNodeRequest) Reset() {
+	*x = GetNodeRequest{}
+	mi := &file_mesh_control_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetNodeRequest) ProtoMessage() {}
+
+func (x *GetNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetNodeRequest.ProtoReflect.Descriptor instead.
+func (*GetNodeRequest) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetNodeRequest) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+type ConfigureNodeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Mac           string                 `protobuf:"bytes,1,opt,name=mac,proto3" json:"mac,omitempty"`
+	AdapterType   int32                  `protobuf:"zigzag32,2,opt,name=adapterType,proto3" json:"adapterType,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureNodeRequest) Reset() {
+	*x = ConfigureNodeRequest{}
+	mi := &file_mesh_control_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureNodeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureNodeRequest) ProtoMessage() {}
+
+func (x *ConfigureNodeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureNodeRequest.ProtoReflect.Descriptor instead.
+func (*ConfigureNodeRequest) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *ConfigureNodeRequest) GetMac() string {
+	if x != nil {
+		return x.Mac
+	}
+	return ""
+}
+
+func (x *ConfigureNodeRequest) GetAdapterType() int32 {
+	if x != nil {
+		return x.AdapterType
+	}
+	return 0
+}
+
+type ConfigureAllNodesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	AdapterType   int32                  `protobuf:"zigzag32,1,opt,name=adapterType,proto3" json:"adapterType,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ConfigureAllNodesRequest) Reset() {
+	*x = ConfigureAllNodesRequest{}
+	mi := &file_mesh_control_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ConfigureAllNodesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ConfigureAllNodesRequest) ProtoMessage() {}
+
+func (x *ConfigureAllNodesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ConfigureAllNodesRequest.ProtoReflect.Descriptor instead.
+func (*ConfigureAllNodesRequest) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ConfigureAllNodesRequest) GetAdapterType() int32 {
+	if x != nil {
+		return x.AdapterType
+	}
+	return 0
+}
+
+type BroadcastDataRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	DataType      int32                  `protobuf:"zigzag32,1,opt,name=dataType,proto3" json:"dataType,omitempty"`
+	Data          []byte                 `protobuf:"bytes,2,opt,name=data,proto3" json:"data,omitempty"` // up to 12 bytes
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BroadcastDataRequest) Reset() {
+	*x = BroadcastDataRequest{}
+	mi := &file_mesh_control_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BroadcastDataRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BroadcastDataRequest) ProtoMessage() {}
+
+func (x *BroadcastDataRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BroadcastDataRequest.ProtoReflect.Descriptor instead.
+func (*BroadcastDataRequest) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BroadcastDataRequest) GetDataType() int32 {
+	if x != nil {
+		return x.DataType
+	}
+	return 0
+}
+
+func (x *BroadcastDataRequest) GetData() []byte {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+type RequestHealthRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RequestHealthRequest) Reset() {
+	*x = RequestHealthRequest{}
+	mi := &file_mesh_control_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RequestHealthRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RequestHealthRequest) ProtoMessage() {}
+
+func (x *RequestHealthRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RequestHealthRequest.ProtoReflect.Descriptor instead.
+func (*RequestHealthRequest) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{7}
+}
+
+type ControlResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Message       string                 `protobuf:"bytes,1,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ControlResponse) Reset() {
+	*x = ControlResponse{}
+	mi := &file_mesh_control_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ControlResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ControlResponse) ProtoMessage() {}
+
+func (x *ControlResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ControlResponse.ProtoReflect.Descriptor instead.
+func (*ControlResponse) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *ControlResponse) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+// Filters match like the type, mac and zone parameters of /events/stream;
+// empty lists match everything
+type WatchEventsRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Types         []string               `protobuf:"bytes,1,rep,name=types,proto3" json:"types,omitempty"` // message, motion, health, node, audit or full CloudEvents types
+	Macs          []string               `protobuf:"bytes,2,rep,name=macs,proto3" json:"macs,omitempty"`
+	Zones         []string               `protobuf:"bytes,3,rep,name=zones,proto3" json:"zones,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchEventsRequest) Reset() {
+	*x = WatchEventsRequest{}
+	mi := &file_mesh_control_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchEventsRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchEventsRequest) ProtoMessage() {}
+
+func (x *WatchEventsRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_mesh_control_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchEventsRequest.ProtoReflect.Descriptor instead.
+func (*WatchEventsRequest) Descriptor() ([]byte, []int) {
+	return file_mesh_control_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *WatchEventsRequest) GetTypes() []string {
+	if x != nil {
+		return x.Types
+	}
+	return nil
+}
+
+func (x *WatchEventsRequest) GetMacs() []string {
+	if x != nil {
+		return x.Macs
+	}
+	return nil
+}
+
+func (x *WatchEventsRequest) GetZones() []string {
+	if x != nil {
+		return x.Zones
+	}
+	return nil
+}
+
+var File_mesh_control_proto protoreflect.FileDescriptor
+
+const file_mesh_control_proto_rawDesc = "" +
+	"\n" +
+	"\x12mesh/control.proto\x12\x04mesh\x1a\x1fgoogle/protobuf/timestamp.proto\x1a\x11mesh/events.proto\"\x90\x02\n" +
+	"\x04Node\x12\x10\n" +
+	"\x03mac\x18\x01 \x01(\tR\x03mac\x12 \n" +
+	"\vadapterType\x18\x02 \x01(\x05R\vadapterType\x12(\n" +
+	"\x0fadapterTypeName\x18\x03 \x01(\tR\x0fadapterTypeName\x12\x16\n" +
+	"\x06uptime\x18\x04 \x01(\rR\x06uptime\x126\n" +
+	"\blastSeen\x18\x05 \x01(\v2\x1a.google.protobuf.TimestampR\blastSeen\x12\x1a\n" +
+	"\bhopCount\x18\x06 \x01(\rR\bhopCount\x12\x16\n" +
+	"\x06online\x18\a \x01(\bR\x06online\x12\x12\n" +
+	"\x04name\x18\b \x01(\tR\x04name\x12\x12\n" +
+	"\x04zone\x18\t \x01(\tR\x04zone\"\x12\n" +
+	"\x10ListNodesRequest\"5\n" +
+	"\x11ListNodesResponse\x12 \n" +
+	"\x05nodes\x18\x01 \x03(\v2\n" +
+	".mesh.NodeR\x05nodes\"\"\n" +
+	"\x0eGetNodeRequest\x12\x10\n" +
+	"\x03mac\x18\x01 \x01(\tR\x03mac\"J\n" +
+	"\x14ConfigureNodeRequest\x12\x10\n" +
+	"\x03mac\x18\x01 \x01(\tR\x03mac\x12 \n" +
+	"\vadapterType\x18\x02 \x01(\x11R\vadapterType\"<\n" +
+	"\x18ConfigureAllNodesRequest\x12 \n" +
+	"\vadapterType\x18\x01 \x01(\x11R\vadapterType\"F\n" +
+	"\x14BroadcastDataRequest\x12\x1a\n" +
+	"\bdataType\x18\x01 \x01(\x11R\bdataType\x12\x12\n" +
+	"\x04data\x18\x02 \x01(\fR\x04data\"\x16\n" +
+	"\x14RequestHealthRequest\"+\n" +
+	"\x0fControlResponse\x12\x18\n" +
+	"\amessage\x18\x01 \x01(\tR\amessage\"T\n" +
+	"\x12WatchEventsRequest\x12\x14\n" +
+	"\x05types\x18\x01 \x03(\tR\x05types\x12\x12\n" +
+	"\x04macs\x18\x02 \x03(\tR\x04macs\x12\x14\n" +
+	"\x05zones\x18\x03 \x03(\tR\x05zones2\xd2\x03\n" +
+	"\vMeshControl\x12<\n" +
+	"\tListNodes\x12\x16.mesh.ListNodesRequest\x1a\x17.mesh.ListNodesResponse\x12+\n" +
+	"\aGetNode\x12\x14.mesh.GetNodeRequest\x1a\n" +
+	".mesh.Node\x12B\n" +
+	"\rConfigureNode\x12\x1a.mesh.ConfigureNodeRequest\x1a\x15.mesh.ControlResponse\x12J\n" +
+	"\x11ConfigureAllNodes\x12\x1e.mesh.ConfigureAllNodesRequest\x1a\x15.mesh.ControlResponse\x12B\n" +
+	"\rBroadcastData\x12\x1a.mesh.BroadcastDataRequest\x1a\x15.mesh.ControlResponse\x12B\n" +
+	"\rRequestHealth\x12\x1a.mesh.RequestHealthRequest\x1a\x15.mesh.ControlResponse\x12@\n" +
+	"\vWatchEvents\x12\x18.mesh.WatchEventsRequest\x1a\x15.mesh.events.Envelope0\x01B,Z*github.com/superbrobenji/motionServer/meshb\x06proto3"
+
+var (
+	file_mesh_control_proto_rawDescOnce sync.Once
+	file_mesh_control_proto_rawDescData []byte
+)
+
+func file_mesh_control_proto_rawDescGZIP() []byte {
+	file_mesh_control_proto_rawDescOnce.Do(func() {
+		file_mesh_control_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_mesh_control_proto_rawDesc), len(file_mesh_control_proto_rawDesc)))
+	})
+	return file_mesh_control_proto_rawDescData
+}
+
+var file_mesh_control_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_mesh_control_proto_goTypes = []any{
+	(*Node)(nil),                     // 0: mesh.Node
+	(*ListNodesRequest)(nil),         // 1: mesh.ListNodesRequest
+	(*ListNodesResponse)(nil),        // 2: mesh.ListNodesResponse
+	(*GetNodeRequest)(nil),           // 3: mesh.GetNodeRequest
+	(*ConfigureNodeRequest)(nil),     // 4: mesh.ConfigureNodeRequest
+	(*ConfigureAllNodesRequest)(nil), // 5: mesh.ConfigureAllNodesRequest
+	(*BroadcastDataRequest)(nil),     // 6: mesh.BroadcastDataRequest
+	(*RequestHealthRequest)(nil),     // 7: mesh.RequestHealthRequest
+	(*ControlResponse)(nil),          // 8: mesh.ControlResponse
+	(*WatchEventsRequest)(nil),       // 9: mesh.WatchEventsRequest
+	(*timestamppb.Timestamp)(nil),    // 10: google.protobuf.Timestamp
+	(*eventspb.Envelope)(nil),        // 11: mesh.events.Envelope
+}
+var file_mesh_control_proto_depIdxs = []int32{
+	10, // 0: mesh.Node.lastSeen:type_name -> google.protobuf.Timestamp
+	0,  // 1: mesh.ListNodesResponse.nodes:type_name -> mesh.Node
+	1,  // 2: mesh.MeshControl.ListNodes:input_type -> mesh.ListNodesRequest
+	3,  // 3: mesh.MeshControl.GetNode:input_type -> mesh.GetNodeRequest
+	4,  // 4: mesh.MeshControl.ConfigureNode:input_type -> mesh.ConfigureNodeRequest
+	5,  // 5: mesh.MeshControl.ConfigureAllNodes:input_type -> mesh.ConfigureAllNodesRequest
+	6,  // 6: mesh.MeshControl.BroadcastData:input_type -> mesh.BroadcastDataRequest
+	7,  // 7: mesh.MeshControl.RequestHealth:input_type -> mesh.RequestHealthRequest
+	9,  // 8: mesh.MeshControl.WatchEvents:input_type -> mesh.WatchEventsRequest
+	2,  // 9: mesh.MeshControl.ListNodes:output_type -> mesh.ListNodesResponse
+	0,  // 10: mesh.MeshControl.GetNode:output_type -> mesh.Node
+	8,  // 11: mesh.MeshControl.ConfigureNode:output_type -> mesh.ControlResponse
+	8,  // 12: mesh.MeshControl.ConfigureAllNodes:output_type -> mesh.ControlResponse
+	8,  // 13: mesh.MeshControl.BroadcastData:output_type -> mesh.ControlResponse
+	8,  // 14: mesh.MeshControl.RequestHealth:output_type -> mesh.ControlResponse
+	11, // 15: mesh.MeshControl.WatchEvents:output_type -> mesh.events.Envelope
+	9,  // [9:16] is the sub-list for method output_type
+	2,  // [2:9] is the sub-list for method input_type
+	2,  // [2:2] is the sub-list for extension type_name
+	2,  // [2:2] is the sub-list for extension extendee
+	0,  // [0:2] is the sub-list for field type_name
+}
+
+func init() { file_mesh_control_proto_init() }
+func file_mesh_control_proto_init() {
+	if File_mesh_control_proto != nil {
+		return
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_mesh_control_proto_rawDesc), len(file_mesh_control_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_mesh_control_proto_goTypes,
+		DependencyIndexes: file_mesh_control_proto_depIdxs,
+		MessageInfos:      file_mesh_control_proto_msgTypes,
+	}.Build()
+	File_mesh_control_proto = out.File
+	file_mesh_control_proto_goTypes = nil
+	file_mesh_control_proto_depIdxs = nil
+}
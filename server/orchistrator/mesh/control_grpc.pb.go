@@ -0,0 +1,368 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             v6.32.0
+// source: mesh/control.proto
+
+package mesh
+
+import (
+	context "context"
+	eventspb "github.com/superbrobenji/motionServer/events/eventspb"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	MeshControl_ListNodes_FullMethodName         = "/mesh.MeshControl/ListNodes"
+	MeshControl_GetNode_FullMethodName           = "/mesh.MeshControl/GetNode"
+	MeshControl_ConfigureNode_FullMethodName     = "/mesh.MeshControl/ConfigureNode"
+	MeshControl_ConfigureAllNodes_FullMethodName = "/mesh.MeshControl/ConfigureAllNodes"
+	MeshControl_BroadcastData_FullMethodName     = "/mesh.MeshControl/BroadcastData"
+	MeshControl_RequestHealth_FullMethodName     = "/mesh.MeshControl/RequestHealth"
+	MeshControl_WatchEvents_FullMethodName       = "/mesh.MeshControl/WatchEvents"
+)
+
+// MeshControlClient is the client API for MeshControl service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// gRPC counterpart of the HTTP control API. Credentials are sent as
+// "x-api-key" or "authorization: Bearer <key or JWT>" metadata and need the
+// same roles as the matching HTTP routes. MAC addresses are colon-separated
+// strings, e.g. "aa:bb:cc:dd:ee:ff".
+type MeshControlClient interface {
+	ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error)
+	GetNode(ctx context.Context, in *GetNodeRequest, opts ...grpc.CallOption) (*Node, error)
+	ConfigureNode(ctx context.Context, in *ConfigureNodeRequest, opts ...grpc.CallOption) (*ControlResponse, error)
+	ConfigureAllNodes(ctx context.Context, in *ConfigureAllNodesRequest, opts ...grpc.CallOption) (*ControlResponse, error)
+	BroadcastData(ctx context.Context, in *BroadcastDataRequest, opts ...grpc.CallOption) (*ControlResponse, error)
+	RequestHealth(ctx context.Context, in *RequestHealthRequest, opts ...grpc.CallOption) (*ControlResponse, error)
+	// WatchEvents streams live events until the client cancels. Slow clients
+	// are disconnected with RESOURCE_EXHAUSTED rather than delaying the mesh.
+	WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[eventspb.Envelope], error)
+}
+
+type meshControlClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewMeshControlClient(cc grpc.ClientConnInterface) MeshControlClient {
+	return &meshControlClient{cc}
+}
+
+func (c *meshControlClient) ListNodes(ctx context.Context, in *ListNodesRequest, opts ...grpc.CallOption) (*ListNodesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ListNodesResponse)
+	err := c.cc.Invoke(ctx, MeshControl_ListNodes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshControlClient) GetNode(ctx context.Context, in *GetNodeRequest, opts ...grpc.CallOption) (*Node, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Node)
+	err := c.cc.Invoke(ctx, MeshControl_GetNode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshControlClient) ConfigureNode(ctx context.Context, in *ConfigureNodeRequest, opts ...grpc.CallOption) (*ControlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlResponse)
+	err := c.cc.Invoke(ctx, MeshControl_ConfigureNode_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshControlClient) ConfigureAllNodes(ctx context.Context, in *ConfigureAllNodesRequest, opts ...grpc.CallOption) (*ControlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlResponse)
+	err := c.cc.Invoke(ctx, MeshControl_ConfigureAllNodes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshControlClient) BroadcastData(ctx context.Context, in *BroadcastDataRequest, opts ...grpc.CallOption) (*ControlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlResponse)
+	err := c.cc.Invoke(ctx, MeshControl_BroadcastData_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshControlClient) RequestHealth(ctx context.Context, in *RequestHealthRequest, opts ...grpc.CallOption) (*ControlResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ControlResponse)
+	err := c.cc.Invoke(ctx, MeshControl_RequestHealth_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *meshControlClient) WatchEvents(ctx context.Context, in *WatchEventsRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[eventspb.Envelope], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &MeshControl_ServiceDesc.Streams[0], MeshControl_WatchEvents_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchEventsRequest, eventspb.Envelope]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MeshControl_WatchEventsClient = grpc.ServerStreamingClient[eventspb.Envelope]
+
+// MeshControlServer is the server API for MeshControl service.
+// All implementations must embed UnimplementedMeshControlServer
+// for forward compatibility.
+//
+// gRPC counterpart of the HTTP control API. Credentials are sent as
+// "x-api-key" or "authorization: Bearer <key or JWT>" metadata and need the
+// same roles as the matching HTTP routes. MAC addresses are colon-separated
+// strings, e.g. "aa:bb:cc:dd:ee:ff".
+type MeshControlServer interface {
+	ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error)
+	GetNode(context.Context, *GetNodeRequest) (*Node, error)
+	ConfigureNode(context.Context, *ConfigureNodeRequest) (*ControlResponse, error)
+	ConfigureAllNodes(context.Context, *ConfigureAllNodesRequest) (*ControlResponse, error)
+	BroadcastData(context.Context, *BroadcastDataRequest) (*ControlResponse, error)
+	RequestHealth(context.Context, *RequestHealthRequest) (*ControlResponse, error)
+	// WatchEvents streams live events until the client cancels. Slow clients
+	// are disconnected with RESOURCE_EXHAUSTED rather than delaying the mesh.
+	WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[eventspb.Envelope]) error
+	mustEmbedUnimplementedMeshControlServer()
+}
+
+// UnimplementedMeshControlServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedMeshControlServer struct{}
+
+func (UnimplementedMeshControlServer) ListNodes(context.Context, *ListNodesRequest) (*ListNodesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListNodes not implemented")
+}
+func (UnimplementedMeshControlServer) GetNode(context.Context, *GetNodeRequest) (*Node, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetNode not implemented")
+}
+func (UnimplementedMeshControlServer) ConfigureNode(context.Context, *ConfigureNodeRequest) (*ControlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfigureNode not implemented")
+}
+func (UnimplementedMeshControlServer) ConfigureAllNodes(context.Context, *ConfigureAllNodesRequest) (*ControlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfigureAllNodes not implemented")
+}
+func (UnimplementedMeshControlServer) BroadcastData(context.Context, *BroadcastDataRequest) (*ControlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BroadcastData not implemented")
+}
+func (UnimplementedMeshControlServer) RequestHealth(context.Context, *RequestHealthRequest) (*ControlResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestHealth not implemented")
+}
+func (UnimplementedMeshControlServer) WatchEvents(*WatchEventsRequest, grpc.ServerStreamingServer[eventspb.Envelope]) error {
+	return status.Errorf(codes.Unimplemented, "method WatchEvents not implemented")
+}
+func (UnimplementedMeshControlServer) mustEmbedUnimplementedMeshControlServer() {}
+func (UnimplementedMeshControlServer) testEmbeddedByValue()                     {}
+
+// UnsafeMeshControlServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to MeshControlServer will
+// result in compilation errors.
+type UnsafeMeshControlServer interface {
+	mustEmbedUnimplementedMeshControlServer()
+}
+
+func RegisterMeshControlServer(s grpc.ServiceRegistrar, srv MeshControlServer) {
+	// If the following call pancis, it indicates UnimplementedMeshControlServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&MeshControl_ServiceDesc, srv)
+}
+
+func _MeshControl_ListNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshControlServer).ListNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MeshControl_ListNodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshControlServer).ListNodes(ctx, req.(*ListNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshControl_GetNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshControlServer).GetNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MeshControl_GetNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshControlServer).GetNode(ctx, req.(*GetNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshControl_ConfigureNode_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigureNodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshControlServer).ConfigureNode(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MeshControl_ConfigureNode_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshControlServer).ConfigureNode(ctx, req.(*ConfigureNodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshControl_ConfigureAllNodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ConfigureAllNodesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshControlServer).ConfigureAllNodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MeshControl_ConfigureAllNodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshControlServer).ConfigureAllNodes(ctx, req.(*ConfigureAllNodesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshControl_BroadcastData_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BroadcastDataRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshControlServer).BroadcastData(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MeshControl_BroadcastData_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshControlServer).BroadcastData(ctx, req.(*BroadcastDataRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshControl_RequestHealth_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RequestHealthRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(MeshControlServer).RequestHealth(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: MeshControl_RequestHealth_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(MeshControlServer).RequestHealth(ctx, req.(*RequestHealthRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _MeshControl_WatchEvents_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchEventsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(MeshControlServer).WatchEvents(m, &grpc.GenericServerStream[WatchEventsRequest, eventspb.Envelope]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type MeshControl_WatchEventsServer = grpc.ServerStreamingServer[eventspb.Envelope]
+
+// MeshControl_ServiceDesc is the grpc.ServiceDesc for MeshControl service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var MeshControl_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "mesh.MeshControl",
+	HandlerType: (*MeshControlServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListNodes",
+			Handler:    _MeshControl_ListNodes_Handler,
+		},
+		{
+			MethodName: "GetNode",
+			Handler:    _MeshControl_GetNode_Handler,
+		},
+		{
+			MethodName: "ConfigureNode",
+			Handler:    _MeshControl_ConfigureNode_Handler,
+		},
+		{
+			MethodName: "ConfigureAllNodes",
+			Handler:    _MeshControl_ConfigureAllNodes_Handler,
+		},
+		{
+			MethodName: "BroadcastData",
+			Handler:    _MeshControl_BroadcastData_Handler,
+		},
+		{
+			MethodName: "RequestHealth",
+			Handler:    _MeshControl_RequestHealth_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchEvents",
+			Handler:       _MeshControl_WatchEvents_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "mesh/control.proto",
+}
@@ -0,0 +1,203 @@
+package mesh
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/superbrobenji/motionServer/events"
+)
+
+const defaultStreamBuffer = 256
+
+// StreamEvent is a published event as delivered to live stream clients
+type StreamEvent struct {
+	ID      string
+	Type    string
+	Subject string // MAC address of the node the event is about
+	Zone    string
+	Data    []byte // CloudEvents JSON envelope
+}
+
+// StreamFilter selects the events a stream client receives. Empty sets match
+// everything.
+type StreamFilter struct {
+	Types map[string]bool
+	MACs  map[string]bool
+	Zones map[string]bool
+}
+
+// streamTypes maps the short type names accepted by ParseStreamFilter
+var streamTypes = map[string]string{
+	"message": events.TypeMeshMessage,
+	"motion":  events.TypeMotion,
+	"health":  events.TypeHealth,
+	"node":    events.TypeNodeLifecycle,
+	"audit":   events.TypeAudit,
+}
+
+// ParseStreamFilter reads comma-separated type, mac and zone query parameters.
+// Types may be given in full or by short name (message, motion, health, node,
+// audit).
+func ParseStreamFilter(query url.Values) (StreamFilter, error) {
+	filter := StreamFilter{}
+
+	for _, name := range splitList(query["type"]) {
+		eventType, ok := streamTypes[name]
+		if !ok {
+			if _, known := events.Schema(name); !known {
+				return filter, fmt.Errorf("unknown event type: %s", name)
+			}
+			eventType = name
+		}
+		filter.Types = addToSet(filter.Types, eventType)
+	}
+
+	for _, macStr := range splitList(query["mac"]) {
+		mac, err := StringToMAC(macStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.MACs = addToSet(filter.MACs, macToString(mac))
+	}
+
+	for _, zone := range splitList(query["zone"]) {
+		filter.Zones = addToSet(filter.Zones, zone)
+	}
+
+	return filter, nil
+}
+
+// Match reports whether an event passes the filter
+func (f StreamFilter) Match(event StreamEvent) bool {
+	return (len(f.Types) == 0 || f.Types[event.Type]) &&
+		(len(f.MACs) == 0 || f.MACs[event.Subject]) &&
+		(len(f.Zones) == 0 || f.Zones[event.Zone])
+}
+
+// EventBus fans published events out to live stream subscribers. Publishing
+// never blocks: a subscriber whose buffer is full is disconnected.
+type EventBus struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	buffer int
+}
+
+// Subscription receives the events matching its filter until it is closed
+type Subscription struct {
+	bus    *EventBus
+	filter StreamFilter
+	events chan StreamEvent
+	done   chan struct{}
+	once   sync.Once
+	slow   atomic.Bool
+}
+
+// NewEventBus creates an event bus whose subscribers buffer up to buffer events
+func NewEventBus(buffer int) *EventBus {
+	if buffer <= 0 {
+		buffer = defaultStreamBuffer
+	}
+	return &EventBus{
+		subs:   make(map[*Subscription]struct{}),
+		buffer: buffer,
+	}
+}
+
+// Subscribe registers a subscriber for events matching filter
+func (b *EventBus) Subscribe(filter StreamFilter) *Subscription {
+	sub := &Subscription{
+		bus:    b,
+		filter: filter,
+		events: make(chan StreamEvent, b.buffer),
+		done:   make(chan struct{}),
+	}
+
+	b.mu.Lock()
+	b.subs[sub] = struct{}{}
+	b.mu.Unlock()
+	return sub
+}
+
+// Publish delivers an event to every matching subscriber without blocking
+func (b *EventBus) Publish(event StreamEvent) {
+	var slow []*Subscription
+
+	b.mu.RLock()
+	for sub := range b.subs {
+		if !sub.filter.Match(event) {
+			continue
+		}
+		select {
+		case sub.events <- event:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	b.mu.RUnlock()
+
+	for _, sub := range slow {
+		sub.slow.Store(true)
+		sub.Close()
+	}
+}
+
+// Subscribers returns the number of connected subscribers
+func (b *EventBus) Subscribers() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subs)
+}
+
+// Events returns the channel of delivered events
+func (s *Subscription) Events() <-chan StreamEvent {
+	return s.events
+}
+
+// Done is closed when the subscription ends
+func (s *Subscription) Done() <-chan struct{} {
+	return s.done
+}
+
+// Slow reports whether the subscription was dropped for falling behind
+func (s *Subscription) Slow() bool {
+	select {
+	case <-s.done:
+		return s.slow.Load()
+	default:
+		return false
+	}
+}
+
+// Close unsubscribes; it is safe to call more than once
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.done)
+	})
+}
+
+// splitList splits comma-separated query values, dropping empty entries
+func splitList(values []string) []string {
+	var items []string
+	for _, value := range values {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				items = append(items, item)
+			}
+		}
+	}
+	return items
+}
+
+func addToSet(set map[string]bool, value string) map[string]bool {
+	if set == nil {
+		set = make(map[string]bool)
+	}
+	set[value] = true
+	return set
+}
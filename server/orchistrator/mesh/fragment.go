@@ -0,0 +1,191 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	fragmentHeaderLength = 5                                    // Message ID, index, count and message type
+	fragmentDataLength   = MaxDataLength - fragmentHeaderLength // Payload bytes per fragment
+	maxFragments         = 255                                  // The fragment count is one byte
+	MaxPayloadLength     = maxFragments * fragmentDataLength    // Largest payload SendMessage accepts
+	defaultFragmentTTL   = 5 * time.Second                      // How long a partial message waits for its fragments
+	maxPartialMessages   = 64                                   // Partial messages kept before the oldest is dropped
+)
+
+// fragmentKey identifies a message being reassembled
+type fragmentKey struct {
+	origin string
+	id     uint16
+}
+
+// partialMessage collects the fragments of one message
+type partialMessage struct {
+	first     *MeshMessage // Fragment 0, or whichever arrived first
+	fragments [][]byte
+	received  int
+	started   time.Time
+}
+
+// fragmentReassembler rebuilds messages from MessageTypeFragment frames.
+// Partial messages expire after ttl, and fragments of messages that have
+// already been reassembled or expired within ttl are dropped as duplicates.
+type fragmentReassembler struct {
+	mu       sync.Mutex
+	ttl      time.Duration
+	partial  map[fragmentKey]*partialMessage
+	finished map[fragmentKey]time.Time
+}
+
+func newFragmentReassembler(ttl time.Duration) *fragmentReassembler {
+	if ttl <= 0 {
+		ttl = defaultFragmentTTL
+	}
+	return &fragmentReassembler{
+		ttl:      ttl,
+		partial:  make(map[fragmentKey]*partialMessage),
+		finished: make(map[fragmentKey]time.Time),
+	}
+}
+
+// fragmentMessage splits a message whose payload is too long for one frame
+// into fragments addressed like it. id tells the receiver which fragments
+// belong together.
+func fragmentMessage(msg *MeshMessage, id uint16) ([]*MeshMessage, error) {
+	if len(msg.Data) > MaxPayloadLength {
+		return nil, fmt.Errorf("data length %d exceeds maximum %d", len(msg.Data), MaxPayloadLength)
+	}
+	if msg.MessageType > 0xFF {
+		return nil, fmt.Errorf("message type %d cannot be fragmented", msg.MessageType)
+	}
+
+	count := (len(msg.Data) + fragmentDataLength - 1) / fragmentDataLength
+	fragments := make([]*MeshMessage, 0, count)
+	for i := 0; i < count; i++ {
+		chunk := msg.Data[i*fragmentDataLength : min((i+1)*fragmentDataLength, len(msg.Data))]
+
+		data := make([]byte, fragmentHeaderLength, fragmentHeaderLength+len(chunk))
+		binary.LittleEndian.PutUint16(data[0:2], id)
+		data[2] = byte(i)
+		data[3] = byte(count)
+		data[4] = byte(msg.MessageType)
+		data = append(data, chunk...)
+
+		fragments = append(fragments, &MeshMessage{
+			MessageType:      MessageTypeFragment,
+			DataType:         msg.DataType,
+			OriginMacAddress: msg.OriginMacAddress,
+			TargetMacAddress: msg.TargetMacAddress,
+			Data:             data,
+		})
+	}
+	return fragments, nil
+}
+
+// add records a fragment received at receivedAt and returns the message once
+// all of its fragments have arrived
+func (r *fragmentReassembler) add(frag *MeshMessage, receivedAt time.Time) (*MeshMessage, error) {
+	if len(frag.Data) < fragmentHeaderLength {
+		fragmentMessages.WithLabelValues("invalid").Inc()
+		return nil, fmt.Errorf("fragment too short: %d bytes", len(frag.Data))
+	}
+	key := fragmentKey{origin: macToString(frag.OriginMacAddress), id: binary.LittleEndian.Uint16(frag.Data[0:2])}
+	index, count := int(frag.Data[2]), int(frag.Data[3])
+	if count == 0 || index >= count {
+		fragmentMessages.WithLabelValues("invalid").Inc()
+		return nil, fmt.Errorf("fragment %d of %d from %s is out of range", index, count, key.origin)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.expire(receivedAt)
+
+	if _, done := r.finished[key]; done {
+		fragmentDuplicates.Inc()
+		return nil, nil
+	}
+
+	partial, exists := r.partial[key]
+	if !exists {
+		if len(r.partial) >= maxPartialMessages {
+			r.dropOldest(receivedAt)
+		}
+		partial = &partialMessage{first: frag, fragments: make([][]byte, count), started: receivedAt}
+		r.partial[key] = partial
+	}
+	if len(partial.fragments) != count || int(partial.first.Data[4]) != int(frag.Data[4]) {
+		fragmentMessages.WithLabelValues("invalid").Inc()
+		return nil, fmt.Errorf("fragment %d of message %d from %s does not match the fragments before it", index, key.id, key.origin)
+	}
+	if partial.fragments[index] != nil {
+		fragmentDuplicates.Inc()
+		return nil, nil
+	}
+	partial.fragments[index] = frag.Data[fragmentHeaderLength:]
+	partial.received++
+	if index == 0 {
+		partial.first = frag
+	}
+	if partial.received < count {
+		return nil, nil
+	}
+
+	delete(r.partial, key)
+	r.finished[key] = receivedAt
+	fragmentMessages.WithLabelValues("reassembled").Inc()
+
+	var data []byte
+	for _, chunk := range partial.fragments {
+		data = append(data, chunk...)
+	}
+	first := partial.first
+	return &MeshMessage{
+		MessageType:       uint32(first.Data[4]),
+		DataType:          first.DataType,
+		OriginMacAddress:  first.OriginMacAddress,
+		TargetMacAddress:  first.TargetMacAddress,
+		LastHopMacAddress: frag.LastHopMacAddress,
+		HopCount:          frag.HopCount,
+		Data:              data,
+	}, nil
+}
+
+// expire drops partial messages older than the TTL, remembering them so
+// their late fragments are dropped, and forgets finished messages after
+// another TTL
+func (r *fragmentReassembler) expire(now time.Time) {
+	cutoff := now.Add(-r.ttl)
+	for key, partial := range r.partial {
+		if partial.started.Before(cutoff) {
+			log.Printf("[FRAGMENT] Message %d from %s expired with %d of %d fragments", key.id, key.origin, partial.received, len(partial.fragments))
+			fragmentMessages.WithLabelValues("expired").Inc()
+			delete(r.partial, key)
+			r.finished[key] = now
+		}
+	}
+	for key, at := range r.finished {
+		if at.Before(cutoff) {
+			delete(r.finished, key)
+		}
+	}
+}
+
+// dropOldest makes room for a new partial message
+func (r *fragmentReassembler) dropOldest(now time.Time) {
+	var oldest fragmentKey
+	var oldestAt time.Time
+	for key, partial := range r.partial {
+		if oldestAt.IsZero() || partial.started.Before(oldestAt) {
+			oldest, oldestAt = key, partial.started
+		}
+	}
+	log.Printf("[FRAGMENT] Too many partial messages, dropping message %d from %s", oldest.id, oldest.origin)
+	fragmentMessages.WithLabelValues("expired").Inc()
+	delete(r.partial, oldest)
+	r.finished[oldest] = now
+}
@@ -0,0 +1,370 @@
+package mesh
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/superbrobenji/motionServer/auth"
+	"github.com/superbrobenji/motionServer/events"
+	"github.com/superbrobenji/motionServer/events/eventspb"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// grpcRoles is the role each MeshControl method requires, matching the HTTP
+// routes. Methods missing from this map are refused.
+var grpcRoles = map[string]auth.Role{
+	MeshControl_ListNodes_FullMethodName:         auth.RoleViewer,
+	MeshControl_GetNode_FullMethodName:           auth.RoleViewer,
+	MeshControl_ConfigureNode_FullMethodName:     auth.RoleOperator,
+	MeshControl_ConfigureAllNodes_FullMethodName: auth.RoleOperator,
+	MeshControl_BroadcastData_FullMethodName:     auth.RoleOperator,
+	MeshControl_RequestHealth_FullMethodName:     auth.RoleOperator,
+	MeshControl_WatchEvents_FullMethodName:       auth.RoleViewer,
+}
+
+// GRPCServer implements the MeshControl gRPC service
+type GRPCServer struct {
+	UnimplementedMeshControlServer
+	meshServer   *MeshServer
+	auth         *auth.Authenticator
+	server       *grpc.Server
+	shutdown     chan struct{} // Closed to end WatchEvents streams
+	shutdownOnce sync.Once
+}
+
+// NewGRPCServer creates a gRPC server exposing meshServer. A nil
+// authenticator disables authentication.
+func NewGRPCServer(meshServer *MeshServer, authenticator *auth.Authenticator) *GRPCServer {
+	control := &GRPCServer{
+		meshServer: meshServer,
+		auth:       authenticator,
+		shutdown:   make(chan struct{}),
+	}
+
+	control.server = grpc.NewServer(
+		grpc.UnaryInterceptor(control.interceptUnary),
+		grpc.StreamInterceptor(control.interceptStream),
+	)
+	RegisterMeshControlServer(control.server, control)
+	return control
+}
+
+// ListenAndServe serves the gRPC API on port until Shutdown is called
+func (s *GRPCServer) ListenAndServe(port int) error {
+	listener, err := net.Listen("tcp", fmt.Sprintf(":%d", port))
+	if err != nil {
+		return fmt.Errorf("failed to listen on port %d: %w", port, err)
+	}
+
+	log.Printf("Starting gRPC server on port %d", port)
+	return s.Serve(listener)
+}
+
+// Serve serves the gRPC API on listener until Shutdown is called
+func (s *GRPCServer) Serve(listener net.Listener) error {
+	return s.server.Serve(listener)
+}
+
+// Shutdown ends WatchEvents streams and waits for other calls to finish. If
+// ctx expires first, the remaining calls are cancelled.
+func (s *GRPCServer) Shutdown(ctx context.Context) error {
+	s.shutdownOnce.Do(func() { close(s.shutdown) })
+
+	stopped := make(chan struct{})
+	go func() {
+		s.server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.server.Stop()
+		return ctx.Err()
+	}
+}
+
+// ListNodes returns all known nodes
+func (s *GRPCServer) ListNodes(ctx context.Context, req *ListNodesRequest) (*ListNodesResponse, error) {
+	response := &ListNodesResponse{}
+	now := time.Now()
+	for _, node := range s.meshServer.GetNodeRegistry().GetAllNodes() {
+		response.Nodes = append(response.Nodes, nodeToProto(s.meshServer.NodeView(node, now)))
+	}
+	return response, nil
+}
+
+// GetNode returns information about a specific node
+func (s *GRPCServer) GetNode(ctx context.Context, req *GetNodeRequest) (*Node, error) {
+	mac, err := StringToMAC(req.Mac)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid MAC address: %v", err)
+	}
+
+	node, exists := s.meshServer.GetNodeRegistry().GetNode(mac)
+	if !exists {
+		return nil, status.Error(codes.NotFound, "node not found")
+	}
+	return nodeToProto(s.meshServer.NodeView(node, time.Now())), nil
+}
+
+// ConfigureNode configures a specific node's adapter type
+func (s *GRPCServer) ConfigureNode(ctx context.Context, req *ConfigureNodeRequest) (*ControlResponse, error) {
+	mac, err := StringToMAC(req.Mac)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid MAC address: %v", err)
+	}
+
+	if err := s.meshServer.ConfigureNode(mac, req.AdapterType); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to configure node: %v", err)
+	}
+	return &ControlResponse{
+		Message: fmt.Sprintf("Node %s configured to adapter type %s", macToString(mac), GetAdapterTypeName(req.AdapterType)),
+	}, nil
+}
+
+// ConfigureAllNodes configures all nodes' adapter type
+func (s *GRPCServer) ConfigureAllNodes(ctx context.Context, req *ConfigureAllNodesRequest) (*ControlResponse, error) {
+	if err := s.meshServer.ConfigureAllNodes(req.AdapterType); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to configure all nodes: %v", err)
+	}
+	return &ControlResponse{
+		Message: fmt.Sprintf("All nodes configured to adapter type %s", GetAdapterTypeName(req.AdapterType)),
+	}, nil
+}
+
+// BroadcastData broadcasts data to all nodes
+func (s *GRPCServer) BroadcastData(ctx context.Context, req *BroadcastDataRequest) (*ControlResponse, error) {
+	if err := s.meshServer.BroadcastData(req.DataType, req.Data); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to broadcast data: %v", err)
+	}
+	return &ControlResponse{
+		Message: fmt.Sprintf("Data broadcasted to all nodes (type: %s, length: %d)",
+			GetAdapterTypeName(req.DataType), len(req.Data)),
+	}, nil
+}
+
+// RequestHealth requests health reports from all nodes
+func (s *GRPCServer) RequestHealth(ctx context.Context, req *RequestHealthRequest) (*ControlResponse, error) {
+	if err := s.meshServer.RequestHealthReports(); err != nil {
+		return nil, status.Errorf(codes.Internal, "failed to request health reports: %v", err)
+	}
+	return &ControlResponse{Message: "Health reports requested"}, nil
+}
+
+// WatchEvents streams live events matching the request's filters
+func (s *GRPCServer) WatchEvents(req *WatchEventsRequest, stream grpc.ServerStreamingServer[eventspb.Envelope]) error {
+	filter, err := ParseStreamFilter(url.Values{"type": req.Types, "mac": req.Macs, "zone": req.Zones})
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	sub := s.meshServer.GetEventBus().Subscribe(filter)
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-s.shutdown:
+			return status.Error(codes.Unavailable, "server shutting down")
+		case <-sub.Done():
+			if sub.Slow() {
+				log.Printf("[GRPC] Dropped slow WatchEvents client %s", peerAddress(stream.Context()))
+				return status.Error(codes.ResourceExhausted, "client too slow, events were dropped")
+			}
+			return nil
+		case event := <-sub.Events():
+			envelope, err := streamEventToProto(event)
+			if err != nil {
+				log.Printf("[GRPC] Failed to encode %s event %s: %v", event.Type, event.ID, err)
+				continue
+			}
+			if err := stream.Send(envelope); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// interceptUnary authenticates unary calls and records calls that change
+// state in the audit log
+func (s *GRPCServer) interceptUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	principal, authErr := s.authorize(ctx, info.FullMethod)
+	if role, ok := grpcRoles[info.FullMethod]; ok && role == auth.RoleViewer {
+		if authErr != nil {
+			return nil, authErr
+		}
+		return handler(auth.WithPrincipal(ctx, principal), req)
+	}
+
+	start := time.Now()
+	event := &events.AuditEvent{
+		Action:    grpcAction(info.FullMethod),
+		Interface: "grpc",
+		SourceIP:  peerAddress(ctx),
+		Path:      info.FullMethod,
+		Params:    grpcParams(req),
+	}
+	if target, ok := req.(interface{ GetMac() string }); ok {
+		if mac, err := StringToMAC(target.GetMac()); err == nil {
+			event.Target = macToString(mac)
+		}
+	}
+	if principal != nil {
+		event.Actor = principal.ID
+		event.ActorName = principal.Name
+		event.Role = string(principal.Role)
+		event.AuthMethod = principal.Method
+	}
+
+	var response interface{}
+	err := authErr
+	if err == nil {
+		response, err = handler(auth.WithPrincipal(ctx, principal), req)
+	}
+
+	event.DurationMs = time.Since(start).Milliseconds()
+	switch status.Code(err) {
+	case codes.OK:
+		event.Outcome = events.AuditSuccess
+	case codes.Unauthenticated, codes.PermissionDenied:
+		event.Outcome = events.AuditDenied
+		event.Error = status.Convert(err).Message()
+	default:
+		event.Outcome = events.AuditFailure
+		event.Error = status.Convert(err).Message()
+	}
+	s.meshServer.RecordAudit(event)
+
+	return response, err
+}
+
+// interceptStream authenticates streaming calls
+func (s *GRPCServer) interceptStream(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if _, err := s.authorize(stream.Context(), info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, stream)
+}
+
+// authorize authenticates the caller from the x-api-key or authorization
+// metadata and checks their role. The principal is returned when known, even
+// if the role is insufficient.
+func (s *GRPCServer) authorize(ctx context.Context, method string) (*auth.Principal, error) {
+	required, ok := grpcRoles[method]
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "no role configured for %s", method)
+	}
+	if s.auth == nil {
+		return auth.Anonymous, nil
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	credential := firstValue(md, "x-api-key")
+	if credential == "" {
+		if scheme, value, ok := strings.Cut(firstValue(md, "authorization"), " "); ok && strings.EqualFold(scheme, "Bearer") {
+			credential = strings.TrimSpace(value)
+		}
+	}
+
+	principal, err := s.auth.AuthenticateCredential(credential)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	if !principal.Role.Allows(required) {
+		return principal, status.Errorf(codes.PermissionDenied, "role %s is not allowed to call %s (requires %s)",
+			principal.Role, method, required)
+	}
+	return principal, nil
+}
+
+// grpcAction derives the audit action from a full method name, giving the
+// same names as the HTTP routes, e.g. "/mesh.MeshControl/ConfigureNode"
+// becomes "configureNode"
+func grpcAction(method string) string {
+	name := method[strings.LastIndex(method, "/")+1:]
+	if name == "" {
+		return method
+	}
+	runes := []rune(name)
+	runes[0] = unicode.ToLower(runes[0])
+	return string(runes)
+}
+
+// grpcParams converts a request message to audit log parameters
+func grpcParams(req interface{}) map[string]interface{} {
+	msg, ok := req.(proto.Message)
+	if !ok {
+		return nil
+	}
+	data, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+
+	var params map[string]interface{}
+	if err := json.Unmarshal(data, &params); err != nil || len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// streamEventToProto converts a published event to its protobuf envelope
+func streamEventToProto(event StreamEvent) (*eventspb.Envelope, error) {
+	envelope, payload, err := events.Decode(event.Data, events.ContentTypeJSON)
+	if err != nil {
+		return nil, err
+	}
+	return envelope.ToProto(payload)
+}
+
+func nodeToProto(node NodeView) *Node {
+	return &Node{
+		Mac:             node.MACString,
+		AdapterType:     node.AdapterType,
+		AdapterTypeName: node.AdapterTypeName,
+		Uptime:          node.Uptime,
+		LastSeen:        timestamppb.New(node.LastSeen),
+		HopCount:        node.HopCount,
+		Online:          node.Online,
+		Name:            node.Name,
+		Zone:            node.Zone,
+	}
+}
+
+// peerAddress returns the host of the client that made a call
+func peerAddress(ctx context.Context) string {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return ""
+	}
+	host, _, err := net.SplitHostPort(p.Addr.String())
+	if err != nil {
+		return p.Addr.String()
+	}
+	return host
+}
+
+func firstValue(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
@@ -0,0 +1,215 @@
+package mesh
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	EventStore "github.com/superbrobenji/motionServer/eventStore"
+)
+
+const (
+	defaultFrameWindow    = 2 * time.Minute // Serial is ready if a frame arrived this recently
+	eventStorePingTimeout = 2 * time.Second
+	maxPendingWrites      = 64               // Writes in progress before the queue counts as saturated
+	writeStallTimeout     = 30 * time.Second // Age at which a single write counts as stalled
+)
+
+// Readiness dependency names
+const (
+	checkSerial      = "serial"
+	checkEventStore  = "eventStore"
+	checkWriterQueue = "writerQueue"
+)
+
+// DependencyStatus is the readiness of one dependency
+type DependencyStatus struct {
+	Ready   bool                   `json:"ready"`
+	Message string                 `json:"message"`
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Readiness reports whether the server can do useful work, per dependency
+type Readiness struct {
+	Ready     bool                        `json:"ready"`
+	Checks    map[string]DependencyStatus `json:"checks"`
+	CheckedAt time.Time                   `json:"checkedAt"`
+}
+
+// Failing returns the names of the dependencies that are not ready, sorted
+func (r Readiness) Failing() []string {
+	var failing []string
+	for name, check := range r.Checks {
+		if !check.Ready {
+			failing = append(failing, name)
+		}
+	}
+	sort.Strings(failing)
+	return failing
+}
+
+// Readiness checks the serial link, the event store and the event writer
+func (ms *MeshServer) Readiness(ctx context.Context) Readiness {
+	now := time.Now()
+	readiness := Readiness{
+		Checks: map[string]DependencyStatus{
+			checkSerial:      ms.serialReadiness(now),
+			checkEventStore:  ms.eventStoreReadiness(ctx),
+			checkWriterQueue: ms.writerQueueReadiness(now),
+		},
+		CheckedAt: now,
+	}
+	readiness.Ready = len(readiness.Failing()) == 0
+	return readiness
+}
+
+// serialReadiness requires an open port that has received a frame within the
+// frame window. A freshly opened port gets one window to receive its first.
+func (ms *MeshServer) serialReadiness(now time.Time) DependencyStatus {
+	ms.mu.RLock()
+	running, startedAt := ms.running, ms.startedAt
+	ms.mu.RUnlock()
+
+	details := map[string]interface{}{
+		"port":        ms.serialPort,
+		"frameWindow": ms.frameWindow.String(),
+	}
+	if !running {
+		return DependencyStatus{Message: "serial port is not open", Details: details}
+	}
+
+	lastActivity := startedAt
+	if nanos := ms.lastFrame.Load(); nanos != 0 {
+		lastFrame := time.Unix(0, nanos)
+		details["lastFrameAt"] = lastFrame.UTC()
+		if lastFrame.After(lastActivity) {
+			lastActivity = lastFrame
+		}
+	}
+
+	if idle := now.Sub(lastActivity); idle > ms.frameWindow {
+		return DependencyStatus{
+			Message: fmt.Sprintf("no frames received for %s", idle.Truncate(time.Second)),
+			Details: details,
+		}
+	}
+	return DependencyStatus{Ready: true, Message: "serial port open and receiving frames", Details: details}
+}
+
+// eventStoreReadiness pings the configured event sinks
+func (ms *MeshServer) eventStoreReadiness(ctx context.Context) DependencyStatus {
+	if ms.eventStore == nil {
+		return DependencyStatus{Ready: true, Message: "no event store configured"}
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, eventStorePingTimeout)
+	defer cancel()
+	if err := EventStore.Ping(ctx, ms.eventStore); err != nil {
+		return DependencyStatus{Message: err.Error()}
+	}
+	return DependencyStatus{Ready: true, Message: "event store reachable"}
+}
+
+// writerQueueReadiness fails when too many event writes are waiting on the
+// store, or one of them has been waiting too long
+func (ms *MeshServer) writerQueueReadiness(now time.Time) DependencyStatus {
+	depth, oldest := ms.writes.stats(now)
+	status := DependencyStatus{
+		Details: map[string]interface{}{
+			"pending":    depth,
+			"capacity":   maxPendingWrites,
+			"oldestWait": oldest.Truncate(time.Millisecond).String(),
+		},
+	}
+
+	switch {
+	case depth >= maxPendingWrites:
+		status.Message = fmt.Sprintf("%d event writes pending", depth)
+	case oldest > writeStallTimeout:
+		status.Message = fmt.Sprintf("event write stalled for %s", oldest.Truncate(time.Second))
+	default:
+		status.Ready = true
+		status.Message = "event writes keeping up"
+	}
+	return status
+}
+
+// writeQueue tracks event store writes in progress. The zero value is ready
+// to use.
+type writeQueue struct {
+	mu      sync.Mutex
+	next    uint64
+	pending map[uint64]time.Time
+}
+
+// begin records the start of a write and returns its ID for end
+func (q *writeQueue) begin() uint64 {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.pending == nil {
+		q.pending = make(map[uint64]time.Time)
+	}
+	q.next++
+	q.pending[q.next] = time.Now()
+	eventWritesInFlight.Inc()
+	return q.next
+}
+
+// end records the completion of a write
+func (q *writeQueue) end(id uint64) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	delete(q.pending, id)
+	eventWritesInFlight.Dec()
+}
+
+// stats returns the number of writes in progress and how long the oldest has
+// been waiting
+func (q *writeQueue) stats(now time.Time) (int, time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	var oldest time.Duration
+	for _, started := range q.pending {
+		if wait := now.Sub(started); wait > oldest {
+			oldest = wait
+		}
+	}
+	return len(q.pending), oldest
+}
+
+// getLiveness reports that the process is serving requests
+func (api *APIServer) getLiveness(w http.ResponseWriter, r *http.Request) {
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data: map[string]interface{}{
+			"status": "alive",
+			"uptime": time.Since(api.meshServer.createdAt).Truncate(time.Second).String(),
+		},
+	})
+}
+
+// getReadiness reports each dependency's readiness, with 503 Service
+// Unavailable if any of them is not ready
+func (api *APIServer) getReadiness(w http.ResponseWriter, r *http.Request) {
+	readiness := api.meshServer.Readiness(r.Context())
+	if !readiness.Ready {
+		api.writeJSON(w, http.StatusServiceUnavailable, APIResponse{
+			Success: false,
+			Data:    readiness,
+			Error:   "Not ready: " + strings.Join(readiness.Failing(), ", "),
+		})
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    readiness,
+	})
+}
@@ -0,0 +1,132 @@
+package mesh
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/superbrobenji/motionServer/auth"
+)
+
+// HTTPConfig configures the HTTP API server. Zero timeouts disable the
+// corresponding limit.
+type HTTPConfig struct {
+	Port           int
+	TLSCertFile    string // HTTPS is served when both files are set
+	TLSKeyFile     string
+	AllowedOrigins []string // Browser origins allowed by CORS and WebSocket upgrades; "*" allows any
+	ReadTimeout    time.Duration
+	WriteTimeout   time.Duration // Event streams and replays are exempt
+	IdleTimeout    time.Duration
+}
+
+// HTTPServer serves the API over HTTP or HTTPS
+type HTTPServer struct {
+	api    *APIServer
+	server *http.Server
+	config HTTPConfig
+}
+
+// NewHTTPServer creates an HTTP API server for meshServer. A nil
+// authenticator disables authentication.
+func NewHTTPServer(meshServer *MeshServer, authenticator *auth.Authenticator, config HTTPConfig) (*HTTPServer, error) {
+	if (config.TLSCertFile == "") != (config.TLSKeyFile == "") {
+		return nil, fmt.Errorf("TLS needs both a certificate and a key file")
+	}
+
+	api := NewAPIServerWithAuth(meshServer, authenticator)
+	api.allowedOrigins = config.AllowedOrigins
+
+	server := &http.Server{
+		Addr:         fmt.Sprintf(":%d", config.Port),
+		Handler:      api,
+		ReadTimeout:  config.ReadTimeout,
+		WriteTimeout: config.WriteTimeout,
+		IdleTimeout:  config.IdleTimeout,
+		TLSConfig:    &tls.Config{MinVersion: tls.VersionTLS12},
+	}
+	// Event streams never finish on their own, so end them when shutting down
+	server.RegisterOnShutdown(api.closeStreams)
+
+	return &HTTPServer{api: api, server: server, config: config}, nil
+}
+
+// ListenAndServe serves requests until Shutdown is called, then returns nil
+func (s *HTTPServer) ListenAndServe() error {
+	var err error
+	if s.config.TLSCertFile != "" {
+		log.Printf("Starting API server on port %d (TLS)", s.config.Port)
+		err = s.server.ListenAndServeTLS(s.config.TLSCertFile, s.config.TLSKeyFile)
+	} else {
+		log.Printf("Starting API server on port %d", s.config.Port)
+		err = s.server.ListenAndServe()
+	}
+
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops accepting connections, ends event streams and waits for
+// in-flight requests to finish or ctx to expire
+func (s *HTTPServer) Shutdown(ctx context.Context) error {
+	return s.server.Shutdown(ctx)
+}
+
+// closeStreams ends every SSE and WebSocket event stream
+func (api *APIServer) closeStreams() {
+	api.shutdownOnce.Do(func() { close(api.shutdown) })
+}
+
+// cors adds CORS headers for allowed origins and answers preflight requests.
+// It returns true when the request has been handled.
+func (api *APIServer) cors(w http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" || !api.corsAllowed(origin) {
+		return false
+	}
+
+	header := w.Header()
+	header.Set("Access-Control-Allow-Origin", origin)
+	header.Add("Vary", "Origin")
+	header.Set("Access-Control-Expose-Headers", "Deprecation, Link, WWW-Authenticate")
+
+	if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+		return false
+	}
+	header.Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE")
+	header.Set("Access-Control-Allow-Headers", "Authorization, Content-Type, X-API-Key")
+	header.Set("Access-Control-Max-Age", "600")
+	w.WriteHeader(http.StatusNoContent)
+	return true
+}
+
+// corsAllowed reports whether origin is in the allowed origins
+func (api *APIServer) corsAllowed(origin string) bool {
+	for _, allowed := range api.allowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkOrigin allows WebSocket upgrades from non-browser clients, the API's
+// own origin and the allowed origins
+func (api *APIServer) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if u, err := url.Parse(origin); err == nil && strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	return api.corsAllowed(origin)
+}
@@ -27,14 +27,14 @@ type MeshMessage struct {
 	// 0 = ADAPTER_DATA (normal adapter-originated data)
 	// 1 = MASTER_BEACON (mesh-internal heartbeat from master)
 	// 3 = SERIAL_CMD_BROADCAST (special: server→device serial command to broadcast adapter data)
+	// 4 = FRAGMENT (part of a message whose data does not fit in one frame)
 	MessageType uint32 `protobuf:"varint,1,opt,name=messageType,proto3" json:"messageType,omitempty"`
 	// Adapter data type (maps to firmware enum adapter_types)
-	//
-	//	-1 UNKNOWN
-	//	 0 PIR
-	//	 1 WIFI (reserved)
-	//	 2 LED  (reserved)
-	//	 3 SERIAL (serial control / health / commands)
+	//   -1 UNKNOWN
+	//    0 PIR
+	//    1 WIFI (reserved)
+	//    2 LED  (reserved)
+	//    3 SERIAL (serial control / health / commands)
 	DataType int32 `protobuf:"zigzag32,2,opt,name=dataType,proto3" json:"dataType,omitempty"` // zigzag encoded
 	// MAC addresses (6 bytes used). The server only needs to send targetMacAddress.
 	// originMacAddress, lastHopMacAddress, and hopCount are automatically generated by the device.
@@ -42,9 +42,12 @@ type MeshMessage struct {
 	TargetMacAddress  []byte `protobuf:"bytes,4,opt,name=targetMacAddress,proto3" json:"targetMacAddress,omitempty"`   // 6 bytes (required from server)
 	LastHopMacAddress []byte `protobuf:"bytes,5,opt,name=lastHopMacAddress,proto3" json:"lastHopMacAddress,omitempty"` // 6 bytes (auto-generated by device)
 	// 12-byte adapter payload. For SERIAL (dataType=3) this contains control opcodes.
-	Data []byte `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"` // up to 12 bytes used
+	Data []byte `protobuf:"bytes,6,opt,name=data,proto3" json:"data,omitempty"` // up to 12 bytes used; fragments carry a 5-byte header and 7 bytes of the message
 	// Mesh hop count (0 for locally originated)
-	HopCount      uint32 `protobuf:"varint,7,opt,name=hopCount,proto3" json:"hopCount,omitempty"` // auto-generated by device
+	HopCount uint32 `protobuf:"varint,7,opt,name=hopCount,proto3" json:"hopCount,omitempty"` // auto-generated by device
+	// Signature in security mode (13 bytes): key ID, counter (uint32 LE) and the
+	// first 8 bytes of an HMAC-SHA256 over the frame. Empty when unsigned.
+	Auth          []byte `protobuf:"bytes,8,opt,name=auth,proto3" json:"auth,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -128,11 +131,18 @@ func (x *MeshMessage) GetHopCount() uint32 {
 	return 0
 }
 
+func (x *MeshMessage) GetAuth() []byte {
+	if x != nil {
+		return x.Auth
+	}
+	return nil
+}
+
 var File_mesh_mesh_proto protoreflect.FileDescriptor
 
 const file_mesh_mesh_proto_rawDesc = "" +
 	"\n" +
-	"\x0fmesh/mesh.proto\x12\x04mesh\"\x81\x02\n" +
+	"\x0fmesh/mesh.proto\x12\x04mesh\"\x95\x02\n" +
 	"\vMeshMessage\x12 \n" +
 	"\vmessageType\x18\x01 \x01(\rR\vmessageType\x12\x1a\n" +
 	"\bdataType\x18\x02 \x01(\x11R\bdataType\x12*\n" +
@@ -140,7 +150,8 @@ const file_mesh_mesh_proto_rawDesc = "" +
 	"\x10targetMacAddress\x18\x04 \x01(\fR\x10targetMacAddress\x12,\n" +
 	"\x11lastHopMacAddress\x18\x05 \x01(\fR\x11lastHopMacAddress\x12\x12\n" +
 	"\x04data\x18\x06 \x01(\fR\x04data\x12\x1a\n" +
-	"\bhopCount\x18\a \x01(\rR\bhopCountB,Z*github.com/superbrobenji/motionServer/meshb\x06proto3"
+	"\bhopCount\x18\a \x01(\rR\bhopCount\x12\x12\n" +
+	"\x04auth\x18\b \x01(\fR\x04authB,Z*github.com/superbrobenji/motionServer/meshb\x06proto3"
 
 var (
 	file_mesh_mesh_proto_rawDescOnce sync.Once
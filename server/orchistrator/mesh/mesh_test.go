@@ -1,8 +1,46 @@
 package mesh
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"hash/crc32"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/gorilla/websocket"
+	mochi "github.com/mochi-mqtt/server/v2"
+	mochiauth "github.com/mochi-mqtt/server/v2/hooks/auth"
+	"github.com/mochi-mqtt/server/v2/listeners"
+	"github.com/mochi-mqtt/server/v2/packets"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/superbrobenji/motionServer/auth"
+	EventStore "github.com/superbrobenji/motionServer/eventStore"
+	"github.com/superbrobenji/motionServer/events"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
 )
 
 // MockSerialPort implements SerialPort for testing
@@ -38,6 +76,141 @@ func (m *MockSerialPort) GetWrittenData() []byte {
 	return m.writeBuffer.Bytes()
 }
 
+// echoPort is a serial port whose nodes answer pings, except the sequence
+// numbers in drop, and node info requests for the nodes in details
+type echoPort struct {
+	server  *MeshServer
+	mu      sync.Mutex
+	written []byte
+	drop    map[uint16]bool
+	details map[string]NodeDetails
+	asked   []string // Targets of node info requests
+}
+
+func (p *echoPort) Read([]byte) (int, error) { return 0, io.EOF }
+func (p *echoPort) Close() error             { return nil }
+
+func (p *echoPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.written = append(p.written, b...)
+	for len(p.written) >= 2 {
+		length := int(binary.LittleEndian.Uint16(p.written))
+		if len(p.written) < 2+length {
+			break
+		}
+		var msg MeshMessage
+		if err := proto.Unmarshal(p.written[2:2+length], &msg); err == nil && PingOpcode.Matches(&msg) {
+			echo, _ := PingOpcode.Parse(&msg)
+			if !p.drop[echo.Sequence] {
+				pong, _ := PongOpcode.Build(nil, echo)
+				pong.OriginMacAddress = msg.TargetMacAddress
+				go p.server.processMessage(pong, time.Now(), false)
+			}
+		} else if err == nil && NodeInfoRequestOpcode.Matches(&msg) {
+			request, _ := NodeInfoRequestOpcode.Parse(&msg)
+			p.asked = append(p.asked, macToString(request.Target))
+			for mac, details := range p.details {
+				if target := macToString(request.Target); target == mac || bytes.Equal(request.Target, BroadcastMAC) {
+					reply, _ := NodeInfoOpcode.Build(nil, details)
+					reply.OriginMacAddress, _ = StringToMAC(mac)
+					go p.server.processMessage(reply, time.Now(), false)
+				}
+			}
+		}
+		p.written = p.written[2+length:]
+	}
+	return len(b), nil
+}
+
+// otaNode is a simulated node on an otaPort
+type otaNode struct {
+	maxPayload byte
+	silent     bool            // Never answers
+	dropOnce   map[uint32]bool // Chunk offsets lost the first time they are sent
+	session    uint16
+	size       uint32
+	image      []byte
+	committed  bool
+}
+
+// otaPort is a serial port whose nodes take firmware updates, acknowledging
+// chunks cumulatively and Nacking gaps
+type otaPort struct {
+	server  *MeshServer
+	mu      sync.Mutex
+	written []byte
+	nodes   map[string]*otaNode
+}
+
+func (p *otaPort) Read([]byte) (int, error) { return 0, io.EOF }
+func (p *otaPort) Close() error             { return nil }
+
+func (p *otaPort) Write(b []byte) (int, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.written = append(p.written, b...)
+	for len(p.written) >= 2 {
+		length := int(binary.LittleEndian.Uint16(p.written))
+		if len(p.written) < 2+length {
+			break
+		}
+		var msg MeshMessage
+		if err := proto.Unmarshal(p.written[2:2+length], &msg); err == nil {
+			p.receive(&msg)
+		}
+		p.written = p.written[2+length:]
+	}
+	return len(b), nil
+}
+
+func (p *otaPort) receive(msg *MeshMessage) {
+	node := p.nodes[macToString(msg.TargetMacAddress)]
+	if node == nil || node.silent {
+		return
+	}
+	reply := func(status OTAStatus) {
+		status.Session = node.session
+		report, _ := OTAStatusOpcode.Build(nil, status)
+		report.OriginMacAddress = msg.TargetMacAddress
+		go p.server.processMessage(report, time.Now(), false)
+	}
+
+	switch {
+	case OTABeginOpcode.Matches(msg):
+		begin, _ := OTABeginOpcode.Parse(msg)
+		node.session, node.size, node.image = begin.Session, begin.Size, nil
+		reply(OTAStatus{Status: OTAStatusReady, MaxPayload: node.maxPayload})
+	case OTAChunkOpcode.Matches(msg):
+		chunk, err := OTAChunkOpcode.Parse(msg)
+		if err != nil || node.dropOnce[chunk.Offset] {
+			delete(node.dropOnce, chunk.Offset)
+			return
+		}
+		expected := uint32(len(node.image))
+		switch {
+		case chunk.Offset == expected:
+			data := chunk.Data[:min(len(chunk.Data), int(node.size-expected))]
+			node.image = append(node.image, data...)
+			reply(OTAStatus{Status: OTAStatusAck, Offset: uint32(len(node.image))})
+		case chunk.Offset > expected:
+			reply(OTAStatus{Status: OTAStatusNack, Offset: expected})
+		default:
+			reply(OTAStatus{Status: OTAStatusAck, Offset: expected})
+		}
+	case OTACommitOpcode.Matches(msg):
+		commit, _ := OTACommitOpcode.Parse(msg)
+		if crc32.ChecksumIEEE(node.image) != commit.CRC32 {
+			reply(OTAStatus{Status: OTAStatusError, Code: 1})
+			return
+		}
+		node.committed = true
+		reply(OTAStatus{Status: OTAStatusDone})
+	}
+}
+
 func TestMessageBuilder(t *testing.T) {
 	builder := NewMessageBuilder()
 
@@ -130,6 +303,91 @@ func TestMessageBuilder(t *testing.T) {
 	})
 }
 
+func TestOpcodeRegistry(t *testing.T) {
+	decodeByte := func(payload []byte) (byte, error) { return payload[1], nil }
+
+	t.Run("LayoutValidation", func(t *testing.T) {
+		registry := NewOpcodeRegistry()
+		testCases := []struct {
+			name   string
+			opcode *Opcode[byte]
+		}{
+			{"DuplicateCode", &Opcode[byte]{OpcodeSpec: OpcodeSpec{Code: OpHealthReport, Name: "clash"}, Decode: decodeByte}},
+			{"OverlapsOpcode", &Opcode[byte]{OpcodeSpec: OpcodeSpec{Code: 0xE0, Name: "overlap", Fields: []PayloadField{{Name: "a", Offset: 0, Size: 1}}}, Decode: decodeByte}},
+			{"OverlapsField", &Opcode[byte]{OpcodeSpec: OpcodeSpec{Code: 0xE0, Name: "overlap", Fields: []PayloadField{{Name: "a", Offset: 1, Size: 4}, {Name: "b", Offset: 3, Size: 1}}}, Decode: decodeByte}},
+			{"TooLong", &Opcode[byte]{OpcodeSpec: OpcodeSpec{Code: 0xE0, Name: "long", Fields: []PayloadField{{Name: "a", Offset: 8, Size: 5}}}, Decode: decodeByte}},
+			{"NoDecoder", &Opcode[byte]{OpcodeSpec: OpcodeSpec{Code: 0xE0, Name: "mute"}}},
+		}
+		for _, tc := range testCases {
+			if err := registry.Register(tc.opcode); err == nil {
+				t.Errorf("%s: expected registration to fail", tc.name)
+			}
+		}
+
+		for i, spec := range registry.Specs() {
+			if spec.Code == 0xE0 || spec.Code == OpHealthReport && spec.Name != "healthReport" {
+				t.Errorf("Expected rejected opcodes not to be registered, got %+v", spec)
+			}
+			if i > 0 && spec.Code <= registry.Specs()[i-1].Code {
+				t.Error("Expected specs ordered by code")
+			}
+		}
+	})
+
+	t.Run("ReservedBytes", func(t *testing.T) {
+		sloppy := &Opcode[byte]{
+			OpcodeSpec: OpcodeSpec{Code: 0xE1, Name: "sloppy", Fields: []PayloadField{{Name: "value", Offset: 1, Size: 1}}},
+			Encode:     func(payload []byte, value byte) { payload[1], payload[2] = value, value },
+			Decode:     decodeByte,
+		}
+		if _, err := sloppy.Build(nil, 1); err == nil {
+			t.Error("Expected writing outside the layout to fail")
+		}
+	})
+
+	t.Run("Dispatch", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		var handled []byte
+		custom := &Opcode[byte]{
+			OpcodeSpec: OpcodeSpec{Code: 0xE2, Name: "custom", Fields: []PayloadField{{Name: "value", Offset: 1, Size: 1}}},
+			Encode:     func(payload []byte, value byte) { payload[1] = value },
+			Decode:     decodeByte,
+			Handle: func(_ *MeshServer, _ *MeshMessage, value byte, _ time.Time, _ bool) error {
+				handled = append(handled, value)
+				return nil
+			},
+		}
+		if err := server.GetOpcodes().Register(custom); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		msg, err := custom.Build(BroadcastMAC, 42)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := server.processMessage(msg, time.Now(), false); err != nil || len(handled) != 1 || handled[0] != 42 {
+			t.Errorf("Expected the custom handler to receive 42, got %v (%v)", handled, err)
+		}
+
+		report, _ := HealthReportOpcode.Build(nil, HealthReport{MAC: []byte{1, 2, 3, 4, 5, 6}, AdapterType: AdapterTypeLED, Uptime: 99})
+		if err := server.processMessage(report, time.Now(), false); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if node, exists := server.GetNodeRegistry().GetNode([]byte{1, 2, 3, 4, 5, 6}); !exists || node.Uptime != 99 || node.AdapterType != AdapterTypeLED {
+			t.Errorf("Expected the health report to update the registry, got %+v", node)
+		}
+
+		truncated := &MeshMessage{MessageType: MessageTypeAdapterData, DataType: AdapterTypeSerial, Data: []byte{OpHealthReport, 0}}
+		if err := server.processMessage(truncated, time.Now(), false); err == nil {
+			t.Error("Expected a short health report to be rejected")
+		}
+		unknown := &MeshMessage{MessageType: MessageTypeAdapterData, DataType: AdapterTypeSerial, Data: []byte{0xEE}}
+		if err := server.processMessage(unknown, time.Now(), false); err != nil {
+			t.Errorf("Expected unknown opcodes to be ignored, got %v", err)
+		}
+	})
+}
+
 func TestNodeRegistry(t *testing.T) {
 	registry := NewNodeRegistry()
 
@@ -175,104 +433,2464 @@ func TestNodeRegistry(t *testing.T) {
 			t.Errorf("Expected count 2, got %d", count)
 		}
 	})
+
+	t.Run("OnlineTransitions", func(t *testing.T) {
+		firstSeen, cameOnline := registry.UpdateNode(mac1, AdapterTypePIR, 1010, 1)
+		if firstSeen || cameOnline {
+			t.Errorf("Expected known online node, got firstSeen=%v cameOnline=%v", firstSeen, cameOnline)
+		}
+
+		offline := registry.MarkOffline(0)
+		if len(offline) != 2 {
+			t.Fatalf("Expected 2 nodes to go offline, got %d", len(offline))
+		}
+		if len(registry.MarkOffline(0)) != 0 {
+			t.Error("Expected offline nodes to be reported only once")
+		}
+
+		_, cameOnline = registry.UpdateNode(mac1, AdapterTypePIR, 1020, 1)
+		if !cameOnline {
+			t.Error("Expected node to come back online")
+		}
+	})
 }
 
-func TestSerialComm(t *testing.T) {
-	mockPort := NewMockSerialPort()
-	comm := NewSerialComm(mockPort)
+func TestNodeQuery(t *testing.T) {
+	server := NewMeshServer(MeshServerConfig{HealthTimeout: time.Minute})
+	api := NewAPIServer(server)
+	now := time.Now()
+	registry := server.GetNodeRegistry()
+	registry.UpdateNodeAt([]byte{0x01, 0, 0, 0, 0, 0}, AdapterTypePIR, 10, 1, now.Add(-10*time.Second))
+	registry.UpdateNodeAt([]byte{0x02, 0, 0, 0, 0, 0}, AdapterTypeLED, 20, 3, now.Add(-5*time.Minute))
+	registry.UpdateNodeAt([]byte{0x03, 0, 0, 0, 0, 0}, AdapterTypePIR, 30, 2, now.Add(-20*time.Second))
+	registry.SetMetadata([]byte{0x03, 0, 0, 0, 0, 0}, "Hallway", "")
 
-	t.Run("WriteAndReadFrame", func(t *testing.T) {
-		// Create test message
-		originalMsg := &MeshMessage{
-			MessageType: MessageTypeAdapterData,
-			DataType:    AdapterTypePIR,
-			Data:        []byte{0x01, 0x02, 0x03, 0x04},
+	getNodes := func(t *testing.T, query string) (APIResponse, []map[string]interface{}) {
+		t.Helper()
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/nodes?"+query, nil))
+		var response struct {
+			APIResponse
+			Data []map[string]interface{} `json:"data"`
+		}
+		json.NewDecoder(w.Body).Decode(&response)
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status 200, got %d: %s", w.Code, response.Error)
+		}
+		return response.APIResponse, response.Data
+	}
+	macs := func(nodes []map[string]interface{}) string {
+		var macs []string
+		for _, node := range nodes {
+			macs = append(macs, node["macString"].(string)[:2])
 		}
+		return strings.Join(macs, ",")
+	}
 
-		// Write frame
-		err := comm.WriteFrame(originalMsg)
+	testCases := []struct {
+		query string
+		want  string
+	}{
+		{"", "01,02,03"},
+		{"sort=-lastSeen", "01,03,02"},
+		{"sort=hopCount", "01,03,02"},
+		{"sort=-name", "03,01,02"},
+		{"status=online", "01,03"},
+		{"status=offline", "02"},
+		{"adapterType=led", "02"},
+		{"adapterType=0&minHops=2", "03"},
+		{"minHops=2&maxHops=2", "03"},
+	}
+	for _, tc := range testCases {
+		t.Run("Query/"+tc.query, func(t *testing.T) {
+			if _, nodes := getNodes(t, tc.query); macs(nodes) != tc.want {
+				t.Errorf("Expected nodes %s, got %s", tc.want, macs(nodes))
+			}
+		})
+	}
+
+	t.Run("ComputedFields", func(t *testing.T) {
+		_, nodes := getNodes(t, "sort=mac")
+		if nodes[0]["online"] != true || nodes[1]["online"] != false {
+			t.Errorf("Expected online from the health timeout, got %v and %v", nodes[0]["online"], nodes[1]["online"])
+		}
+		if ago := nodes[1]["lastSeenAgo"].(float64); ago < 300 || ago > 310 {
+			t.Errorf("Expected lastSeenAgo of about 300 seconds, got %v", ago)
+		}
+		if nodes[1]["adapterTypeName"] != "LED" {
+			t.Errorf("Expected adapterTypeName LED, got %v", nodes[1]["adapterTypeName"])
+		}
+	})
+
+	t.Run("Pagination", func(t *testing.T) {
+		var pages []string
+		cursor := ""
+		for {
+			response, nodes := getNodes(t, "sort=-hopCount&limit=2&cursor="+cursor)
+			pages = append(pages, macs(nodes))
+			if cursor = response.NextCursor; cursor == "" {
+				break
+			}
+		}
+		if got := strings.Join(pages, "|"); got != "02,03|01" {
+			t.Errorf("Expected pages 02,03|01, got %s", got)
+		}
+	})
+
+	t.Run("Fields", func(t *testing.T) {
+		_, nodes := getNodes(t, "fields=macString,online")
+		if len(nodes[0]) != 2 || nodes[0]["online"] != true {
+			t.Errorf("Expected only macString and online, got %v", nodes[0])
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		response, _ := getNodes(t, "sort=hopCount&limit=1")
+		for _, query := range []string{
+			"sort=uptime",
+			"status=asleep",
+			"adapterType=toaster",
+			"minHops=3&maxHops=1",
+			"limit=0",
+			"fields=secret",
+			"cursor=garbage",
+			"sort=mac&cursor=" + response.NextCursor,
+		} {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/nodes?"+query, nil))
+			if w.Code != http.StatusBadRequest {
+				t.Errorf("Expected status 400 for %s, got %d", query, w.Code)
+			}
+		}
+	})
+}
+
+func TestMotionHistory(t *testing.T) {
+	base := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+	records := []MotionRecord{
+		{MAC: "aa:bb:cc:dd:ee:ff", Zone: "hall", HopCount: 1, ReceivedAt: base.Add(5 * time.Minute)},
+		{MAC: "11:22:33:44:55:66", HopCount: 2, ReceivedAt: base.Add(70 * time.Minute)},
+		{MAC: "aa:bb:cc:dd:ee:ff", Zone: "hall", HopCount: 1, ReceivedAt: base.Add(10 * time.Minute)},
+	}
+
+	t.Run("Query", func(t *testing.T) {
+		history := NewMotionHistory(10)
+		for _, record := range records {
+			history.Record(record)
+		}
+		if isNew, _ := history.Record(records[0]); isNew {
+			t.Error("Expected duplicate record to be ignored")
+		}
+
+		all := history.Query(MotionFilter{})
+		if len(all) != 3 || !all[0].ReceivedAt.Equal(records[1].ReceivedAt) {
+			t.Fatalf("Expected 3 records newest first, got %+v", all)
+		}
+		if hall := history.Query(MotionFilter{Zones: map[string]bool{"hall": true}}); len(hall) != 2 {
+			t.Errorf("Expected 2 records in hall, got %d", len(hall))
+		}
+		if early := history.Query(MotionFilter{To: base.Add(time.Hour), Limit: 1}); len(early) != 1 || early[0].ReceivedAt != records[2].ReceivedAt {
+			t.Errorf("Expected the latest record before 13:00, got %+v", early)
+		}
+	})
+
+	t.Run("Stats", func(t *testing.T) {
+		history := NewMotionHistory(10)
+		for _, record := range records {
+			history.Record(record)
+		}
+
+		stats, err := history.Stats(MotionFilter{}, time.Hour, base.Add(3*time.Hour+time.Minute))
 		if err != nil {
-			t.Fatalf("Expected no error writing frame, got %v", err)
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(stats.Buckets) != 4 || !stats.Buckets[0].Start.Equal(base) {
+			t.Fatalf("Expected 4 hourly buckets from 12:00, got %+v", stats.Buckets)
+		}
+		first, second := stats.Buckets[0], stats.Buckets[1]
+		if first.Total != 2 || first.Nodes["aa:bb:cc:dd:ee:ff"] != 2 || first.Zones["hall"] != 2 {
+			t.Errorf("Unexpected first bucket: %+v", first)
+		}
+		if second.Total != 1 || len(second.Zones) != 0 || stats.Buckets[2].Total != 0 {
+			t.Errorf("Unexpected later buckets: %+v", stats.Buckets[1:])
 		}
 
-		// Get written data and add it to read buffer for testing
-		writtenData := mockPort.GetWrittenData()
-		mockPort.AddReadData(writtenData)
+		if _, err := history.Stats(MotionFilter{From: base}, time.Minute, base.Add(48*time.Hour)); err == nil {
+			t.Error("Expected too many buckets to be rejected")
+		}
+	})
 
-		// Read frame back
-		readMsg, err := comm.ReadFrame()
+	t.Run("Persistence", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "motion.jsonl")
+		history, err := OpenMotionHistory(path, 2)
 		if err != nil {
-			t.Fatalf("Expected no error reading frame, got %v", err)
+			t.Fatalf("Expected no error, got %v", err)
 		}
+		for _, record := range records {
+			history.Record(record)
+		}
+		history.Close()
 
-		// Compare messages
-		if readMsg.MessageType != originalMsg.MessageType {
-			t.Errorf("Expected MessageType %d, got %d", originalMsg.MessageType, readMsg.MessageType)
+		reopened, err := OpenMotionHistory(path, 2)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer reopened.Close()
+		if reopened.Len() != 2 {
+			t.Fatalf("Expected the 2 most recent records to be loaded, got %d", reopened.Len())
+		}
+		if isNew, _ := reopened.Record(records[2]); isNew {
+			t.Error("Expected loaded records to be deduplicated")
 		}
 
-		if readMsg.DataType != originalMsg.DataType {
-			t.Errorf("Expected DataType %d, got %d", originalMsg.DataType, readMsg.DataType)
+		data, _ := os.ReadFile(path)
+		if lines := strings.Count(string(data), "\n"); lines != 2 {
+			t.Errorf("Expected the file to be compacted to 2 lines, got %d", lines)
 		}
+	})
 
-		if !bytes.Equal(readMsg.Data, originalMsg.Data) {
-			t.Errorf("Expected Data %x, got %x", originalMsg.Data, readMsg.Data)
+	t.Run("API", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		for _, record := range records {
+			server.GetMotionHistory().Record(record)
+		}
+		api := NewAPIServer(server)
+
+		testCases := []struct {
+			query  string
+			status int
+		}{
+			{"/motion?mac=aa:bb:cc:dd:ee:ff&from=2024-01-01T12:00:00Z", http.StatusOK},
+			{"/motion?from=yesterday", http.StatusBadRequest},
+			{"/motion/stats?bucket=15m&zone=hall", http.StatusOK},
+			{"/motion/stats?bucket=1s", http.StatusBadRequest},
+		}
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1"+tc.query, nil))
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d for %s, got %d: %s", tc.status, tc.query, w.Code, w.Body.String())
+			}
 		}
 	})
 }
 
-func TestStringToMAC(t *testing.T) {
-	testCases := []struct {
-		input    string
-		expected []byte
-		hasError bool
-	}{
-		{"aa:bb:cc:dd:ee:ff", []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, false},
-		{"11:22:33:44:55:66", []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, false},
-		{"aabbccddeeff", []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, false},
-		{"invalid", nil, true},
-		{"aa:bb:cc:dd:ee", nil, true}, // Too short
+func TestWebhooks(t *testing.T) {
+	mac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+
+	type received struct {
+		header http.Header
+		body   []byte
 	}
+	requests := make(chan received, 10)
+	var failures atomic.Int32
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- received{r.Header, body}
+		switch {
+		case r.URL.Path == "/rejects":
+			w.WriteHeader(http.StatusBadRequest)
+		case failures.Add(-1) >= 0:
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer receiver.Close()
 
-	for _, tc := range testCases {
-		t.Run(tc.input, func(t *testing.T) {
-			result, err := StringToMAC(tc.input)
-			
-			if tc.hasError {
-				if err == nil {
-					t.Errorf("Expected error for input %s", tc.input)
-				}
-			} else {
-				if err != nil {
-					t.Errorf("Unexpected error for input %s: %v", tc.input, err)
-				}
-				
-				if !bytes.Equal(result, tc.expected) {
-					t.Errorf("Expected %x, got %x for input %s", tc.expected, result, tc.input)
-				}
+	webhooks, err := NewWebhookManager(WebhookConfig{
+		Path:        filepath.Join(t.TempDir(), "webhooks.json"),
+		BaseBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer webhooks.Close()
+	server := NewMeshServer(MeshServerConfig{Webhooks: webhooks})
+
+	waitForStatus := func(t *testing.T, id string, dead bool, status string) WebhookDelivery {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for time.Now().Before(deadline) {
+			deliveries, _ := webhooks.Deliveries(id, dead)
+			if len(deliveries) > 0 && deliveries[0].Status == status {
+				return deliveries[0]
 			}
-		})
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("Expected a %s delivery", status)
+		return WebhookDelivery{}
 	}
+
+	t.Run("SignedWithRetries", func(t *testing.T) {
+		hook, err := webhooks.Create(Webhook{URL: receiver.URL + "/ok", Types: []string{"node"}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer webhooks.Delete(hook.ID)
+
+		failures.Store(2)
+		server.publishLifecycle(mac, events.NodeOnline, true, time.Now())
+		delivery := waitForStatus(t, hook.ID, false, DeliveryDelivered)
+		if delivery.Attempts != 3 || delivery.EventType != events.TypeNodeLifecycle {
+			t.Errorf("Expected node event delivered on the third attempt, got %+v", delivery)
+		}
+
+		var last received
+		for len(requests) > 0 {
+			last = <-requests
+		}
+		timestamp, _ := strconv.ParseInt(last.header.Get(HeaderWebhookTimestamp), 10, 64)
+		if last.header.Get(HeaderWebhookSignature) != SignWebhook(hook.Secret, timestamp, last.body) {
+			t.Error("Expected a valid signature")
+		}
+		if !strings.Contains(string(last.body), `"subject":"aa:bb:cc:dd:ee:ff"`) {
+			t.Errorf("Expected the CloudEvents envelope, got %s", last.body)
+		}
+	})
+
+	t.Run("Filtered", func(t *testing.T) {
+		hook, _ := webhooks.Create(Webhook{URL: receiver.URL + "/ok", Types: []string{"motion"}})
+		defer webhooks.Delete(hook.ID)
+
+		server.publishLifecycle(mac, events.NodeOffline, false, time.Now())
+		if deliveries, _ := webhooks.Deliveries(hook.ID, false); len(deliveries) != 0 {
+			t.Errorf("Expected no deliveries for a node event, got %+v", deliveries)
+		}
+	})
+
+	t.Run("DeadLetters", func(t *testing.T) {
+		hook, _ := webhooks.Create(Webhook{URL: receiver.URL + "/rejects"})
+		defer webhooks.Delete(hook.ID)
+
+		server.publishLifecycle(mac, events.NodeOnline, false, time.Now())
+		dead := waitForStatus(t, hook.ID, true, DeliveryDead)
+		if dead.Attempts != 1 || dead.LastStatus != http.StatusBadRequest {
+			t.Errorf("Expected a rejected delivery not to be retried, got %+v", dead)
+		}
+
+		retry, err := webhooks.Redeliver(hook.ID, dead.ID)
+		if err != nil || retry.EventID != dead.EventID {
+			t.Fatalf("Expected the event to be queued again, got %+v, %v", retry, err)
+		}
+		if deadLetters, _ := webhooks.Deliveries(hook.ID, true); len(deadLetters) != 0 && deadLetters[0].ID == dead.ID {
+			t.Error("Expected the dead letter to be removed")
+		}
+	})
+
+	t.Run("Persisted", func(t *testing.T) {
+		hook, _ := webhooks.Create(Webhook{URL: receiver.URL + "/ok", Zones: []string{"hall"}})
+		reloaded, err := NewWebhookManager(WebhookConfig{Path: webhooks.config.Path})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer reloaded.Close()
+
+		hooks := reloaded.List()
+		if len(hooks) != 1 || hooks[0].ID != hook.ID || hooks[0].Secret != "" {
+			t.Errorf("Expected the webhook without its secret, got %+v", hooks)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		api := NewAPIServer(server)
+		testCases := []struct {
+			method, path, body string
+			status             int
+		}{
+			{"POST", "/webhooks", `{"url":"ftp://example.com"}`, http.StatusBadRequest},
+			{"POST", "/webhooks", `{"url":"https://example.com","types":["toaster"]}`, http.StatusBadRequest},
+			{"POST", "/webhooks", `{"url":"https://example.com","types":["motion"]}`, http.StatusCreated},
+			{"GET", "/webhooks", "", http.StatusOK},
+			{"GET", "/webhooks/missing/deliveries", "", http.StatusNotFound},
+			{"DELETE", "/webhooks/missing", "", http.StatusNotFound},
+		}
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(tc.method, "/api/v1"+tc.path, strings.NewReader(tc.body)))
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d for %s %s, got %d: %s", tc.status, tc.method, tc.path, w.Code, w.Body.String())
+			}
+		}
+	})
 }
 
-func TestGetAdapterTypeName(t *testing.T) {
-	testCases := []struct {
-		adapterType int32
-		expected    string
-	}{
-		{AdapterTypeUnknown, "Unknown"},
-		{AdapterTypePIR, "PIR"},
-		{AdapterTypeWIFI, "WiFi"},
-		{AdapterTypeLED, "LED"},
-		{AdapterTypeSerial, "Serial"},
-		{99, "Unknown(99)"},
+func TestMQTT(t *testing.T) {
+	mac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+
+	broker := mochi.New(&mochi.Options{InlineClient: true, Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	broker.AddHook(new(mochiauth.AllowHook), nil)
+	listener := listeners.NewTCP(listeners.Config{ID: "test", Address: "127.0.0.1:0"})
+	if err := broker.AddListener(listener); err != nil {
+		t.Fatalf("Expected broker listener, got %v", err)
 	}
+	go broker.Serve()
+	defer broker.Close()
 
-	for _, tc := range testCases {
-		t.Run(tc.expected, func(t *testing.T) {
-			result := GetAdapterTypeName(tc.adapterType)
-			if result != tc.expected {
-				t.Errorf("Expected %s, got %s", tc.expected, result)
+	var mu sync.Mutex
+	published := make(map[string]string)
+	broker.Subscribe("#", 1, func(_ *mochi.Client, _ packets.Subscription, pk packets.Packet) {
+		mu.Lock()
+		published[pk.TopicName] = string(pk.Payload)
+		mu.Unlock()
+	})
+	waitFor := func(t *testing.T, topic string, match func(string) bool) string {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			mu.Lock()
+			payload, ok := published[topic]
+			mu.Unlock()
+			if ok && match(payload) {
+				return payload
 			}
-		})
+			time.Sleep(10 * time.Millisecond)
+		}
+		t.Fatalf("Expected a matching message on %s", topic)
+		return ""
+	}
+	equals := func(want string) func(string) bool {
+		return func(payload string) bool { return payload == want }
+	}
+
+	server := NewMeshServer(MeshServerConfig{})
+	server.GetNodeRegistry().UpdateNode(mac, AdapterTypePIR, 1000, 2)
+	server.GetNodeRegistry().SetMetadata(mac, "Hall sensor", "hall")
+
+	bridge := NewMQTTBridge(server, MQTTConfig{Broker: "tcp://" + listener.Address()})
+	if err := bridge.Start(); err != nil {
+		t.Fatalf("Expected bridge to connect, got %v", err)
 	}
+	defer bridge.Stop()
+
+	t.Run("Discovery", func(t *testing.T) {
+		payload := waitFor(t, "homeassistant/binary_sensor/planetopia_aabbccddeeff/motion/config", func(string) bool { return true })
+		var config struct {
+			UniqueID   string `json:"unique_id"`
+			StateTopic string `json:"state_topic"`
+			Device     struct {
+				Name          string `json:"name"`
+				SuggestedArea string `json:"suggested_area"`
+			} `json:"device"`
+		}
+		if err := json.Unmarshal([]byte(payload), &config); err != nil {
+			t.Fatalf("Expected JSON discovery config, got %v", err)
+		}
+		if config.UniqueID != "planetopia_aabbccddeeff_motion" || config.StateTopic != "planetopia/aabbccddeeff/motion" ||
+			config.Device.Name != "Hall sensor" || config.Device.SuggestedArea != "hall" {
+			t.Errorf("Unexpected motion discovery config: %s", payload)
+		}
+		for _, topic := range []string{
+			"homeassistant/sensor/planetopia_aabbccddeeff/uptime/config",
+			"homeassistant/sensor/planetopia_aabbccddeeff/hop_count/config",
+			"homeassistant/select/planetopia_aabbccddeeff/adapter_type/config",
+			"homeassistant/text/planetopia_aabbccddeeff/led/config",
+		} {
+			waitFor(t, topic, func(string) bool { return true })
+		}
+	})
+
+	t.Run("State", func(t *testing.T) {
+		waitFor(t, "planetopia/bridge/availability", equals(mqttOnline))
+		waitFor(t, "planetopia/aabbccddeeff/state", func(payload string) bool {
+			return strings.Contains(payload, `"uptime":1000`) && strings.Contains(payload, `"adapterType":"PIR"`)
+		})
+		waitFor(t, "planetopia/aabbccddeeff/availability", equals(mqttOnline))
+
+		server.handlePIRData(&MeshMessage{OriginMacAddress: mac, HopCount: 2}, time.Now(), false)
+		waitFor(t, "planetopia/aabbccddeeff/motion", equals(mqttMotion))
+
+		server.GetNodeRegistry().MarkOffline(0)
+		server.publishLifecycle(mac, events.NodeOffline, false, time.Now())
+		waitFor(t, "planetopia/aabbccddeeff/availability", equals(mqttOffline))
+	})
+
+	t.Run("Commands", func(t *testing.T) {
+		waitForAudit := func(t *testing.T, action string) events.AuditEvent {
+			t.Helper()
+			deadline := time.Now().Add(5 * time.Second)
+			for time.Now().Before(deadline) {
+				entries, _ := server.GetAuditLog().Query(AuditFilter{})
+				for _, entry := range entries {
+					if entry.Action == action {
+						return entry
+					}
+				}
+				time.Sleep(10 * time.Millisecond)
+			}
+			t.Fatalf("Expected an audited %s command", action)
+			return events.AuditEvent{}
+		}
+
+		broker.Publish("planetopia/aabbccddeeff/adapter_type/set", []byte("LED"), false, 1)
+		entry := waitForAudit(t, "configureNode")
+		if entry.Interface != "mqtt" || entry.ActorName != "mqtt" || entry.Actor != "" || entry.Path != "planetopia/aabbccddeeff/adapter_type/set" ||
+			entry.Target != "aa:bb:cc:dd:ee:ff" || !strings.Contains(entry.Error, "not running") {
+			t.Errorf("Expected the command to reach the stopped mesh server, got %+v", entry)
+		}
+
+		broker.Publish("planetopia/aabbccddeeff/led/set", []byte("not hex"), false, 1)
+		entry = waitForAudit(t, "sendAdapterData")
+		if entry.Outcome != events.AuditFailure || !strings.Contains(entry.Error, "hex") {
+			t.Errorf("Expected invalid LED data to be rejected, got %+v", entry)
+		}
+	})
+}
+
+func TestRestart(t *testing.T) {
+	mac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+
+	t.Run("Tracker", func(t *testing.T) {
+		tracker := NewRestartTracker(time.Minute)
+		seen := time.Now()
+		requested := seen.Add(5 * time.Second)
+		node := &NodeInfo{MAC: mac, MACString: "aa:bb:cc:dd:ee:ff", Uptime: 1000, LastSeen: seen}
+		tracker.Begin(node, RestartReboot, requested)
+
+		if _, confirmed := tracker.Observe(node.MACString, 1010, seen.Add(10*time.Second)); confirmed {
+			t.Error("Expected a report from before the reboot not to confirm it")
+		}
+		restart, confirmed := tracker.Observe(node.MACString, 4, requested.Add(5*time.Second))
+		if !confirmed || restart.Status != RestartConfirmed || *restart.Uptime != 4 {
+			t.Errorf("Expected a reset uptime to confirm the reboot, got %+v", restart)
+		}
+
+		tracker.Begin(node, RestartFactoryReset, requested)
+		if restart, _ := tracker.Get(node.MACString, requested.Add(2*time.Minute)); restart.Status != RestartTimedOut {
+			t.Errorf("Expected the reset to time out, got %s", restart.Status)
+		}
+	})
+
+	t.Run("Confirmations", func(t *testing.T) {
+		store := newConfirmationStore()
+		now := time.Now()
+		token, _, _ := store.issue(RestartFactoryReset, "aa:bb:cc:dd:ee:ff", "alice", now)
+		if store.consume(token, RestartFactoryReset, "aa:bb:cc:dd:ee:ff", "bob", now) {
+			t.Error("Expected a token to be bound to its caller")
+		}
+		if store.consume(token, RestartFactoryReset, "aa:bb:cc:dd:ee:ff", "alice", now) {
+			t.Error("Expected a token to be single-use")
+		}
+		token, _, _ = store.issue(RestartFactoryReset, "aa:bb:cc:dd:ee:ff", "alice", now)
+		if store.consume(token, RestartFactoryReset, "aa:bb:cc:dd:ee:ff", "alice", now.Add(2*confirmationTTL)) {
+			t.Error("Expected an expired token to be rejected")
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		port := NewMockSerialPort()
+		server.serialComm = NewSerialComm(port)
+		server.running = true
+		server.GetNodeRegistry().UpdateNode(mac, AdapterTypePIR, 5000, 1)
+		api := NewAPIServer(server)
+
+		request := func(method, path, body string) (int, APIResponse) {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(method, "/api/v1"+path, strings.NewReader(body)))
+			var response APIResponse
+			json.Unmarshal(w.Body.Bytes(), &response)
+			return w.Code, response
+		}
+
+		if code, _ := request("POST", "/nodes/11:22:33:44:55:66/reboot", ""); code != http.StatusNotFound {
+			t.Errorf("Expected 404 for an unknown node, got %d", code)
+		}
+		if code, _ := request("POST", "/nodes/aa:bb:cc:dd:ee:ff/reboot", ""); code != http.StatusAccepted {
+			t.Fatalf("Expected the reboot to be accepted, got %d", code)
+		}
+		if !bytes.Contains(port.GetWrittenData(), []byte{OpReboot, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}) {
+			t.Error("Expected a reboot frame on the serial port")
+		}
+
+		report, _ := HealthReportOpcode.Build(nil, HealthReport{MAC: mac, AdapterType: AdapterTypePIR, Uptime: 1})
+		server.processMessage(report, time.Now(), false)
+		server.wg.Wait() // The rebooted node is asked for its info
+		if !bytes.Contains(port.GetWrittenData(), []byte{OpNodeInfoReq, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}) {
+			t.Error("Expected a node info request after the reboot")
+		}
+		code, response := request("GET", "/nodes/aa:bb:cc:dd:ee:ff/restart", "")
+		if data, _ := json.Marshal(response.Data); code != http.StatusOK || !strings.Contains(string(data), `"status":"confirmed"`) {
+			t.Errorf("Expected a confirmed reboot, got %d: %s", code, data)
+		}
+
+		code, response = request("POST", "/nodes/aa:bb:cc:dd:ee:ff/reset", "")
+		if code != http.StatusPreconditionRequired {
+			t.Fatalf("Expected the reset to need confirmation, got %d", code)
+		}
+		if bytes.Contains(port.GetWrittenData(), []byte{OpFactoryReset}) {
+			t.Error("Expected no reset frame before confirmation")
+		}
+		token := response.Data.(map[string]interface{})["confirmationToken"].(string)
+
+		if code, _ := request("POST", "/nodes/ff:ff:ff:ff:ff:ff/reset", `{"confirmationToken":"`+token+`"}`); code != http.StatusBadRequest {
+			t.Errorf("Expected the token to be bound to its node, got %d", code)
+		}
+		_, response = request("POST", "/nodes/aa:bb:cc:dd:ee:ff/reset", "")
+		token = response.Data.(map[string]interface{})["confirmationToken"].(string)
+		if code, _ := request("POST", "/nodes/aa:bb:cc:dd:ee:ff/reset", `{"confirmationToken":"`+token+`"}`); code != http.StatusAccepted {
+			t.Errorf("Expected the confirmed reset to be accepted, got %d", code)
+		}
+		if !bytes.Contains(port.GetWrittenData(), []byte{OpFactoryReset, 0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}) {
+			t.Error("Expected a reset frame on the serial port")
+		}
+	})
+}
+
+func TestPing(t *testing.T) {
+	mac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	server := NewMeshServer(MeshServerConfig{})
+	port := &echoPort{server: server, drop: map[uint16]bool{2: true}}
+	server.serialComm = NewSerialComm(port)
+	server.running = true
+	server.GetNodeRegistry().UpdateNode(mac, AdapterTypePIR, 100, 3)
+
+	t.Run("Stats", func(t *testing.T) {
+		stats, err := server.PingN(context.Background(), mac, 3, time.Millisecond, 100*time.Millisecond)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if stats.Sent != 3 || stats.Received != 2 || len(stats.RTTsMs) != 2 || stats.Loss < 0.33 || stats.Loss > 0.34 {
+			t.Errorf("Expected one of three pings lost, got %+v", stats)
+		}
+		if stats.MinMs > stats.AvgMs || stats.AvgMs > stats.MaxMs {
+			t.Errorf("Expected min <= avg <= max, got %+v", stats)
+		}
+
+		latency, exists := server.GetLatency("aa:bb:cc:dd:ee:ff")
+		if !exists || latency.Sent != 3 || latency.Lost != 1 {
+			t.Errorf("Expected the pings recorded as the node's latency, got %+v", latency)
+		}
+	})
+
+	t.Run("UnmatchedPong", func(t *testing.T) {
+		ping := server.pings.begin("aa:bb:cc:dd:ee:ff", time.Now())
+		defer server.pings.cancel(ping)
+
+		server.pings.resolve("aa:bb:cc:dd:ee:ff", Echo{Sequence: ping.echo.Sequence, Timestamp: ping.echo.Timestamp + 1}, time.Now())
+		server.pings.resolve("11:22:33:44:55:66", ping.echo, time.Now())
+		select {
+		case <-ping.reply:
+			t.Error("Expected pongs for another ping or node to be dropped")
+		default:
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		api := NewAPIServer(server)
+		testCases := []struct {
+			path   string
+			status int
+		}{
+			{"/nodes/aa:bb:cc:dd:ee:ff/ping?count=2", http.StatusOK},
+			{"/nodes/aa:bb:cc:dd:ee:ff/ping?count=11", http.StatusBadRequest},
+			{"/nodes/11:22:33:44:55:66/ping", http.StatusNotFound},
+		}
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1"+tc.path, nil))
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d for %s, got %d: %s", tc.status, tc.path, w.Code, w.Body.String())
+			}
+		}
+
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/nodes/aa:bb:cc:dd:ee:ff", nil))
+		if !strings.Contains(w.Body.String(), `"latency":{"lastRttMs"`) {
+			t.Errorf("Expected the node to include its latency, got %s", w.Body.String())
+		}
+	})
+}
+
+// TestStopWhileProbing stops the server while the latency probe is waiting
+// to send
+func TestStopWhileProbing(t *testing.T) {
+	server := NewMeshServer(MeshServerConfig{PingInterval: time.Millisecond})
+	server.serialComm = NewSerialComm(NewMockSerialPort())
+	server.running = true
+	server.GetNodeRegistry().UpdateNode([]byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x01}, AdapterTypePIR, 100, 1)
+
+	// Hold the lock senders share until Stop is waiting for it
+	server.mu.RLock()
+	stopped := make(chan error, 1)
+	go func() { stopped <- server.Stop() }()
+	for server.mu.TryRLock() {
+		server.mu.RUnlock()
+		runtime.Gosched()
+	}
+
+	// The probe's ping now blocks behind Stop
+	server.wg.Add(1)
+	go server.latencyProbe()
+	for {
+		server.pings.mu.Lock()
+		pending := len(server.pings.pending)
+		server.pings.mu.Unlock()
+		if pending > 0 {
+			break
+		}
+		runtime.Gosched()
+	}
+	server.mu.RUnlock()
+
+	select {
+	case err := <-stopped:
+		if err != nil {
+			t.Errorf("Expected no error, got %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop deadlocked with the latency probe")
+	}
+}
+
+// TestStartAfterStop restarts the server and checks the new run reads frames
+func TestStartAfterStop(t *testing.T) {
+	mac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	server := NewMeshServer(MeshServerConfig{})
+	before := testutil.ToFloat64(serialReconnects)
+
+	if err := server.startWith(func() (SerialPort, error) { return NewMockSerialPort(), nil }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := server.Stop(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	port := NewMockSerialPort()
+	report, _ := HealthReportOpcode.Build(nil, HealthReport{MAC: mac, AdapterType: AdapterTypePIR, Uptime: 1})
+	if err := NewSerialComm(port).WriteFrame(report); err != nil {
+		t.Fatalf("Expected no error writing frame, got %v", err)
+	}
+	port.AddReadData(port.GetWrittenData())
+	if err := server.startWith(func() (SerialPort, error) { return port, nil }); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer server.Stop()
+
+	if reconnects := testutil.ToFloat64(serialReconnects) - before; reconnects != 1 {
+		t.Errorf("Expected one reconnect, got %v", reconnects)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if _, exists := server.GetNodeRegistry().GetNode(mac); exists {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("Expected the restarted server to process frames")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestNodeInfo(t *testing.T) {
+	nodeA := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x01}
+	nodeB := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x02}
+	nodeC := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x03}
+	server := NewMeshServer(MeshServerConfig{})
+	port := &echoPort{server: server, details: map[string]NodeDetails{
+		"aa:00:00:00:00:01": {Major: 1, Minor: 2, Patch: 0, Build: 0x3f2a9c01, ChipModel: 5, ChipRevision: 0x04, Adapters: 0b1001},
+		"aa:00:00:00:00:02": {Major: 1, Minor: 10, Patch: 3, Build: 0xdeadbeef, ChipModel: 1, ChipRevision: 0x31, Adapters: 0b0111},
+	}}
+	server.serialComm = NewSerialComm(port)
+	server.running = true
+
+	firmware := func(mac []byte) *NodeFirmware {
+		node, _ := server.GetNodeRegistry().GetNode(mac)
+		return node.Firmware
+	}
+	waitFor := func(t *testing.T, mac []byte) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for firmware(mac) == nil {
+			if time.Now().After(deadline) {
+				t.Fatalf("Expected %s to report its firmware", macToString(mac))
+			}
+			time.Sleep(time.Millisecond)
+		}
+	}
+
+	t.Run("Payload", func(t *testing.T) {
+		details := NodeDetails{Major: 1, Minor: 2, Patch: 3, Build: 0x01020304, ChipModel: 9, ChipRevision: 0x12, Adapters: 0x05}
+		msg, err := NodeInfoOpcode.Build(nil, details)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		parsed, err := NodeInfoOpcode.Parse(msg)
+		if err != nil || parsed != details {
+			t.Errorf("Expected %+v to round-trip, got %+v (%v)", details, parsed, err)
+		}
+
+		got := details.Firmware(time.Time{})
+		want := NodeFirmware{Version: "1.2.3", BuildHash: "01020304", ChipModel: "ESP32-S3", ChipRevision: "v1.2", SupportedAdapters: []string{"PIR", "LED"}}
+		if got.Version != want.Version || got.BuildHash != want.BuildHash || got.ChipModel != want.ChipModel ||
+			got.ChipRevision != want.ChipRevision || strings.Join(got.SupportedAdapters, ",") != "PIR,LED" {
+			t.Errorf("Expected %+v, got %+v", want, got)
+		}
+		if model := (NodeDetails{ChipModel: 99}).Firmware(time.Time{}).ChipModel; model != "Unknown(99)" {
+			t.Errorf("Expected an unknown chip to be labelled, got %s", model)
+		}
+	})
+
+	t.Run("RequestedWhenOnline", func(t *testing.T) {
+		for _, mac := range [][]byte{nodeA, nodeB, nodeC} {
+			report, _ := HealthReportOpcode.Build(nil, HealthReport{AdapterType: AdapterTypePIR, MAC: mac, Uptime: 60})
+			report.OriginMacAddress = mac
+			if err := server.processMessage(report, time.Now(), false); err != nil {
+				t.Fatalf("Failed to process health report: %v", err)
+			}
+		}
+		waitFor(t, nodeA)
+		waitFor(t, nodeB)
+
+		if fw := firmware(nodeA); fw.Version != "1.2.0" || fw.BuildHash != "3f2a9c01" || fw.ChipModel != "ESP32-C3" ||
+			strings.Join(fw.SupportedAdapters, ",") != "PIR,Serial" {
+			t.Errorf("Expected node A's firmware recorded, got %+v", fw)
+		}
+		if firmware(nodeC) != nil {
+			t.Error("Expected a node that never answered to have no firmware")
+		}
+
+		// Reports from unknown nodes and stale reports are ignored
+		if server.GetNodeRegistry().SetFirmware([]byte{1, 2, 3, 4, 5, 6}, NodeFirmware{}) {
+			t.Error("Expected firmware of an unknown node to be ignored")
+		}
+		stale := NodeDetails{Major: 0, Minor: 9}.Firmware(time.Now().Add(-time.Hour))
+		server.GetNodeRegistry().SetFirmware(nodeA, stale)
+		if fw := firmware(nodeA); fw.Version != "1.2.0" {
+			t.Errorf("Expected a stale report to be ignored, got %s", fw.Version)
+		}
+	})
+
+	t.Run("Filter", func(t *testing.T) {
+		api := NewAPIServer(server)
+		testCases := []struct {
+			query string
+			want  string
+		}{
+			{"firmware=1.2.0", "aa:00:00:00:00:01"},
+			{"firmware=1.10.3,unknown", "aa:00:00:00:00:02,aa:00:00:00:00:03"},
+			{"firmwareBelow=1.3", "aa:00:00:00:00:01,aa:00:00:00:00:03"},
+			{"firmwareBelow=1.10.3", "aa:00:00:00:00:01,aa:00:00:00:00:03"},
+			{"firmwareBelow=2", "aa:00:00:00:00:01,aa:00:00:00:00:02,aa:00:00:00:00:03"},
+		}
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/nodes?fields=macString&"+tc.query, nil))
+			var resp struct {
+				Data []struct {
+					MACString string `json:"macString"`
+				} `json:"data"`
+			}
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("Failed to decode %s: %v", w.Body.String(), err)
+			}
+			var macs []string
+			for _, node := range resp.Data {
+				macs = append(macs, node.MACString)
+			}
+			if got := strings.Join(macs, ","); got != tc.want {
+				t.Errorf("Expected %s for %s, got %s", tc.want, tc.query, got)
+			}
+		}
+
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/nodes?firmwareBelow=1.x", nil))
+		if w.Code != http.StatusBadRequest {
+			t.Errorf("Expected status %d for an invalid version, got %d", http.StatusBadRequest, w.Code)
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		api := NewAPIServer(server)
+		testCases := []struct {
+			path   string
+			status int
+		}{
+			{"/nodes/aa:00:00:00:00:03/info", http.StatusAccepted},
+			{"/nodes/ff:ff:ff:ff:ff:ff/info", http.StatusAccepted},
+			{"/nodes/11:22:33:44:55:66/info", http.StatusNotFound},
+		}
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1"+tc.path, nil))
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d for %s, got %d: %s", tc.status, tc.path, w.Code, w.Body.String())
+			}
+		}
+
+		port.mu.Lock()
+		defer port.mu.Unlock()
+		if asked := strings.Join(port.asked[len(port.asked)-2:], ","); asked != "aa:00:00:00:00:03,ff:ff:ff:ff:ff:ff" {
+			t.Errorf("Expected requests to the node and to all nodes, got %s", asked)
+		}
+	})
+}
+
+func TestFragmentation(t *testing.T) {
+	mac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	builder := NewMessageBuilder()
+	pattern := make([]byte, 30)
+	for i := range pattern {
+		pattern[i] = byte(i + 1)
+	}
+
+	t.Run("RoundTrip", func(t *testing.T) {
+		msg, err := builder.BuildAdapterDataMessage(mac, AdapterTypeLED, pattern)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		fragments, err := fragmentMessage(msg, 0x1234)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(fragments) != 5 {
+			t.Fatalf("Expected 30 bytes in 5 fragments, got %d", len(fragments))
+		}
+		for i, frag := range fragments {
+			if frag.MessageType != MessageTypeFragment || frag.DataType != AdapterTypeLED || !bytes.Equal(frag.TargetMacAddress, mac) {
+				t.Errorf("Expected fragment %d addressed like the message, got %+v", i, frag)
+			}
+			if len(frag.Data) > MaxDataLength || binary.LittleEndian.Uint16(frag.Data[0:2]) != 0x1234 || frag.Data[2] != byte(i) || frag.Data[3] != 5 {
+				t.Errorf("Expected fragment %d of 5 of message 0x1234, got %x", i, frag.Data)
+			}
+		}
+		if last := fragments[4].Data; len(last) != fragmentHeaderLength+2 {
+			t.Errorf("Expected the last fragment to carry the remaining 2 bytes, got %x", last)
+		}
+
+		r := newFragmentReassembler(time.Second)
+		now := time.Now()
+		for _, i := range []int{3, 0, 3, 4, 1} {
+			if reassembled, err := r.add(fragments[i], now); err != nil || reassembled != nil {
+				t.Fatalf("Expected fragment %d to wait for the rest, got %v (%v)", i, reassembled, err)
+			}
+		}
+		reassembled, err := r.add(fragments[2], now)
+		if err != nil || reassembled == nil {
+			t.Fatalf("Expected the message once every fragment arrived, got %v", err)
+		}
+		if reassembled.MessageType != MessageTypeAdapterData || reassembled.DataType != AdapterTypeLED || !bytes.Equal(reassembled.Data, pattern) {
+			t.Errorf("Expected the original message, got %+v", reassembled)
+		}
+
+		if again, err := r.add(fragments[2], now); err != nil || again != nil {
+			t.Errorf("Expected a late duplicate to be dropped, got %v (%v)", again, err)
+		}
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		msg, _ := builder.BuildAdapterDataMessage(mac, AdapterTypeLED, pattern[:20])
+		fragments, _ := fragmentMessage(msg, 7)
+		r := newFragmentReassembler(time.Second)
+		now := time.Now()
+
+		r.add(fragments[0], now)
+		r.add(fragments[1], now)
+		if reassembled, _ := r.add(fragments[2], now.Add(2*time.Second)); reassembled != nil {
+			t.Error("Expected a message missing fragments past the timeout to be dropped")
+		}
+		if len(r.partial) != 0 {
+			t.Errorf("Expected no partial messages to be kept, got %d", len(r.partial))
+		}
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		r := newFragmentReassembler(time.Second)
+		for _, data := range [][]byte{{1, 0, 0}, {1, 0, 3, 3, 0}, {1, 0, 0, 0, 0}} {
+			if _, err := r.add(&MeshMessage{MessageType: MessageTypeFragment, Data: data}, time.Now()); err == nil {
+				t.Errorf("Expected fragment %x to be rejected", data)
+			}
+		}
+		r.add(&MeshMessage{MessageType: MessageTypeFragment, Data: []byte{2, 0, 0, 2, 0, 0xAA}}, time.Now())
+		if _, err := r.add(&MeshMessage{MessageType: MessageTypeFragment, Data: []byte{2, 0, 1, 3, 0, 0xBB}}, time.Now()); err == nil {
+			t.Error("Expected a fragment with a different count to be rejected")
+		}
+
+		if _, err := builder.BuildAdapterDataMessage(mac, AdapterTypeLED, make([]byte, MaxPayloadLength+1)); err == nil {
+			t.Error("Expected data beyond the fragment limit to be rejected")
+		}
+	})
+
+	t.Run("SendMessage", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		port := NewMockSerialPort()
+		server.serialComm = NewSerialComm(port)
+		server.running = true
+
+		if err := server.SendAdapterData(mac, AdapterTypeLED, pattern[:5]); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := server.SendAdapterData(mac, AdapterTypeLED, pattern); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		var frames []*MeshMessage
+		written := port.GetWrittenData()
+		for len(written) >= 2 {
+			length := int(binary.LittleEndian.Uint16(written))
+			var msg MeshMessage
+			if err := proto.Unmarshal(written[2:2+length], &msg); err != nil {
+				t.Fatalf("Failed to decode frame: %v", err)
+			}
+			frames = append(frames, &msg)
+			written = written[2+length:]
+		}
+		if len(frames) != 6 || frames[0].MessageType != MessageTypeAdapterData || len(frames[0].Data) != MaxDataLength {
+			t.Fatalf("Expected one padded frame then 5 fragments, got %d frames", len(frames))
+		}
+
+		r := newFragmentReassembler(time.Second)
+		var reassembled *MeshMessage
+		for _, frame := range frames[1:] {
+			reassembled, _ = r.add(frame, time.Now())
+		}
+		if reassembled == nil || !bytes.Equal(reassembled.Data, pattern) {
+			t.Errorf("Expected the fragments to reassemble into the pattern, got %v", reassembled)
+		}
+	})
+
+	t.Run("Inbound", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		report, _ := HealthReportOpcode.Build(nil, HealthReport{AdapterType: AdapterTypePIR, MAC: mac, Uptime: 42})
+		report.OriginMacAddress = mac
+		fragments, _ := fragmentMessage(report, 1)
+
+		for i, frag := range fragments {
+			if err := server.processMessage(frag, time.Now(), false); err != nil {
+				t.Fatalf("Failed to process fragment %d: %v", i, err)
+			}
+			if _, exists := server.GetNodeRegistry().GetNode(mac); exists != (i == len(fragments)-1) {
+				t.Fatalf("Expected the report to be handled only after its last fragment, got %v after %d", exists, i)
+			}
+		}
+		if node, _ := server.GetNodeRegistry().GetNode(mac); node.Uptime != 42 {
+			t.Errorf("Expected the reassembled health report, got %+v", node)
+		}
+	})
+}
+
+func TestSerialComm(t *testing.T) {
+	mockPort := NewMockSerialPort()
+	comm := NewSerialComm(mockPort)
+
+	t.Run("WriteAndReadFrame", func(t *testing.T) {
+		// Create test message
+		originalMsg := &MeshMessage{
+			MessageType: MessageTypeAdapterData,
+			DataType:    AdapterTypePIR,
+			Data:        []byte{0x01, 0x02, 0x03, 0x04},
+		}
+
+		// Write frame
+		err := comm.WriteFrame(originalMsg)
+		if err != nil {
+			t.Fatalf("Expected no error writing frame, got %v", err)
+		}
+
+		// Get written data and add it to read buffer for testing
+		writtenData := mockPort.GetWrittenData()
+		mockPort.AddReadData(writtenData)
+
+		// Read frame back
+		readMsg, err := comm.ReadFrame()
+		if err != nil {
+			t.Fatalf("Expected no error reading frame, got %v", err)
+		}
+
+		// Compare messages
+		if readMsg.MessageType != originalMsg.MessageType {
+			t.Errorf("Expected MessageType %d, got %d", originalMsg.MessageType, readMsg.MessageType)
+		}
+
+		if readMsg.DataType != originalMsg.DataType {
+			t.Errorf("Expected DataType %d, got %d", originalMsg.DataType, readMsg.DataType)
+		}
+
+		if !bytes.Equal(readMsg.Data, originalMsg.Data) {
+			t.Errorf("Expected Data %x, got %x", originalMsg.Data, readMsg.Data)
+		}
+	})
+}
+
+func TestStringToMAC(t *testing.T) {
+	testCases := []struct {
+		input    string
+		expected []byte
+		hasError bool
+	}{
+		{"aa:bb:cc:dd:ee:ff", []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, false},
+		{"11:22:33:44:55:66", []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, false},
+		{"aabbccddeeff", []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, false},
+		{"invalid", nil, true},
+		{"aa:bb:cc:dd:ee", nil, true}, // Too short
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.input, func(t *testing.T) {
+			result, err := StringToMAC(tc.input)
+			
+			if tc.hasError {
+				if err == nil {
+					t.Errorf("Expected error for input %s", tc.input)
+				}
+			} else {
+				if err != nil {
+					t.Errorf("Unexpected error for input %s: %v", tc.input, err)
+				}
+				
+				if !bytes.Equal(result, tc.expected) {
+					t.Errorf("Expected %x, got %x for input %s", tc.expected, result, tc.input)
+				}
+			}
+		})
+	}
+}
+
+func TestGetAdapterTypeName(t *testing.T) {
+	testCases := []struct {
+		adapterType int32
+		expected    string
+	}{
+		{AdapterTypeUnknown, "Unknown"},
+		{AdapterTypePIR, "PIR"},
+		{AdapterTypeWIFI, "WiFi"},
+		{AdapterTypeLED, "LED"},
+		{AdapterTypeSerial, "Serial"},
+		{99, "Unknown(99)"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.expected, func(t *testing.T) {
+			result := GetAdapterTypeName(tc.adapterType)
+			if result != tc.expected {
+				t.Errorf("Expected %s, got %s", tc.expected, result)
+			}
+		})
+	}
+}
+
+func TestPublishedEvents(t *testing.T) {
+	store := NewMockEventStore()
+	server := NewMeshServer(MeshServerConfig{
+		SerialPort: "/dev/ttyUSB0",
+		EventStore: store,
+	})
+
+	msg := &MeshMessage{
+		MessageType:      MessageTypeAdapterData,
+		DataType:         AdapterTypePIR,
+		OriginMacAddress: []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF},
+		Data:             []byte{0x01},
+		HopCount:         2,
+	}
+	if err := server.handleMessage(msg); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	topics := store.GetTopics()
+	if len(topics) != 2 || topics[0] != events.TopicMeshMessages || topics[1] != events.TopicMotion {
+		t.Fatalf("Expected mesh-messages then motion-trigger, got %v", topics)
+	}
+
+	var envelope struct {
+		events.Envelope
+		Data events.MotionEvent `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(store.GetMessages()[1]), &envelope); err != nil {
+		t.Fatalf("Expected valid envelope, got %v", err)
+	}
+
+	if envelope.Type != events.TypeMotion || envelope.Source != "/orchistrator/dev/ttyUSB0" {
+		t.Errorf("Unexpected envelope attributes: type=%s source=%s", envelope.Type, envelope.Source)
+	}
+	if envelope.Data.MAC != "aa:bb:cc:dd:ee:ff" || envelope.Data.HopCount != 2 || envelope.Data.Data != "01" {
+		t.Errorf("Unexpected motion payload: %+v", envelope.Data)
+	}
+}
+
+func TestReplay(t *testing.T) {
+	store := EventStore.NewMemory(100)
+	live := NewMeshServer(MeshServerConfig{EventStore: store})
+
+	nodeMAC := []byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}
+	relayMAC := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+
+	health := make([]byte, MaxDataLength)
+	health[0] = OpHealthReport
+	health[1] = byte(AdapterTypePIR)
+	copy(health[2:8], nodeMAC)
+	health[8] = 0x10
+
+	frames := []*MeshMessage{
+		{
+			MessageType:       MessageTypeAdapterData,
+			DataType:          AdapterTypeSerial,
+			OriginMacAddress:  nodeMAC,
+			LastHopMacAddress: relayMAC,
+			Data:              health,
+			HopCount:          2,
+		},
+		{
+			MessageType:       MessageTypeAdapterData,
+			DataType:          AdapterTypePIR,
+			OriginMacAddress:  nodeMAC,
+			LastHopMacAddress: relayMAC,
+			Data:              []byte{0x01},
+			HopCount:          2,
+		},
+	}
+	for _, frame := range frames {
+		if err := live.handleMessage(frame); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+
+	t.Run("RebuildsState", func(t *testing.T) {
+		restarted := NewMeshServer(MeshServerConfig{EventStore: store})
+		result, err := restarted.Replay(context.Background(), EventStore.ReplayFrom{})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if result.Applied != 2 {
+			t.Errorf("Expected 2 applied frames, got %+v", result)
+		}
+
+		node, exists := restarted.GetNodeRegistry().GetNode(nodeMAC)
+		if !exists || node.Uptime != 16 || node.HopCount != 2 {
+			t.Fatalf("Expected node to be restored, got %+v", node)
+		}
+
+		links := restarted.GetTopology().Links()
+		if len(links) != 1 || links[0].LastHop != "aa:bb:cc:dd:ee:ff" || links[0].Frames != 2 {
+			t.Errorf("Unexpected topology: %+v", links)
+		}
+
+		if restarted.GetMotionHistory().Len() != 1 {
+			t.Errorf("Expected 1 motion record, got %d", restarted.GetMotionHistory().Len())
+		}
+	})
+
+	t.Run("PublishesNothing", func(t *testing.T) {
+		before := len(store.(EventStore.Queryable).Query("", 0, 0))
+		if _, err := live.Replay(context.Background(), EventStore.ReplayFrom{}); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if after := len(store.(EventStore.Queryable).Query("", 0, 0)); after != before {
+			t.Errorf("Expected no new events, got %d", after-before)
+		}
+		if live.GetMotionHistory().Len() != 1 {
+			t.Errorf("Expected replayed motion to be deduplicated, got %d records", live.GetMotionHistory().Len())
+		}
+	})
+
+	t.Run("ReplayTwice", func(t *testing.T) {
+		restarted := NewMeshServer(MeshServerConfig{EventStore: store})
+		for _, server := range []*MeshServer{live, restarted} {
+			for i := 0; i < 2; i++ {
+				if _, err := server.Replay(context.Background(), EventStore.ReplayFrom{}); err != nil {
+					t.Fatalf("Expected no error, got %v", err)
+				}
+			}
+			if links := server.GetTopology().Links(); len(links) != 1 || links[0].Frames != 2 {
+				t.Errorf("Expected each logged frame counted once, got %+v", links)
+			}
+		}
+	})
+
+	t.Run("RequiresReplayableStore", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{EventStore: NewMockEventStore()})
+		if _, err := server.Replay(context.Background(), EventStore.ReplayFrom{}); err == nil {
+			t.Error("Expected error for a store without replay support")
+		}
+	})
+}
+
+func TestEventStream(t *testing.T) {
+	t.Run("Filter", func(t *testing.T) {
+		filter, err := ParseStreamFilter(url.Values{"type": {"motion,node"}, "mac": {"AABBCCDDEEFF"}})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if !filter.Match(StreamEvent{Type: events.TypeMotion, Subject: "aa:bb:cc:dd:ee:ff"}) {
+			t.Error("Expected motion event from filtered MAC to match")
+		}
+		if filter.Match(StreamEvent{Type: events.TypeHealth, Subject: "aa:bb:cc:dd:ee:ff"}) {
+			t.Error("Expected health event to be filtered out")
+		}
+		if filter.Match(StreamEvent{Type: events.TypeMotion, Subject: "11:22:33:44:55:66"}) {
+			t.Error("Expected other MAC to be filtered out")
+		}
+		if _, err := ParseStreamFilter(url.Values{"type": {"bogus"}}); err == nil {
+			t.Error("Expected error for unknown event type")
+		}
+	})
+
+	t.Run("SlowSubscriberDropped", func(t *testing.T) {
+		bus := NewEventBus(1)
+		sub := bus.Subscribe(StreamFilter{})
+		bus.Publish(StreamEvent{ID: "1"})
+		bus.Publish(StreamEvent{ID: "2"}) // Must not block
+
+		select {
+		case <-sub.Done():
+		default:
+			t.Fatal("Expected slow subscriber to be disconnected")
+		}
+		if !sub.Slow() || bus.Subscribers() != 0 {
+			t.Errorf("Expected slow subscriber to be removed, slow=%v subscribers=%d", sub.Slow(), bus.Subscribers())
+		}
+	})
+
+	t.Run("ServerSentEvents", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		api := httptest.NewServer(NewAPIServer(server))
+		defer api.Close()
+
+		resp, err := http.Get(api.URL + "/events/stream?type=motion")
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		defer resp.Body.Close()
+		if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+			t.Fatalf("Expected text/event-stream, got %s", ct)
+		}
+
+		for server.GetEventBus().Subscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		server.handleMessage(&MeshMessage{
+			MessageType:      MessageTypeAdapterData,
+			DataType:         AdapterTypePIR,
+			OriginMacAddress: []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF},
+			Data:             []byte{0x01},
+		})
+
+		reader := bufio.NewReader(resp.Body)
+		var frame []string
+		for len(frame) < 3 {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Expected event, got %v", err)
+			}
+			if strings.HasPrefix(line, "id:") || len(frame) > 0 {
+				frame = append(frame, strings.TrimSpace(line))
+			}
+		}
+
+		if frame[1] != "event: "+events.TypeMotion || !strings.Contains(frame[2], `"mac":"aa:bb:cc:dd:ee:ff"`) {
+			t.Errorf("Unexpected SSE frame: %q", frame)
+		}
+	})
+
+	t.Run("WebSocket", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		api := httptest.NewServer(NewAPIServer(server))
+		defer api.Close()
+
+		conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(api.URL, "http")+"/events/ws?type=node", nil)
+		if err != nil {
+			t.Fatalf("Expected upgrade, got %v", err)
+		}
+		defer conn.Close()
+
+		for server.GetEventBus().Subscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		server.publishLifecycle([]byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, events.NodeOnline, true, time.Now())
+
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		var envelope events.Envelope
+		if err := conn.ReadJSON(&envelope); err != nil {
+			t.Fatalf("Expected event, got %v", err)
+		}
+		if envelope.Type != events.TypeNodeLifecycle || envelope.Subject != "aa:bb:cc:dd:ee:ff" {
+			t.Errorf("Unexpected envelope: %+v", envelope)
+		}
+	})
+}
+
+func TestAPIAuthorization(t *testing.T) {
+	keys, _ := auth.NewKeyStore("")
+	keys.AddStatic("viewer", auth.RoleViewer, "viewer-key")
+	keys.AddStatic("admin", auth.RoleAdmin, "admin-key")
+	api := NewAPIServerWithAuth(NewMeshServer(MeshServerConfig{}), auth.NewAuthenticator(keys, nil))
+
+	request := func(method, path, key, body string) (*httptest.ResponseRecorder, APIResponse) {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		if key != "" {
+			r.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+
+		var response APIResponse
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w, response
+	}
+
+	testCases := []struct {
+		method string
+		path   string
+		key    string
+		status int
+	}{
+		{"GET", "/status", "", http.StatusOK},
+		{"GET", "/nodes", "", http.StatusUnauthorized},
+		{"GET", "/nodes", "wrong-key", http.StatusUnauthorized},
+		{"GET", "/nodes", "viewer-key", http.StatusOK},
+		{"POST", "/server/stop", "viewer-key", http.StatusForbidden},
+		{"POST", "/nodes/configure-all", "viewer-key", http.StatusForbidden},
+		{"GET", "/admin/keys", "admin-key", http.StatusOK},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.method+tc.path+"/"+tc.key, func(t *testing.T) {
+			w, response := request(tc.method, tc.path, tc.key, "")
+			if w.Code != tc.status {
+				t.Fatalf("Expected status %d, got %d: %s", tc.status, w.Code, w.Body.String())
+			}
+			if tc.status >= 400 && (response.Success || response.Error == "") {
+				t.Errorf("Expected APIResponse error body, got %s", w.Body.String())
+			}
+		})
+	}
+
+	t.Run("CreatedKeyGrantsRole", func(t *testing.T) {
+		w, _ := request("POST", "/admin/keys", "admin-key", `{"name":"ops","role":"operator"}`)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status 201, got %d: %s", w.Code, w.Body.String())
+		}
+
+		var created struct {
+			Data CreateKeyResponse `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &created)
+
+		if w, _ := request("POST", "/health/request", created.Data.Key, ""); w.Code == http.StatusUnauthorized || w.Code == http.StatusForbidden {
+			t.Errorf("Expected operator key to be authorized, got %d", w.Code)
+		}
+		if w, _ := request("DELETE", "/admin/keys/"+created.Data.ID, "admin-key", ""); w.Code != http.StatusOK {
+			t.Errorf("Expected key to be revoked, got %d", w.Code)
+		}
+		if w, _ := request("GET", "/nodes", created.Data.Key, ""); w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected revoked key to be rejected, got %d", w.Code)
+		}
+	})
+}
+
+func TestAuditLog(t *testing.T) {
+	auditLog, err := OpenAuditLog(filepath.Join(t.TempDir(), "audit.jsonl"))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	defer auditLog.Close()
+
+	store := EventStore.NewMemory(100)
+	keys, _ := auth.NewKeyStore("")
+	keys.AddStatic("viewer", auth.RoleViewer, "viewer-key")
+	keys.AddStatic("admin", auth.RoleAdmin, "admin-key")
+	api := NewAPIServerWithAuth(
+		NewMeshServer(MeshServerConfig{EventStore: store, AuditLog: auditLog}),
+		auth.NewAuthenticator(keys, nil),
+	)
+
+	request := func(method, path, key, body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(method, path, strings.NewReader(body))
+		r.RemoteAddr = "192.168.1.10:50000"
+		r.Header.Set("X-API-Key", key)
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, r)
+		return w
+	}
+
+	request("POST", "/nodes/configure-all", "viewer-key", `{"adapterType":0}`)
+	request("POST", "/nodes/aa:bb:cc:dd:ee:ff/configure", "admin-key", `{"adapterType":0}`) // Serial not running
+	request("GET", "/nodes", "admin-key", "")
+
+	entries, err := auditLog.Query(AuditFilter{})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("Expected 2 audited requests, got %d: %+v", len(entries), entries)
+	}
+
+	configure, denied := entries[0], entries[1]
+	if denied.Action != "configureAllNodes" || denied.Outcome != events.AuditDenied || denied.Actor != "static-viewer" {
+		t.Errorf("Unexpected denied entry: %+v", denied)
+	}
+	if configure.Action != "configureNode" || configure.Outcome != events.AuditFailure || configure.Error == "" {
+		t.Errorf("Unexpected failed entry: %+v", configure)
+	}
+	if configure.Target != "aa:bb:cc:dd:ee:ff" || configure.SourceIP != "192.168.1.10" || configure.Params["adapterType"] != float64(0) {
+		t.Errorf("Expected caller and parameters to be recorded, got %+v", configure)
+	}
+
+	t.Run("Filter", func(t *testing.T) {
+		w := request("GET", "/audit?outcome=denied&actor=viewer", "admin-key", "")
+		var response struct {
+			Data []events.AuditEvent `json:"data"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		if w.Code != http.StatusOK || len(response.Data) != 1 || response.Data[0].Action != "configureAllNodes" {
+			t.Errorf("Unexpected filtered audit: %d %s", w.Code, w.Body.String())
+		}
+
+		if w := request("GET", "/audit", "viewer-key", ""); w.Code != http.StatusForbidden {
+			t.Errorf("Expected audit log to require admin, got %d", w.Code)
+		}
+	})
+
+	t.Run("Published", func(t *testing.T) {
+		if published := store.(EventStore.Queryable).Query(events.TopicAudit, 0, 0); len(published) != 2 {
+			t.Errorf("Expected 2 events on the audit topic, got %d", len(published))
+		}
+	})
+}
+
+func TestMetrics(t *testing.T) {
+	t.Run("NodeCollector", func(t *testing.T) {
+		registry := NewNodeRegistry()
+		registry.UpdateNode([]byte{0x11, 0x22, 0x33, 0x44, 0x55, 0x66}, AdapterTypePIR, 10, 1)
+		registry.UpdateNodeAt([]byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, AdapterTypePIR, 10, 1, time.Now().Add(-time.Minute))
+		registry.MarkOffline(30 * time.Second)
+
+		collector := NewNodeCollector(registry)
+		expected := `
+# HELP mesh_nodes_online Mesh nodes currently online.
+# TYPE mesh_nodes_online gauge
+mesh_nodes_online 1
+# HELP mesh_nodes_total Known mesh nodes.
+# TYPE mesh_nodes_total gauge
+mesh_nodes_total 2
+`
+		if err := testutil.CollectAndCompare(collector, strings.NewReader(expected), "mesh_nodes_total", "mesh_nodes_online"); err != nil {
+			t.Error(err)
+		}
+		if count := testutil.CollectAndCount(collector, "mesh_node_last_seen_age_seconds"); count != 2 {
+			t.Errorf("Expected a last-seen age per node, got %d", count)
+		}
+	})
+
+	t.Run("FrameErrors", func(t *testing.T) {
+		before := testutil.ToFloat64(frameErrors.WithLabelValues(frameErrorZeroLength))
+		port := NewMockSerialPort()
+		port.AddReadData([]byte{0x00, 0x00})
+		if _, err := NewSerialComm(port).ReadFrame(); err == nil {
+			t.Fatal("Expected zero-length frame error")
+		}
+		if after := testutil.ToFloat64(frameErrors.WithLabelValues(frameErrorZeroLength)); after != before+1 {
+			t.Errorf("Expected zero_length errors to increase by 1, got %v -> %v", before, after)
+		}
+	})
+
+	t.Run("Endpoint", func(t *testing.T) {
+		api := NewAPIServer(NewMeshServer(MeshServerConfig{}))
+		api.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/status", nil))
+
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+		if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `mesh_http_request_duration_seconds_count{code="200",method="GET",route="getStatus"}`) {
+			t.Errorf("Expected request latency for getStatus, got %d:\n%s", w.Code, w.Body.String())
+		}
+	})
+}
+
+func TestOpenAPI(t *testing.T) {
+	api := NewAPIServer(NewMeshServer(MeshServerConfig{}))
+
+	var spec struct {
+		OpenAPI string `json:"openapi"`
+		Paths   map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+		} `json:"paths"`
+	}
+	if err := json.Unmarshal(openAPISpec, &spec); err != nil {
+		t.Fatalf("Expected valid openapi.json, got %v", err)
+	}
+	if !strings.HasPrefix(spec.OpenAPI, "3.") {
+		t.Errorf("Expected an OpenAPI 3 document, got version %q", spec.OpenAPI)
+	}
+
+	t.Run("EveryRouteDocumented", func(t *testing.T) {
+		documented := make(map[string]bool)
+		api.v1.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+			template, _ := route.GetPathTemplate()
+			path := strings.TrimPrefix(template, apiPrefix)
+			methods, _ := route.GetMethods()
+			for _, method := range methods {
+				key := method + " " + path
+				documented[key] = true
+
+				operation, ok := spec.Paths[path][strings.ToLower(method)]
+				if !ok {
+					t.Errorf("Route %s (%s) has no entry in openapi.json", key, route.GetName())
+					continue
+				}
+				if operation.OperationID != route.GetName() {
+					t.Errorf("Expected operationId %q for %s, got %q", route.GetName(), key, operation.OperationID)
+				}
+			}
+			return nil
+		})
+
+		for path, operations := range spec.Paths {
+			for method := range operations {
+				if key := strings.ToUpper(method) + " " + path; !documented[key] {
+					t.Errorf("openapi.json documents %s, which has no route", key)
+				}
+			}
+		}
+	})
+
+	t.Run("ServedPublicly", func(t *testing.T) {
+		for _, path := range []string{"/api/v1/openapi.json", "/openapi.json"} {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+			if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), openAPISpec) {
+				t.Errorf("Expected spec at %s, got status %d", path, w.Code)
+			}
+		}
+	})
+
+	t.Run("LegacyAliases", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/status", nil))
+		if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "" {
+			t.Errorf("Expected versioned route without deprecation, got %d %v", w.Code, w.Header())
+		}
+
+		w = httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/status", nil))
+		if w.Code != http.StatusOK || w.Header().Get("Deprecation") != "true" {
+			t.Errorf("Expected deprecated legacy route, got %d %v", w.Code, w.Header())
+		}
+		if link := w.Header().Get("Link"); link != `</api/v1/status>; rel="successor-version"` {
+			t.Errorf("Unexpected Link header %q", link)
+		}
+	})
+}
+
+func TestGRPC(t *testing.T) {
+	keys, _ := auth.NewKeyStore("")
+	keys.AddStatic("viewer", auth.RoleViewer, "viewer-key")
+	server := NewMeshServer(MeshServerConfig{})
+	server.GetNodeRegistry().UpdateNode([]byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, AdapterTypePIR, 1000, 1)
+
+	listener := bufconn.Listen(1 << 20)
+	grpcServer := NewGRPCServer(server, auth.NewAuthenticator(keys, nil))
+	go grpcServer.Serve(listener)
+	defer grpcServer.Shutdown(context.Background())
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return listener.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("Expected client, got %v", err)
+	}
+	defer conn.Close()
+	client := NewMeshControlClient(conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	viewer := metadata.AppendToOutgoingContext(ctx, "x-api-key", "viewer-key")
+
+	t.Run("EveryMethodHasRole", func(t *testing.T) {
+		var methods []string
+		for _, method := range MeshControl_ServiceDesc.Methods {
+			methods = append(methods, method.MethodName)
+		}
+		for _, stream := range MeshControl_ServiceDesc.Streams {
+			methods = append(methods, stream.StreamName)
+		}
+		for _, method := range methods {
+			if _, ok := grpcRoles["/"+MeshControl_ServiceDesc.ServiceName+"/"+method]; !ok {
+				t.Errorf("Method %s has no entry in grpcRoles", method)
+			}
+		}
+	})
+
+	t.Run("Authorization", func(t *testing.T) {
+		if _, err := client.ListNodes(ctx, &ListNodesRequest{}); status.Code(err) != codes.Unauthenticated {
+			t.Errorf("Expected Unauthenticated without credentials, got %v", err)
+		}
+
+		response, err := client.ListNodes(viewer, &ListNodesRequest{})
+		if err != nil || len(response.Nodes) != 1 || response.Nodes[0].Mac != "aa:bb:cc:dd:ee:ff" {
+			t.Fatalf("Expected one node, got %v (%v)", response, err)
+		}
+
+		_, err = client.ConfigureNode(viewer, &ConfigureNodeRequest{Mac: "aa:bb:cc:dd:ee:ff", AdapterType: AdapterTypeLED})
+		if status.Code(err) != codes.PermissionDenied {
+			t.Errorf("Expected PermissionDenied for viewer, got %v", err)
+		}
+
+		entries, _ := server.GetAuditLog().Query(AuditFilter{})
+		if len(entries) != 1 {
+			t.Fatalf("Expected only the configure call to be audited, got %d entries", len(entries))
+		}
+		entry := entries[0]
+		if entry.Interface != "grpc" || entry.Action != "configureNode" || entry.Outcome != events.AuditDenied ||
+			entry.ActorName != "viewer" || entry.Target != "aa:bb:cc:dd:ee:ff" {
+			t.Errorf("Unexpected audit entry: %+v", entry)
+		}
+	})
+
+	t.Run("GetNodeNotFound", func(t *testing.T) {
+		if _, err := client.GetNode(viewer, &GetNodeRequest{Mac: "11:22:33:44:55:66"}); status.Code(err) != codes.NotFound {
+			t.Errorf("Expected NotFound, got %v", err)
+		}
+		if _, err := client.GetNode(viewer, &GetNodeRequest{Mac: "bogus"}); status.Code(err) != codes.InvalidArgument {
+			t.Errorf("Expected InvalidArgument, got %v", err)
+		}
+	})
+
+	t.Run("WatchEvents", func(t *testing.T) {
+		stream, err := client.WatchEvents(viewer, &WatchEventsRequest{Types: []string{"node"}})
+		if err != nil {
+			t.Fatalf("Expected stream, got %v", err)
+		}
+
+		for server.GetEventBus().Subscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+		server.publishLifecycle([]byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, events.NodeOffline, false, time.Now())
+
+		envelope, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Expected event, got %v", err)
+		}
+		lifecycle := envelope.GetNodeLifecycle()
+		if envelope.Type != events.TypeNodeLifecycle || lifecycle == nil || lifecycle.State != events.NodeOffline {
+			t.Errorf("Unexpected envelope: %v", envelope)
+		}
+	})
+
+	t.Run("ShutdownEndsStreams", func(t *testing.T) {
+		stream, err := client.WatchEvents(viewer, &WatchEventsRequest{})
+		if err != nil {
+			t.Fatalf("Expected stream, got %v", err)
+		}
+		for server.GetEventBus().Subscribers() == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		shutdownCtx, cancelShutdown := context.WithTimeout(context.Background(), time.Second)
+		defer cancelShutdown()
+		if err := grpcServer.Shutdown(shutdownCtx); err != nil {
+			t.Errorf("Expected graceful shutdown, got %v", err)
+		}
+		if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+			t.Errorf("Expected Unavailable after shutdown, got %v", err)
+		}
+	})
+}
+
+func TestReadiness(t *testing.T) {
+	store := EventStore.NewFile(filepath.Join(t.TempDir(), "events"), 0, 0)
+	server := NewMeshServer(MeshServerConfig{EventStore: store, FrameWindow: time.Minute})
+	keys, _ := auth.NewKeyStore("")
+	api := NewAPIServerWithAuth(server, auth.NewAuthenticator(keys, nil))
+
+	readyz := func() (int, Readiness, string) {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/readyz", nil))
+
+		var response struct {
+			Data  Readiness `json:"data"`
+			Error string    `json:"error"`
+		}
+		json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response.Data, response.Error
+	}
+
+	t.Run("Liveness", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/healthz", nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("Expected 200 from /healthz, got %d", w.Code)
+		}
+	})
+
+	t.Run("NotReady", func(t *testing.T) {
+		code, readiness, errMsg := readyz()
+		if code != http.StatusServiceUnavailable || readiness.Ready {
+			t.Fatalf("Expected 503 before the serial port is open, got %d", code)
+		}
+		if errMsg != "Not ready: eventStore, serial" {
+			t.Errorf("Unexpected error %q", errMsg)
+		}
+		if !readiness.Checks[checkWriterQueue].Ready {
+			t.Errorf("Expected idle writer queue to be ready: %+v", readiness.Checks[checkWriterQueue])
+		}
+	})
+
+	// Pretend the serial port has been open for a while
+	store.Connect()
+	server.mu.Lock()
+	server.running = true
+	server.startedAt = time.Now().Add(-5 * time.Minute)
+	server.mu.Unlock()
+
+	t.Run("NoRecentFrames", func(t *testing.T) {
+		_, readiness, _ := readyz()
+		if check := readiness.Checks[checkSerial]; check.Ready || !strings.HasPrefix(check.Message, "no frames received") {
+			t.Errorf("Expected idle serial port to be not ready, got %+v", check)
+		}
+	})
+
+	t.Run("Ready", func(t *testing.T) {
+		server.lastFrame.Store(time.Now().UnixNano())
+		code, readiness, _ := readyz()
+		if code != http.StatusOK || !readiness.Ready {
+			t.Errorf("Expected ready, got %d %+v", code, readiness)
+		}
+	})
+
+	t.Run("WriterQueueSaturated", func(t *testing.T) {
+		var writes []uint64
+		for i := 0; i < maxPendingWrites; i++ {
+			writes = append(writes, server.writes.begin())
+		}
+		defer func() {
+			for _, write := range writes {
+				server.writes.end(write)
+			}
+		}()
+
+		code, readiness, _ := readyz()
+		if code != http.StatusServiceUnavailable || readiness.Checks[checkWriterQueue].Ready {
+			t.Errorf("Expected saturated writer queue to be not ready, got %d %+v", code, readiness.Checks[checkWriterQueue])
+		}
+	})
+}
+
+func TestHTTPServer(t *testing.T) {
+	if _, err := NewHTTPServer(NewMeshServer(MeshServerConfig{}), nil, HTTPConfig{TLSCertFile: "cert.pem"}); err == nil {
+		t.Error("Expected error for a TLS certificate without a key")
+	}
+
+	server, err := NewHTTPServer(NewMeshServer(MeshServerConfig{}), nil, HTTPConfig{
+		AllowedOrigins: []string{"http://localhost:3000"},
+		ReadTimeout:    time.Second,
+		WriteTimeout:   time.Second,
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	t.Run("CORS", func(t *testing.T) {
+		preflight := func(origin string) *httptest.ResponseRecorder {
+			r := httptest.NewRequest("OPTIONS", "/api/v1/nodes/configure-all", nil)
+			r.Header.Set("Origin", origin)
+			r.Header.Set("Access-Control-Request-Method", "POST")
+			w := httptest.NewRecorder()
+			server.api.ServeHTTP(w, r)
+			return w
+		}
+
+		w := preflight("http://localhost:3000")
+		if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" ||
+			!strings.Contains(w.Header().Get("Access-Control-Allow-Headers"), "X-API-Key") {
+			t.Errorf("Expected preflight to be allowed, got %d %v", w.Code, w.Header())
+		}
+		if w := preflight("http://evil.example"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+			t.Errorf("Expected other origins to be refused, got %v", w.Header())
+		}
+
+		r := httptest.NewRequest("GET", "/api/v1/status", nil)
+		r.Header.Set("Origin", "http://localhost:3000")
+		w = httptest.NewRecorder()
+		server.api.ServeHTTP(w, r)
+		if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "http://localhost:3000" {
+			t.Errorf("Expected CORS headers on a simple request, got %d %v", w.Code, w.Header())
+		}
+	})
+
+	t.Run("WebSocketOrigin", func(t *testing.T) {
+		api := httptest.NewServer(server.api)
+		defer api.Close()
+		wsURL := "ws" + strings.TrimPrefix(api.URL, "http") + "/api/v1/events/ws"
+
+		_, resp, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"http://evil.example"}})
+		if err == nil || resp == nil || resp.StatusCode != http.StatusForbidden {
+			t.Errorf("Expected upgrade from other origin to be refused, got %v", err)
+		}
+
+		conn, _, err := websocket.DefaultDialer.Dial(wsURL, http.Header{"Origin": {"http://localhost:3000"}})
+		if err != nil {
+			t.Fatalf("Expected upgrade from allowed origin, got %v", err)
+		}
+		conn.Close()
+	})
+
+	t.Run("ShutdownEndsStreams", func(t *testing.T) {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			t.Fatalf("Expected listener, got %v", err)
+		}
+		go server.server.Serve(listener)
+
+		resp, err := http.Get("http://" + listener.Addr().String() + "/api/v1/events/stream")
+		if err != nil {
+			t.Fatalf("Expected stream, got %v", err)
+		}
+		defer resp.Body.Close()
+
+		// Outlive the read and write timeouts, then check events still arrive
+		time.Sleep(1500 * time.Millisecond)
+		server.api.meshServer.publishLifecycle([]byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}, events.NodeOnline, true, time.Now())
+
+		reader := bufio.NewReader(resp.Body)
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				t.Fatalf("Expected stream to stay open past the server timeouts, got %v", err)
+			}
+			if strings.HasPrefix(line, "event: "+events.TypeNodeLifecycle) {
+				break
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			t.Errorf("Expected streams to end on shutdown, got %v", err)
+		}
+	})
+}
+
+func TestOTA(t *testing.T) {
+	nodeA := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x01}
+	nodeB := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x02}
+	nodeC := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x03}
+	nodeD := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x04}
+
+	server := NewMeshServer(MeshServerConfig{OTA: OTAConfig{
+		ChunkTimeout:  20 * time.Millisecond,
+		ReadyTimeout:  20 * time.Millisecond,
+		CommitTimeout: 20 * time.Millisecond,
+		MaxRetries:    2,
+	}})
+	port := &otaPort{server: server, nodes: map[string]*otaNode{
+		"aa:00:00:00:00:01": {dropOnce: map[uint32]bool{14: true}},
+		"aa:00:00:00:00:02": {maxPayload: 64},
+		"aa:00:00:00:00:03": {silent: true},
+		"aa:00:00:00:00:04": {},
+	}}
+	server.serialComm = NewSerialComm(port)
+	server.running = true
+	for _, mac := range [][]byte{nodeA, nodeB, nodeC, nodeD} {
+		server.GetNodeRegistry().UpdateNode(mac, AdapterTypePIR, 100, 1)
+	}
+	server.GetNodeRegistry().SetMetadata(nodeC, "", "attic")
+	server.GetNodeRegistry().SetMetadata(nodeD, "", "attic")
+
+	image := make([]byte, 150)
+	for i := range image {
+		image[i] = byte(i * 7)
+	}
+
+	ota := server.GetOTA()
+	t.Cleanup(ota.Close)
+
+	wait := func(t *testing.T, id string) OTARollout {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for time.Now().Before(deadline) {
+			rollout, err := ota.Rollout(id)
+			if err != nil {
+				t.Fatalf("Expected the rollout, got %v", err)
+			}
+			if rollout.Status != RolloutRunning {
+				return rollout
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+		t.Fatalf("Rollout %s did not finish", id)
+		return OTARollout{}
+	}
+
+	t.Run("ChunkFrames", func(t *testing.T) {
+		msg, err := buildOTAChunk(nodeA, 0x010203, []byte{1, 2, 3})
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(msg.Data) != MaxDataLength || msg.Data[0] != OpOTAChunk || uint24(msg.Data[1:4]) != 0x010203 || msg.Data[4] != crc8([]byte{1, 2, 3, 0, 0, 0, 0}) {
+			t.Errorf("Expected a standard chunk frame, got %x", msg.Data)
+		}
+
+		long := bytes.Repeat([]byte{0x5A}, 40)
+		msg, err = buildOTAChunk(nodeA, 70, long)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		chunk, err := OTAChunkOpcode.Parse(msg)
+		if err != nil || len(msg.Data) != otaChunkHeader+40 || !bytes.Equal(chunk.Data, long) {
+			t.Errorf("Expected a negotiated chunk to carry all 40 bytes, got %x (%v)", msg.Data, err)
+		}
+
+		msg.Data[otaChunkHeader] ^= 0xFF
+		if _, err := OTAChunkOpcode.Parse(msg); err == nil {
+			t.Error("Expected a corrupted chunk to fail its CRC")
+		}
+	})
+
+	fw, err := ota.AddFirmware(image, "1.2.0")
+	if err != nil {
+		t.Fatalf("Failed to add firmware: %v", err)
+	}
+	if again, _ := ota.AddFirmware(image, "1.2.0"); again.ID != fw.ID || len(ota.Firmware()) != 1 {
+		t.Errorf("Expected uploading the same image to return the stored one")
+	}
+
+	t.Run("StagedRollout", func(t *testing.T) {
+		rollout, err := ota.StartRollout(fw.ID, []OTAStage{
+			{Name: "canary", MACs: []string{"aa:00:00:00:00:01", "aa:00:00:00:00:02"}},
+			{Name: "attic", Zones: []string{"attic"}},
+		}, 0)
+		if err != nil {
+			t.Fatalf("Failed to start rollout: %v", err)
+		}
+		if _, err := ota.StartRollout(fw.ID, []OTAStage{{MACs: []string{"aa:00:00:00:00:01"}}}, 0); err != errRolloutRunning {
+			t.Errorf("Expected a second rollout to be refused, got %v", err)
+		}
+
+		rollout = wait(t, rollout.ID)
+		if rollout.Status != RolloutHalted || rollout.CurrentStage != 1 {
+			t.Fatalf("Expected the rollout to halt in the second stage, got %+v", rollout)
+		}
+
+		statuses := make(map[string]*OTANodeProgress)
+		for _, node := range rollout.Nodes {
+			statuses[node.MAC] = node
+		}
+		a, b := statuses["aa:00:00:00:00:01"], statuses["aa:00:00:00:00:02"]
+		if a.Status != OTANodeDone || a.BytesAcked != len(image) || a.ChunkSize != otaChunkData || a.Retransmits == 0 {
+			t.Errorf("Expected the first node updated after retransmitting its lost chunk, got %+v", a)
+		}
+		if b.Status != OTANodeDone || b.ChunkSize != 64-otaChunkHeader {
+			t.Errorf("Expected the second node updated with negotiated chunks, got %+v", b)
+		}
+		if statuses["aa:00:00:00:00:03"].Status != OTANodeFailed || statuses["aa:00:00:00:00:04"].Status != OTANodeSkipped {
+			t.Errorf("Expected the silent node to fail and the rest of its stage skipped, got %+v and %+v",
+				statuses["aa:00:00:00:00:03"], statuses["aa:00:00:00:00:04"])
+		}
+
+		port.mu.Lock()
+		defer port.mu.Unlock()
+		for _, mac := range []string{"aa:00:00:00:00:01", "aa:00:00:00:00:02"} {
+			if node := port.nodes[mac]; !node.committed || !bytes.Equal(node.image, image) {
+				t.Errorf("Expected %s to hold the whole image", mac)
+			}
+		}
+		if port.nodes["aa:00:00:00:00:04"].image != nil {
+			t.Error("Expected the skipped node to receive nothing")
+		}
+	})
+
+	t.Run("Validation", func(t *testing.T) {
+		testCases := []struct {
+			firmware string
+			stages   []OTAStage
+		}{
+			{"missing", []OTAStage{{MACs: []string{"aa:00:00:00:00:01"}}}},
+			{fw.ID, nil},
+			{fw.ID, []OTAStage{{MACs: []string{"11:22:33:44:55:66"}}}},
+			{fw.ID, []OTAStage{{Zones: []string{"garage"}}}},
+		}
+		for _, tc := range testCases {
+			if _, err := ota.StartRollout(tc.firmware, tc.stages, 0); err == nil {
+				t.Errorf("Expected rollout of %s to %+v to be refused", tc.firmware, tc.stages)
+			}
+		}
+	})
+
+	t.Run("API", func(t *testing.T) {
+		api := NewAPIServer(server)
+
+		req := httptest.NewRequest("POST", "/api/v1/ota/firmware?version=2.0.0", bytes.NewReader(image[:100]))
+		req.Header.Set("Content-Type", "application/octet-stream")
+		w := httptest.NewRecorder()
+		api.ServeHTTP(w, req)
+		if w.Code != http.StatusCreated {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusCreated, w.Code, w.Body.String())
+		}
+		var uploaded struct {
+			Data Firmware `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &uploaded); err != nil || uploaded.Data.Version != "2.0.0" || uploaded.Data.Size != 100 {
+			t.Fatalf("Expected the stored firmware, got %s", w.Body.String())
+		}
+
+		body := `{"firmwareId":"` + uploaded.Data.ID + `","stages":[{"macs":["aa:00:00:00:00:04"]}]}`
+		w = httptest.NewRecorder()
+		api.ServeHTTP(w, httptest.NewRequest("POST", "/api/v1/ota/rollouts", strings.NewReader(body)))
+		if w.Code != http.StatusAccepted {
+			t.Fatalf("Expected status %d, got %d: %s", http.StatusAccepted, w.Code, w.Body.String())
+		}
+		var created struct {
+			Data OTARollout `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil {
+			t.Fatalf("Failed to decode rollout: %v", err)
+		}
+		if rollout := wait(t, created.Data.ID); rollout.Status != RolloutCompleted {
+			t.Errorf("Expected the rollout to complete, got %+v", rollout)
+		}
+
+		testCases := []struct {
+			method string
+			path   string
+			body   string
+			status int
+		}{
+			{"GET", "/ota/firmware", "", http.StatusOK},
+			{"GET", "/ota/rollouts", "", http.StatusOK},
+			{"GET", "/ota/rollouts/" + created.Data.ID, "", http.StatusOK},
+			{"GET", "/ota/rollouts/missing", "", http.StatusNotFound},
+			{"POST", "/ota/rollouts/" + created.Data.ID + "/abort", "", http.StatusConflict},
+			{"POST", "/ota/rollouts", `{"firmwareId":"missing","stages":[{"macs":["aa:00:00:00:00:04"]}]}`, http.StatusNotFound},
+			{"POST", "/ota/rollouts", `{"firmwareId":"` + fw.ID + `","stages":[]}`, http.StatusBadRequest},
+		}
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(tc.method, "/api/v1"+tc.path, strings.NewReader(tc.body)))
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d for %s %s, got %d: %s", tc.status, tc.method, tc.path, w.Code, w.Body.String())
+			}
+		}
+
+		audit, err := server.GetAuditLog().Query(AuditFilter{Action: "uploadFirmware"})
+		if err != nil || len(audit) != 1 {
+			t.Fatalf("Expected the upload to be audited, got %v (%v)", audit, err)
+		}
+		if _, recorded := audit[0].Params["body"]; recorded || audit[0].Params["version"] != "2.0.0" {
+			t.Errorf("Expected the upload audited without the image, got %v", audit[0].Params)
+		}
+	})
+}
+
+func TestTimeSync(t *testing.T) {
+	mac := []byte{0xAA, 0xBB, 0xCC, 0xDD, 0xEE, 0xFF}
+	detectedAt := time.UnixMilli(1704110400123)
+
+	t.Run("Payload", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		port := NewMockSerialPort()
+		server.serialComm = NewSerialComm(port)
+		server.running = true
+
+		if err := server.SyncTime(BroadcastMAC); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		written := port.GetWrittenData()
+		var msg MeshMessage
+		if err := proto.Unmarshal(written[2:], &msg); err != nil {
+			t.Fatalf("Failed to decode frame: %v", err)
+		}
+		if !TimeSyncOpcode.Matches(&msg) || !bytes.Equal(msg.TargetMacAddress, BroadcastMAC) {
+			t.Fatalf("Expected a broadcast time sync, got %+v", &msg)
+		}
+		synced, err := TimeSyncOpcode.Parse(&msg)
+		if err != nil || time.Since(synced) < 0 || time.Since(synced) > time.Second {
+			t.Errorf("Expected the server's time, got %v (%v)", synced, err)
+		}
+
+		encoded, _ := TimeSyncOpcode.Build(mac, detectedAt)
+		if millis := binary.LittleEndian.Uint64(encoded.Data[1:9]); millis != 1704110400123 {
+			t.Errorf("Expected Unix milliseconds in bytes 1-8, got %d", millis)
+		}
+	})
+
+	t.Run("Resync", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{TimeSyncInterval: time.Hour})
+		port := NewMockSerialPort()
+		server.serialComm = NewSerialComm(port)
+		server.running = true
+
+		report, _ := HealthReportOpcode.Build(nil, HealthReport{MAC: mac, AdapterType: AdapterTypePIR, Uptime: 1})
+		server.processMessage(report, time.Now(), false)
+		server.wg.Wait()
+		if !bytes.Contains(port.GetWrittenData(), []byte{OpTimeSync}) {
+			t.Error("Expected a node coming online to be sent the time")
+		}
+
+		server.wg.Add(1)
+		go server.timeSyncLoop()
+		if err := server.Stop(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		written := len(port.GetWrittenData())
+		server.resyncTime(mac)
+		server.wg.Wait()
+		if len(port.GetWrittenData()) != written {
+			t.Error("Expected no time sync after Stop")
+		}
+	})
+
+	t.Run("Motion", func(t *testing.T) {
+		server := NewMeshServer(MeshServerConfig{})
+		receivedAt := detectedAt.Add(350 * time.Millisecond)
+		pir := func(data []byte) *MeshMessage {
+			return &MeshMessage{DataType: AdapterTypePIR, OriginMacAddress: mac, Data: data}
+		}
+
+		stamped := make([]byte, MaxDataLength)
+		stamped[0] = pirTimestampMarker
+		binary.LittleEndian.PutUint64(stamped[1:9], uint64(detectedAt.UnixMilli()))
+		unsynced := make([]byte, MaxDataLength)
+		unsynced[0] = pirTimestampMarker
+
+		for _, data := range [][]byte{stamped, unsynced, {0x01}} {
+			if err := server.processMessage(pir(data), receivedAt, false); err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+		}
+		if err := server.processMessage(pir(stamped), receivedAt, true); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+
+		records := server.GetMotionHistory().Query(MotionFilter{})
+		if len(records) != 3 {
+			t.Fatalf("Expected 3 records with the replayed one ignored, got %d", len(records))
+		}
+		var timed int
+		for _, record := range records {
+			if record.DeviceTime == nil {
+				if record.OffsetMs != nil {
+					t.Errorf("Expected no offset without a device time, got %d", *record.OffsetMs)
+				}
+				continue
+			}
+			timed++
+			if !record.DeviceTime.Equal(detectedAt) || record.OffsetMs == nil || *record.OffsetMs != 350 {
+				t.Errorf("Expected device time %v with a 350ms offset, got %+v", detectedAt, record)
+			}
+		}
+		if timed != 1 {
+			t.Errorf("Expected only the synced payload to carry a device time, got %d", timed)
+		}
+	})
+}
+
+func TestMeshSecurity(t *testing.T) {
+	node := []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x01}
+	open := func(t *testing.T, path string) *MeshSecurity {
+		t.Helper()
+		security, err := OpenMeshSecurity(path)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		return security
+	}
+	// sign signs a frame as a node provisioned with the key would
+	sign := func(msg *MeshMessage, security *MeshSecurity, keyID byte, counter uint32) *MeshMessage {
+		auth := []byte{keyID, 0, 0, 0, 0}
+		binary.LittleEndian.PutUint32(auth[1:5], counter)
+		msg.Auth = append(auth, authTag(security.secrets[keyID], keyID, counter, msg.OriginMacAddress, msg)...)
+		return msg
+	}
+	pir := func() *MeshMessage {
+		return &MeshMessage{DataType: AdapterTypePIR, OriginMacAddress: node, Data: []byte{0x01}}
+	}
+
+	t.Run("Counters", func(t *testing.T) {
+		var window counterWindow
+		for _, counter := range []uint32{1, 3, 2, 100, 40} {
+			if !window.accept(counter) {
+				t.Errorf("Expected counter %d to be accepted", counter)
+			}
+		}
+		for _, counter := range []uint32{0, 2, 3, 100, 36} {
+			if window.accept(counter) {
+				t.Errorf("Expected counter %d to be rejected", counter)
+			}
+		}
+	})
+
+	t.Run("Verify", func(t *testing.T) {
+		security := open(t, filepath.Join(t.TempDir(), "mesh-keys.json"))
+
+		if err := security.Verify(sign(pir(), security, 1, 1)); err != nil {
+			t.Fatalf("Expected a signed frame to verify, got %v", err)
+		}
+		tampered := sign(pir(), security, 1, 2)
+		tampered.Data[0] = 0x02
+		forged := sign(pir(), security, 1, 3)
+		forged.OriginMacAddress = []byte{0xAA, 0x00, 0x00, 0x00, 0x00, 0x02}
+		unknown := sign(pir(), security, 1, 4)
+		unknown.Auth[0] = 9
+
+		for name, msg := range map[string]*MeshMessage{
+			"unsigned": pir(),
+			"replayed": sign(pir(), security, 1, 1),
+			"tampered": tampered,
+			"forged":   forged,
+			"unknown":  unknown,
+		} {
+			if err := security.Verify(msg); err == nil {
+				t.Errorf("Expected the %s frame to be rejected", name)
+			}
+		}
+
+		// The server signs with zeros for its own MAC
+		command, _ := RebootOpcode.Build(node, NodeTarget{Target: node})
+		if err := security.Sign(command); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if len(command.Auth) != authLength || !bytes.Equal(command.Auth[5:], authTag(security.secrets[1], 1, 1, nil, command)) {
+			t.Errorf("Expected the command signed with key 1 and counter 1, got %x", command.Auth)
+		}
+	})
+
+	t.Run("Persistence", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mesh-keys.json")
+		security := open(t, path)
+		for i := 0; i < 3; i++ {
+			security.Sign(&MeshMessage{})
+		}
+		if err := security.Verify(sign(pir(), security, 1, 7)); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := security.Close(); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+			t.Errorf("Expected the key file to be private, got %v", info.Mode())
+		}
+
+		reopened := open(t, path)
+		if !bytes.Equal(reopened.secrets[1], security.secrets[1]) {
+			t.Fatal("Expected the key to be reloaded")
+		}
+		msg := &MeshMessage{}
+		reopened.Sign(msg)
+		if counter := binary.LittleEndian.Uint32(msg.Auth[1:5]); counter <= 3 {
+			t.Errorf("Expected counters used before the restart to be skipped, got %d", counter)
+		}
+		if err := reopened.Verify(sign(pir(), reopened, 1, 6)); err == nil {
+			t.Error("Expected a counter from before the restart to be rejected")
+		}
+		if err := reopened.Verify(sign(pir(), reopened, 1, 8)); err != nil {
+			t.Errorf("Expected a new counter to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("Crash", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "mesh-keys.json")
+		security := open(t, path)
+		for _, counter := range []uint32{1, 2, 70} {
+			if err := security.Verify(sign(pir(), security, 1, counter)); err != nil {
+				t.Fatalf("Expected counter %d to be accepted, got %v", counter, err)
+			}
+		}
+
+		// Reopened without Close, as after a crash
+		reopened := open(t, path)
+		for _, counter := range []uint32{70, 100, 133} {
+			if err := reopened.Verify(sign(pir(), reopened, 1, counter)); err == nil {
+				t.Errorf("Expected counter %d reserved before the crash to be rejected", counter)
+			}
+		}
+		if err := reopened.Verify(sign(pir(), reopened, 1, 134)); err != nil {
+			t.Errorf("Expected a counter past the reservation to be accepted, got %v", err)
+		}
+	})
+
+	t.Run("Rotation", func(t *testing.T) {
+		security := open(t, filepath.Join(t.TempDir(), "mesh-keys.json"))
+		key, err := security.AddKey()
+		if err != nil || key.ID != 2 || len(key.Secret) != 2*meshKeyLength {
+			t.Fatalf("Expected key 2 with its secret, got %+v (%v)", key, err)
+		}
+		if err := security.Verify(sign(pir(), security, 2, 1)); err != nil {
+			t.Errorf("Expected frames signed with a new key to be accepted, got %v", err)
+		}
+		if err := security.Activate(2); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := security.Retire(2); !errors.Is(err, ErrActiveMeshKey) {
+			t.Errorf("Expected the active key to stay, got %v", err)
+		}
+		if err := security.Retire(1); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := security.Verify(sign(pir(), security, 1, 2)); err == nil {
+			t.Error("Expected frames signed with a retired key to be rejected")
+		}
+		status := security.Status()
+		if status.ActiveKey != 2 || len(status.Keys) != 1 || status.Keys[0].Secret != "" {
+			t.Errorf("Unexpected status: %+v", status)
+		}
+	})
+
+	t.Run("Server", func(t *testing.T) {
+		security := open(t, filepath.Join(t.TempDir(), "mesh-keys.json"))
+		server := NewMeshServer(MeshServerConfig{Security: security})
+		port := NewMockSerialPort()
+		server.serialComm = NewSerialComm(port)
+		server.running = true
+
+		if err := server.handleMessage(pir()); err == nil {
+			t.Error("Expected an unsigned PIR frame to be rejected")
+		}
+		if err := server.handleMessage(sign(pir(), security, 1, 1)); err != nil {
+			t.Errorf("Expected a signed PIR frame to be accepted, got %v", err)
+		}
+		if records := server.GetMotionHistory().Query(MotionFilter{}); len(records) != 1 {
+			t.Errorf("Expected only the signed detection to be recorded, got %d", len(records))
+		}
+		beacon := &MeshMessage{MessageType: MessageTypeMasterBeacon, OriginMacAddress: node}
+		if err := server.handleMessage(beacon); err != nil {
+			t.Errorf("Expected unsigned beacons to pass, got %v", err)
+		}
+
+		fragments, _ := fragmentMessage(&MeshMessage{DataType: AdapterTypePIR, OriginMacAddress: node, Data: make([]byte, 20)}, 1)
+		var err error
+		for _, frag := range fragments {
+			err = server.processMessage(frag, time.Now(), false)
+		}
+		if err == nil {
+			t.Error("Expected a fragmented PIR message to be rejected")
+		}
+
+		if err := server.RequestNodeInfo(node); err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		written := port.GetWrittenData()
+		var msg MeshMessage
+		if err := proto.Unmarshal(written[2:], &msg); err != nil {
+			t.Fatalf("Failed to decode frame: %v", err)
+		}
+		if len(msg.Auth) != authLength {
+			t.Errorf("Expected the outgoing frame to be signed, got %x", msg.Auth)
+		}
+
+		api := NewAPIServer(server)
+		testCases := []struct {
+			method string
+			path   string
+			status int
+		}{
+			{"GET", "/security", http.StatusOK},
+			{"POST", "/security/keys", http.StatusCreated},
+			{"POST", "/security/keys/2/activate", http.StatusOK},
+			{"POST", "/security/keys/9/activate", http.StatusNotFound},
+			{"DELETE", "/security/keys/2", http.StatusConflict},
+			{"DELETE", "/security/keys/1", http.StatusOK},
+			{"DELETE", "/security/keys/abc", http.StatusBadRequest},
+		}
+		for _, tc := range testCases {
+			w := httptest.NewRecorder()
+			api.ServeHTTP(w, httptest.NewRequest(tc.method, "/api/v1"+tc.path, nil))
+			if w.Code != tc.status {
+				t.Errorf("Expected status %d for %s %s, got %d: %s", tc.status, tc.method, tc.path, w.Code, w.Body.String())
+			}
+		}
+
+		w := httptest.NewRecorder()
+		NewAPIServer(NewMeshServer(MeshServerConfig{})).ServeHTTP(w, httptest.NewRequest("GET", "/api/v1/security", nil))
+		if w.Code != http.StatusConflict {
+			t.Errorf("Expected status %d without security mode, got %d", http.StatusConflict, w.Code)
+		}
+	})
 }
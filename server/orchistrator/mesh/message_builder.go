@@ -1,7 +1,6 @@
 package mesh
 
 import (
-	"encoding/binary"
 	"fmt"
 )
 
@@ -18,19 +17,7 @@ func (mb *MessageBuilder) BuildConfigSetMessage(targetMAC []byte, adapterType in
 	if len(targetMAC) != MACAddressLength {
 		return nil, fmt.Errorf("invalid MAC address length: %d, expected %d", len(targetMAC), MACAddressLength)
 	}
-
-	payload := make([]byte, MaxDataLength)
-	payload[0] = OpConfigSet
-	copy(payload[1:7], targetMAC)
-	payload[7] = byte(adapterType)
-	// Bytes 8-11 are reserved (already zero from make)
-
-	return &MeshMessage{
-		MessageType:      MessageTypeAdapterData,
-		DataType:         AdapterTypeSerial,
-		TargetMacAddress: targetMAC,
-		Data:             payload,
-	}, nil
+	return ConfigSetOpcode.Build(targetMAC, ConfigSet{Target: targetMAC, AdapterType: adapterType})
 }
 
 // BuildConfigSetBroadcastMessage creates a broadcast message to set adapter type on all nodes
@@ -38,28 +25,38 @@ func (mb *MessageBuilder) BuildConfigSetBroadcastMessage(adapterType int32) (*Me
 	return mb.BuildConfigSetMessage(BroadcastMAC, adapterType)
 }
 
-// BuildHealthRequestMessage creates a message to request health reports
-func (mb *MessageBuilder) BuildHealthRequestMessage() *MeshMessage {
-	payload := make([]byte, MaxDataLength)
-	payload[0] = OpHealthReq
-	// Remaining bytes are zero
+// BuildRebootMessage creates a message to reboot a node, or every node when
+// targetMAC is BroadcastMAC
+func (mb *MessageBuilder) BuildRebootMessage(targetMAC []byte) (*MeshMessage, error) {
+	if len(targetMAC) != MACAddressLength {
+		return nil, fmt.Errorf("invalid MAC address length: %d, expected %d", len(targetMAC), MACAddressLength)
+	}
+	return RebootOpcode.Build(targetMAC, NodeTarget{Target: targetMAC})
+}
 
-	return &MeshMessage{
-		MessageType: MessageTypeAdapterData,
-		DataType:    AdapterTypeSerial,
-		Data:        payload,
+// BuildFactoryResetMessage creates a message that erases a node's stored
+// configuration and reboots it, or every node when targetMAC is BroadcastMAC
+func (mb *MessageBuilder) BuildFactoryResetMessage(targetMAC []byte) (*MeshMessage, error) {
+	if len(targetMAC) != MACAddressLength {
+		return nil, fmt.Errorf("invalid MAC address length: %d, expected %d", len(targetMAC), MACAddressLength)
 	}
+	return FactoryResetOpcode.Build(targetMAC, NodeTarget{Target: targetMAC})
 }
 
-// BuildBroadcastMessage creates a broadcast message with custom data
+// BuildHealthRequestMessage creates a message to request health reports
+func (mb *MessageBuilder) BuildHealthRequestMessage() *MeshMessage {
+	msg, _ := HealthRequestOpcode.Build(nil, struct{}{}) // Has no fields, so cannot fail
+	return msg
+}
+
+// BuildBroadcastMessage creates a broadcast message with custom data. Data
+// longer than MaxDataLength is sent in fragments.
 func (mb *MessageBuilder) BuildBroadcastMessage(dataType int32, data []byte) (*MeshMessage, error) {
-	if len(data) > MaxDataLength {
-		return nil, fmt.Errorf("data length %d exceeds maximum %d", len(data), MaxDataLength)
+	payload, err := adapterPayload(data)
+	if err != nil {
+		return nil, err
 	}
 
-	payload := make([]byte, MaxDataLength)
-	copy(payload, data)
-
 	return &MeshMessage{
 		MessageType: MessageTypeSerialCmdBroadcast,
 		DataType:    dataType,
@@ -67,19 +64,18 @@ func (mb *MessageBuilder) BuildBroadcastMessage(dataType int32, data []byte) (*M
 	}, nil
 }
 
-// BuildAdapterDataMessage creates a targeted adapter data message
+// BuildAdapterDataMessage creates a targeted adapter data message. Data
+// longer than MaxDataLength is sent in fragments.
 func (mb *MessageBuilder) BuildAdapterDataMessage(targetMAC []byte, dataType int32, data []byte) (*MeshMessage, error) {
 	if len(targetMAC) != MACAddressLength {
 		return nil, fmt.Errorf("invalid MAC address length: %d, expected %d", len(targetMAC), MACAddressLength)
 	}
 	
-	if len(data) > MaxDataLength {
-		return nil, fmt.Errorf("data length %d exceeds maximum %d", len(data), MaxDataLength)
+	payload, err := adapterPayload(data)
+	if err != nil {
+		return nil, err
 	}
 
-	payload := make([]byte, MaxDataLength)
-	copy(payload, data)
-
 	return &MeshMessage{
 		MessageType:      MessageTypeAdapterData,
 		DataType:         dataType,
@@ -88,32 +84,27 @@ func (mb *MessageBuilder) BuildAdapterDataMessage(targetMAC []byte, dataType int
 	}, nil
 }
 
-// ParseHealthReport extracts health information from a health report message
-func (mb *MessageBuilder) ParseHealthReport(msg *MeshMessage) (*HealthReport, error) {
-	if msg.DataType != AdapterTypeSerial {
-		return nil, fmt.Errorf("message is not a serial message")
+// adapterPayload pads data to a whole frame. Longer data is kept as is, up
+// to what fits in the fragments of one message.
+func adapterPayload(data []byte) ([]byte, error) {
+	if len(data) > MaxPayloadLength {
+		return nil, fmt.Errorf("data length %d exceeds maximum %d", len(data), MaxPayloadLength)
 	}
 
-	if len(msg.Data) < 12 {
-		return nil, fmt.Errorf("insufficient data length for health report: %d", len(msg.Data))
-	}
+	payload := make([]byte, max(len(data), MaxDataLength))
+	copy(payload, data)
+	return payload, nil
+}
 
-	if msg.Data[0] != OpHealthReport {
-		return nil, fmt.Errorf("message is not a health report, opcode: 0x%02x", msg.Data[0])
+// ParseHealthReport extracts health information from a health report message
+func (mb *MessageBuilder) ParseHealthReport(msg *MeshMessage) (*HealthReport, error) {
+	report, err := HealthReportOpcode.Parse(msg)
+	if err != nil {
+		return nil, err
 	}
-
-	adapterType := int32(int8(msg.Data[1])) // Convert to signed int8 first, then to int32
-	mac := make([]byte, MACAddressLength)
-	copy(mac, msg.Data[2:8])
-	uptime := binary.LittleEndian.Uint32(msg.Data[8:12])
-
-	return &HealthReport{
-		MAC:         mac,
-		AdapterType: adapterType,
-		Uptime:      uptime,
-		HopCount:    msg.HopCount,
-		OriginMAC:   msg.OriginMacAddress,
-	}, nil
+	report.HopCount = msg.HopCount
+	report.OriginMAC = msg.OriginMacAddress
+	return &report, nil
 }
 
 // HealthReport represents parsed health report data
@@ -127,9 +118,7 @@ type HealthReport struct {
 
 // IsHealthReport checks if a message is a health report
 func (mb *MessageBuilder) IsHealthReport(msg *MeshMessage) bool {
-	return msg.DataType == AdapterTypeSerial &&
-		len(msg.Data) >= 1 &&
-		msg.Data[0] == OpHealthReport
+	return HealthReportOpcode.Matches(msg)
 }
 
 // IsMasterBeacon checks if a message is a master beacon
@@ -0,0 +1,172 @@
+package mesh
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// Frame error kinds reported by mesh_frame_errors_total
+const (
+	frameErrorZeroLength = "zero_length"
+	frameErrorTooLarge   = "too_large"
+	frameErrorUnmarshal  = "unmarshal"
+	frameErrorRead       = "read"
+)
+
+var (
+	framesReceived = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mesh_frames_received_total",
+		Help: "Frames read from the serial port.",
+	})
+	framesSent = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mesh_frames_sent_total",
+		Help: "Frames written to the serial port.",
+	})
+	frameErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_frame_errors_total",
+		Help: "Frames that could not be read, by kind (zero_length, too_large, unmarshal, read).",
+	}, []string{"kind"})
+	bufferFlushes = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mesh_serial_buffer_flushes_total",
+		Help: "FlushBuffer invocations after repeated frame errors.",
+	})
+	serialReconnects = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mesh_serial_reconnects_total",
+		Help: "Times the serial port was reopened after the first start.",
+	})
+	eventPublishDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mesh_event_publish_duration_seconds",
+		Help:    "Time taken to write an event to the event store, by topic.",
+		Buckets: prometheus.ExponentialBuckets(0.0005, 4, 8), // 0.5ms to ~8s
+	}, []string{"topic"})
+	eventPublishFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_event_publish_failures_total",
+		Help: "Events that could not be written to the event store, by topic.",
+	}, []string{"topic"})
+	eventWritesInFlight = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "mesh_event_writes_in_flight",
+		Help: "Event store writes in progress.",
+	})
+	motionEvents = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_motion_events_total",
+		Help: "PIR motion detections, by node.",
+	}, []string{"mac"})
+	pingRTT = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "mesh_ping_rtt_seconds",
+		Help:    "Round-trip time of answered pings.",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 10), // 5ms to ~2.5s
+	})
+	pingsLost = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mesh_pings_lost_total",
+		Help: "Pings that went unanswered.",
+	})
+	fragmentMessages = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_fragmented_messages_total",
+		Help: "Inbound fragmented messages, by outcome (reassembled, expired, invalid).",
+	}, []string{"outcome"})
+	fragmentDuplicates = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "mesh_fragment_duplicates_total",
+		Help: "Inbound fragments dropped as duplicates.",
+	})
+	authRejected = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_auth_rejected_total",
+		Help: "Inbound frames rejected in security mode, by reason (unsigned, unknown_key, bad_signature, replay, fragmented).",
+	}, []string{"reason"})
+	otaChunks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_ota_chunks_total",
+		Help: "Firmware chunks sent, by kind (sent, retransmitted).",
+	}, []string{"kind"})
+	otaUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_ota_updates_total",
+		Help: "Node firmware updates finished, by outcome (done, failed).",
+	}, []string{"outcome"})
+	webhookDeliveries = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "mesh_webhook_deliveries_total",
+		Help: "Webhook deliveries finished, by outcome (delivered, dead).",
+	}, []string{"outcome"})
+	httpRequestDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "mesh_http_request_duration_seconds",
+		Help:    "HTTP API request latency, by route name, method and status code.",
+		Buckets: prometheus.DefBuckets,
+	}, []string{"route", "method", "code"})
+)
+
+// nodeCollector reports node registry gauges at scrape time
+type nodeCollector struct {
+	registry *NodeRegistry
+	total    *prometheus.Desc
+	online   *prometheus.Desc
+	lastSeen *prometheus.Desc
+}
+
+// NewNodeCollector creates a collector for the node counts and per-node
+// last-seen age of a registry
+func NewNodeCollector(registry *NodeRegistry) prometheus.Collector {
+	return &nodeCollector{
+		registry: registry,
+		total:    prometheus.NewDesc("mesh_nodes_total", "Known mesh nodes.", nil, nil),
+		online:   prometheus.NewDesc("mesh_nodes_online", "Mesh nodes currently online.", nil, nil),
+		lastSeen: prometheus.NewDesc("mesh_node_last_seen_age_seconds", "Seconds since a node's last health report.", []string{"mac"}, nil),
+	}
+}
+
+func (c *nodeCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.total
+	ch <- c.online
+	ch <- c.lastSeen
+}
+
+func (c *nodeCollector) Collect(ch chan<- prometheus.Metric) {
+	nodes := c.registry.GetAllNodes()
+	online := 0
+	for _, node := range nodes {
+		if node.Online {
+			online++
+		}
+		ch <- prometheus.MustNewConstMetric(c.lastSeen, prometheus.GaugeValue, time.Since(node.LastSeen).Seconds(), node.MACString)
+	}
+	ch <- prometheus.MustNewConstMetric(c.total, prometheus.GaugeValue, float64(len(nodes)))
+	ch <- prometheus.MustNewConstMetric(c.online, prometheus.GaugeValue, float64(online))
+}
+
+// statusRecorder captures the status code of a response. It forwards
+// Hijack for WebSocket upgrades and Unwrap for http.ResponseController.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusRecorder) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *statusRecorder) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return http.NewResponseController(w.ResponseWriter).Hijack()
+}
+
+func (w *statusRecorder) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// instrument records request latency under the route's name
+func (api *APIServer) instrument(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		recorder := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		next(recorder, r)
+
+		route := "unknown"
+		if current := mux.CurrentRoute(r); current != nil && current.GetName() != "" {
+			route = current.GetName()
+		}
+		httpRequestDuration.WithLabelValues(route, r.Method, strconv.Itoa(recorder.status)).Observe(time.Since(start).Seconds())
+	}
+}
@@ -1,5 +1,11 @@
 package mesh
 
+import (
+	"context"
+
+	EventStore "github.com/superbrobenji/motionServer/eventStore"
+)
+
 // MockEventStore provides a mock implementation for testing
 type MockEventStore struct {
 	messages []string
@@ -27,7 +33,12 @@ func (m *MockEventStore) WriteMessage(event string, topic string) error {
 }
 
 // SubscribeToEvents implements EventStore_interface
-func (m *MockEventStore) SubscribeToEvents(topic string) error {
+func (m *MockEventStore) SubscribeToEvents(ctx context.Context, topics []string, handler EventStore.Handler) error {
+	return nil
+}
+
+// Close implements EventStore_interface
+func (m *MockEventStore) Close() error {
 	return nil
 }
 
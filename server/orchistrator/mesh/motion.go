@@ -0,0 +1,414 @@
+package mesh
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/superbrobenji/motionServer/atomicfile"
+)
+
+const (
+	defaultMotionHistorySize = 10000
+	defaultMotionBucket      = time.Hour
+	minMotionBucket          = time.Minute
+	maxMotionBuckets         = 1000
+)
+
+// MotionRecord is a single PIR motion detection
+type MotionRecord struct {
+	MAC        string     `json:"mac"`
+	Zone       string     `json:"zone,omitempty"` // Zone of the node when the motion was detected
+	HopCount   uint32     `json:"hopCount"`
+	Data       string     `json:"data"` // hex encoded payload
+	ReceivedAt time.Time  `json:"receivedAt"`
+	DeviceTime *time.Time `json:"deviceTime,omitempty"` // When the node detected the motion, by its synced clock
+	OffsetMs   *int64     `json:"offsetMs,omitempty"`   // ReceivedAt minus DeviceTime: mesh delay plus clock drift
+}
+
+// key identifies a detection regardless of the zone it was filed under.
+// The device time is derived from Data, so it is left out too.
+func (r MotionRecord) key() MotionRecord {
+	r.Zone = ""
+	r.DeviceTime, r.OffsetMs = nil, nil
+	return r
+}
+
+// MotionFilter selects motion records. Zero fields match everything.
+type MotionFilter struct {
+	MACs  map[string]bool
+	Zones map[string]bool
+	From  time.Time
+	To    time.Time
+	Limit int
+}
+
+// Match reports whether a record passes the filter, ignoring Limit
+func (f MotionFilter) Match(record MotionRecord) bool {
+	return (len(f.MACs) == 0 || f.MACs[record.MAC]) &&
+		(len(f.Zones) == 0 || f.Zones[record.Zone]) &&
+		(f.From.IsZero() || !record.ReceivedAt.Before(f.From)) &&
+		(f.To.IsZero() || record.ReceivedAt.Before(f.To))
+}
+
+// MotionBucket counts the motion detections in one time bucket
+type MotionBucket struct {
+	Start time.Time      `json:"start"`
+	Total int            `json:"total"`
+	Nodes map[string]int `json:"nodes"` // By MAC address
+	Zones map[string]int `json:"zones"` // Nodes without a zone are not counted
+}
+
+// MotionStats is motion activity over a time range, one bucket per interval
+type MotionStats struct {
+	Bucket  string         `json:"bucket"`
+	From    time.Time      `json:"from"`
+	To      time.Time      `json:"to"`
+	Buckets []MotionBucket `json:"buckets"`
+}
+
+// MotionHistory keeps the most recent motion detections in a ring buffer.
+// Records already present are ignored, so replaying the event log over live
+// state does not duplicate them. With a file, records are also appended to it
+// as JSON lines and reloaded on open.
+type MotionHistory struct {
+	mu      sync.RWMutex
+	records []MotionRecord
+	next    int
+	full    bool
+	seen    map[MotionRecord]struct{}
+	file    *os.File
+}
+
+// NewMotionHistory creates a motion history holding up to capacity records
+func NewMotionHistory(capacity int) *MotionHistory {
+	if capacity <= 0 {
+		capacity = defaultMotionHistorySize
+	}
+	return &MotionHistory{
+		records: make([]MotionRecord, capacity),
+		seen:    make(map[MotionRecord]struct{}),
+	}
+}
+
+// OpenMotionHistory creates a motion history backed by a JSON-lines file,
+// loading the most recent capacity records from it. Older records are
+// dropped from the file.
+func OpenMotionHistory(path string, capacity int) (*MotionHistory, error) {
+	h := NewMotionHistory(capacity)
+
+	lines, err := h.load(path)
+	if err != nil {
+		return nil, err
+	}
+	if lines > len(h.records) {
+		if err := h.compact(path); err != nil {
+			return nil, err
+		}
+	}
+
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open motion log: %w", err)
+	}
+	h.file = file
+	return h, nil
+}
+
+// load adds the records in path to the history and returns how many lines
+// the file holds
+func (h *MotionHistory) load(path string) (int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("failed to read motion log: %w", err)
+	}
+	defer file.Close()
+
+	lines := 0
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		lines++
+		var record MotionRecord
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			continue // Torn final line after a crash
+		}
+		h.add(record)
+	}
+	if err := scanner.Err(); err != nil {
+		return 0, fmt.Errorf("failed to read motion log: %w", err)
+	}
+	return lines, nil
+}
+
+// compact rewrites path with only the retained records
+func (h *MotionHistory) compact(path string) error {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	records := h.ordered()
+	for i := len(records) - 1; i >= 0; i-- {
+		if err := encoder.Encode(records[i]); err != nil {
+			return fmt.Errorf("failed to compact motion log: %w", err)
+		}
+	}
+	if err := atomicfile.Write(path, buf.Bytes()); err != nil {
+		return fmt.Errorf("failed to compact motion log: %w", err)
+	}
+	return nil
+}
+
+// Record adds a motion detection, evicting the oldest when full. It reports
+// whether the record was new. New records are appended to the file, if any,
+// without waiting for a sync: the event log remains the durable copy.
+func (h *MotionHistory) Record(record MotionRecord) (bool, error) {
+	// Compare instants only, not locations or monotonic readings
+	record.ReceivedAt = record.ReceivedAt.UTC()
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if !h.add(record) {
+		return false, nil
+	}
+	if h.file == nil {
+		return true, nil
+	}
+
+	line, err := json.Marshal(record)
+	if err != nil {
+		return true, fmt.Errorf("failed to encode motion record: %w", err)
+	}
+	if _, err := h.file.Write(append(line, '\n')); err != nil {
+		return true, fmt.Errorf("failed to write motion record: %w", err)
+	}
+	return true, nil
+}
+
+// add inserts a record into the ring unless it is already present
+func (h *MotionHistory) add(record MotionRecord) bool {
+	if _, exists := h.seen[record.key()]; exists {
+		return false
+	}
+	if h.full {
+		delete(h.seen, h.records[h.next].key())
+	}
+
+	h.records[h.next] = record
+	h.seen[record.key()] = struct{}{}
+	h.next = (h.next + 1) % len(h.records)
+	if h.next == 0 {
+		h.full = true
+	}
+	return true
+}
+
+// ordered returns the retained records, most recently added first
+func (h *MotionHistory) ordered() []MotionRecord {
+	count := h.next
+	if h.full {
+		count = len(h.records)
+	}
+
+	records := make([]MotionRecord, 0, count)
+	for i := 1; i <= count; i++ {
+		index := (h.next - i + len(h.records)) % len(h.records)
+		records = append(records, h.records[index])
+	}
+	return records
+}
+
+// Recent returns up to limit records, newest first. A limit of 0 returns all.
+func (h *MotionHistory) Recent(limit int) []MotionRecord {
+	return h.Query(MotionFilter{Limit: limit})
+}
+
+// Query returns the matching records, newest first
+func (h *MotionHistory) Query(filter MotionFilter) []MotionRecord {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	matches := make([]MotionRecord, 0)
+	for _, record := range h.ordered() {
+		if filter.Match(record) {
+			matches = append(matches, record)
+		}
+	}
+
+	// Replayed records can arrive out of order
+	sort.SliceStable(matches, func(i, j int) bool {
+		return matches[i].ReceivedAt.After(matches[j].ReceivedAt)
+	})
+	if filter.Limit > 0 && len(matches) > filter.Limit {
+		matches = matches[:filter.Limit]
+	}
+	return matches
+}
+
+// Stats counts the matching records per node and zone in buckets of the
+// given size, aligned to multiples of it since the Unix epoch. The range
+// defaults to the oldest matching record, or maxMotionBuckets buckets back if
+// that is later, until now. Empty buckets are included so the series is
+// continuous.
+func (h *MotionHistory) Stats(filter MotionFilter, bucket time.Duration, now time.Time) (MotionStats, error) {
+	filter.Limit = 0
+	records := h.Query(filter)
+
+	from, to := filter.From, filter.To
+	if to.IsZero() {
+		to = now
+	}
+	if from.IsZero() {
+		from = to
+		if len(records) > 0 {
+			from = records[len(records)-1].ReceivedAt
+		}
+		if earliest := to.Add(-(maxMotionBuckets - 1) * bucket).Truncate(bucket); from.Before(earliest) {
+			from = earliest
+		}
+	}
+	stats := MotionStats{Bucket: bucket.String(), From: from.UTC(), To: to.UTC(), Buckets: []MotionBucket{}}
+	if !from.Before(to) {
+		return stats, nil
+	}
+
+	start := from.Truncate(bucket)
+	count := int((to.Sub(start) + bucket - 1) / bucket)
+	if count > maxMotionBuckets {
+		return stats, fmt.Errorf("%d buckets of %s is more than %d; use a larger bucket or a shorter range", count, bucket, maxMotionBuckets)
+	}
+
+	stats.Buckets = make([]MotionBucket, count)
+	for i := range stats.Buckets {
+		stats.Buckets[i] = MotionBucket{
+			Start: start.Add(time.Duration(i) * bucket).UTC(),
+			Nodes: make(map[string]int),
+			Zones: make(map[string]int),
+		}
+	}
+	for _, record := range records {
+		if record.ReceivedAt.Before(from) || !record.ReceivedAt.Before(to) {
+			continue
+		}
+		b := &stats.Buckets[record.ReceivedAt.Sub(start)/bucket]
+		b.Total++
+		b.Nodes[record.MAC]++
+		if record.Zone != "" {
+			b.Zones[record.Zone]++
+		}
+	}
+	return stats, nil
+}
+
+// Len returns the number of retained records
+func (h *MotionHistory) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	if h.full {
+		return len(h.records)
+	}
+	return h.next
+}
+
+// Close closes the motion log file
+func (h *MotionHistory) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.file == nil {
+		return nil
+	}
+	err := h.file.Close()
+	h.file = nil
+	return err
+}
+
+// ParseMotionFilter reads the comma-separated mac and zone query parameters,
+// RFC3339 from and to times and limit
+func ParseMotionFilter(query url.Values) (MotionFilter, error) {
+	filter := MotionFilter{Limit: 100}
+
+	for _, macStr := range splitList(query["mac"]) {
+		mac, err := StringToMAC(macStr)
+		if err != nil {
+			return filter, err
+		}
+		filter.MACs = addToSet(filter.MACs, macToString(mac))
+	}
+
+	for _, zone := range splitList(query["zone"]) {
+		filter.Zones = addToSet(filter.Zones, zone)
+	}
+
+	for name, field := range map[string]*time.Time{"from": &filter.From, "to": &filter.To} {
+		if value := query.Get(name); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid %s time (RFC3339): %s", name, value)
+			}
+			*field = parsed
+		}
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > 1000 {
+			return filter, fmt.Errorf("invalid limit (1-1000): %s", value)
+		}
+		filter.Limit = limit
+	}
+
+	return filter, nil
+}
+
+// getMotion returns recorded motion detections, newest first
+func (api *APIServer) getMotion(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseMotionFilter(r.URL.Query())
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    api.meshServer.GetMotionHistory().Query(filter),
+	})
+}
+
+// getMotionStats returns motion counts per node and zone per time bucket
+func (api *APIServer) getMotionStats(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	filter, err := ParseMotionFilter(query)
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	bucket := defaultMotionBucket
+	if value := query.Get("bucket"); value != "" {
+		bucket, err = time.ParseDuration(value)
+		if err != nil || bucket < minMotionBucket {
+			api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid bucket (at least %s): %s", minMotionBucket, value))
+			return
+		}
+	}
+
+	stats, err := api.meshServer.GetMotionHistory().Stats(filter, bucket, time.Now())
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    stats,
+	})
+}
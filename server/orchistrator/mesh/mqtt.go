@@ -0,0 +1,436 @@
+package mesh
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+	"github.com/superbrobenji/motionServer/events"
+)
+
+const (
+	defaultMQTTClientID        = "planetopia-mesh"
+	defaultMQTTTopicPrefix     = "planetopia"
+	defaultMQTTDiscoveryPrefix = "homeassistant"
+	defaultMotionOffDelay      = 30 * time.Second
+	mqttConnectTimeout         = 10 * time.Second
+	mqttPublishTimeout         = 5 * time.Second
+	maxLEDTextBytes            = 127 // Home Assistant text entities hold at most 255 characters
+)
+
+// MQTT payloads understood by Home Assistant
+const (
+	mqttOnline  = "online"
+	mqttOffline = "offline"
+	mqttMotion  = "ON"
+)
+
+// adapterTypeOptions are the adapter types offered by the Home Assistant
+// select entity, by name
+var adapterTypeOptions = []int32{AdapterTypePIR, AdapterTypeWIFI, AdapterTypeLED, AdapterTypeSerial}
+
+// MQTTConfig configures the MQTT bridge
+type MQTTConfig struct {
+	Broker          string // e.g. tcp://localhost:1883
+	ClientID        string // Default "planetopia-mesh"
+	Username        string
+	Password        string
+	TopicPrefix     string        // Root of state and command topics (default "planetopia")
+	DiscoveryPrefix string        // Home Assistant discovery prefix (default "homeassistant")
+	MotionOffDelay  time.Duration // How long motion sensors stay on after a detection (default 30s)
+}
+
+// MQTTBridge publishes nodes to Home Assistant over MQTT. Each node becomes
+// a device with a motion binary_sensor, uptime and hop count sensors, an
+// adapter type select and an LED data text entity, announced on the
+// discovery topics. Availability follows the node's online/offline state,
+// and commands on the select and text entities are sent to the node.
+type MQTTBridge struct {
+	meshServer *MeshServer
+	client     mqtt.Client
+	config     MQTTConfig
+
+	mu        sync.Mutex
+	announced map[string]string // MAC to the device name and zone it was announced with
+
+	stop chan struct{}
+	done chan struct{}
+}
+
+// mqttNodeState is the JSON published on a node's state topic
+type mqttNodeState struct {
+	Uptime      uint32    `json:"uptime"`
+	HopCount    uint32    `json:"hopCount"`
+	AdapterType string    `json:"adapterType"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
+
+// NewMQTTBridge creates a bridge between meshServer and an MQTT broker
+func NewMQTTBridge(meshServer *MeshServer, config MQTTConfig) *MQTTBridge {
+	if config.ClientID == "" {
+		config.ClientID = defaultMQTTClientID
+	}
+	if config.TopicPrefix == "" {
+		config.TopicPrefix = defaultMQTTTopicPrefix
+	}
+	if config.DiscoveryPrefix == "" {
+		config.DiscoveryPrefix = defaultMQTTDiscoveryPrefix
+	}
+	if config.MotionOffDelay <= 0 {
+		config.MotionOffDelay = defaultMotionOffDelay
+	}
+
+	b := &MQTTBridge{
+		meshServer: meshServer,
+		config:     config,
+		announced:  make(map[string]string),
+		stop:       make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+
+	options := mqtt.NewClientOptions().
+		AddBroker(config.Broker).
+		SetClientID(config.ClientID).
+		SetUsername(config.Username).
+		SetPassword(config.Password).
+		SetOrderMatters(false).
+		SetAutoReconnect(true).
+		SetConnectRetry(true).
+		SetWill(b.bridgeTopic(), mqttOffline, 1, true).
+		SetOnConnectHandler(b.onConnect).
+		SetConnectionLostHandler(func(_ mqtt.Client, err error) {
+			log.Printf("[MQTT] Connection to %s lost: %v", config.Broker, err)
+		})
+	b.client = mqtt.NewClient(options)
+	return b
+}
+
+// Start connects to the broker and begins publishing events. If the broker
+// cannot be reached within the connect timeout, Start returns an error but
+// the bridge keeps retrying in the background.
+func (b *MQTTBridge) Start() error {
+	go b.run()
+
+	token := b.client.Connect()
+	if !token.WaitTimeout(mqttConnectTimeout) {
+		return fmt.Errorf("no connection to %s after %s, retrying in the background", b.config.Broker, mqttConnectTimeout)
+	}
+	if err := token.Error(); err != nil {
+		return fmt.Errorf("failed to connect to %s: %w", b.config.Broker, err)
+	}
+	return nil
+}
+
+// Stop marks the bridge offline and disconnects
+func (b *MQTTBridge) Stop() {
+	close(b.stop)
+	<-b.done
+
+	if b.client.IsConnected() {
+		b.client.Publish(b.bridgeTopic(), 1, true, mqttOffline).WaitTimeout(mqttPublishTimeout)
+	}
+	b.client.Disconnect(250)
+}
+
+// IsConnected reports whether the bridge is connected to the broker
+func (b *MQTTBridge) IsConnected() bool {
+	return b.client.IsConnectionOpen()
+}
+
+// run publishes node events until Stop is called. If the event bus drops the
+// bridge for falling behind, it resubscribes and republishes every node.
+func (b *MQTTBridge) run() {
+	defer close(b.done)
+
+	filter := StreamFilter{Types: map[string]bool{
+		events.TypeMotion:        true,
+		events.TypeHealth:        true,
+		events.TypeNodeLifecycle: true,
+	}}
+	for {
+		sub := b.meshServer.GetEventBus().Subscribe(filter)
+	events:
+		for {
+			select {
+			case <-b.stop:
+				sub.Close()
+				return
+			case <-sub.Done():
+				log.Printf("[MQTT] Fell behind the event bus, republishing all nodes")
+				break events
+			case event := <-sub.Events():
+				b.publishEvent(event)
+			}
+		}
+		b.announceAll()
+	}
+}
+
+// onConnect marks the bridge online, subscribes to commands and Home
+// Assistant's birth message, and publishes every node. It runs on every
+// reconnect, since the broker may have lost retained messages.
+func (b *MQTTBridge) onConnect(client mqtt.Client) {
+	log.Printf("[MQTT] Connected to %s", b.config.Broker)
+	client.Publish(b.bridgeTopic(), 1, true, mqttOnline)
+
+	client.Subscribe(b.config.TopicPrefix+"/+/+/set", 1, b.handleCommand)
+	client.Subscribe(b.config.DiscoveryPrefix+"/status", 1, func(_ mqtt.Client, msg mqtt.Message) {
+		if string(msg.Payload()) == mqttOnline {
+			b.announceAll() // Home Assistant restarted
+		}
+	})
+
+	b.mu.Lock()
+	b.announced = make(map[string]string)
+	b.mu.Unlock()
+	b.announceAll()
+}
+
+// publishEvent updates the MQTT state of the node an event is about
+func (b *MQTTBridge) publishEvent(event StreamEvent) {
+	mac, err := StringToMAC(event.Subject)
+	if err != nil {
+		return
+	}
+	node, exists := b.meshServer.GetNodeRegistry().GetNode(mac)
+	if !exists {
+		return
+	}
+
+	b.announce(node)
+	switch event.Type {
+	case events.TypeMotion:
+		b.client.Publish(b.nodeTopic(node, "motion"), 0, false, mqttMotion)
+	case events.TypeHealth:
+		b.publishState(node)
+	case events.TypeNodeLifecycle:
+		b.publishAvailability(node)
+	}
+}
+
+// announceAll publishes discovery, state and availability for every node
+func (b *MQTTBridge) announceAll() {
+	if !b.client.IsConnectionOpen() {
+		return
+	}
+	for _, node := range b.meshServer.GetNodeRegistry().GetAllNodes() {
+		b.announce(node)
+		b.publishState(node)
+		b.publishAvailability(node)
+	}
+}
+
+// announce publishes a node's discovery configs, unless they were already
+// published with its current name and zone
+func (b *MQTTBridge) announce(node *NodeInfo) {
+	device := node.Name + "\x00" + node.Zone
+	b.mu.Lock()
+	if b.announced[node.MACString] == device {
+		b.mu.Unlock()
+		return
+	}
+	b.announced[node.MACString] = device
+	b.mu.Unlock()
+
+	for component, configs := range b.discoveryConfigs(node) {
+		for object, config := range configs {
+			payload, err := json.Marshal(config)
+			if err != nil {
+				log.Printf("[MQTT] Failed to encode %s discovery for %s: %v", object, node.MACString, err)
+				continue
+			}
+			topic := fmt.Sprintf("%s/%s/%s/%s/config", b.config.DiscoveryPrefix, component, b.objectID(node), object)
+			b.client.Publish(topic, 1, true, payload)
+		}
+	}
+	b.publishAvailability(node)
+}
+
+// discoveryConfigs returns a node's Home Assistant entity configs by
+// component and object ID
+func (b *MQTTBridge) discoveryConfigs(node *NodeInfo) map[string]map[string]map[string]interface{} {
+	name := node.Name
+	if name == "" {
+		name = "Mesh node " + node.MACString
+	}
+	device := map[string]interface{}{
+		"identifiers":  []string{b.objectID(node)},
+		"connections":  [][]string{{"mac", node.MACString}},
+		"name":         name,
+		"manufacturer": "Planetopia",
+		"model":        "Mesh node",
+	}
+	if node.Zone != "" {
+		device["suggested_area"] = node.Zone
+	}
+	if node.Firmware != nil {
+		device["sw_version"] = node.Firmware.Version
+		device["model"] = node.Firmware.ChipModel + " mesh node"
+	}
+
+	entity := func(object, entityName string, fields map[string]interface{}) map[string]interface{} {
+		config := map[string]interface{}{
+			"name":      entityName,
+			"unique_id": b.objectID(node) + "_" + object,
+			"device":    device,
+			"availability": []map[string]string{
+				{"topic": b.bridgeTopic()},
+				{"topic": b.nodeTopic(node, "availability")},
+			},
+			"availability_mode": "all",
+		}
+		for key, value := range fields {
+			config[key] = value
+		}
+		return config
+	}
+
+	var options []string
+	for _, adapterType := range adapterTypeOptions {
+		options = append(options, GetAdapterTypeName(adapterType))
+	}
+	stateTopic := b.nodeTopic(node, "state")
+
+	return map[string]map[string]map[string]interface{}{
+		"binary_sensor": {
+			"motion": entity("motion", "Motion", map[string]interface{}{
+				"device_class": "motion",
+				"state_topic":  b.nodeTopic(node, "motion"),
+				"payload_on":   mqttMotion,
+				"off_delay":    int(b.config.MotionOffDelay / time.Second),
+			}),
+		},
+		"sensor": {
+			"uptime": entity("uptime", "Uptime", map[string]interface{}{
+				"device_class":        "duration",
+				"unit_of_measurement": "s",
+				"state_class":         "total_increasing",
+				"entity_category":     "diagnostic",
+				"state_topic":         stateTopic,
+				"value_template":      "{{ value_json.uptime }}",
+			}),
+			"hop_count": entity("hop_count", "Hop count", map[string]interface{}{
+				"state_class":     "measurement",
+				"entity_category": "diagnostic",
+				"state_topic":     stateTopic,
+				"value_template":  "{{ value_json.hopCount }}",
+			}),
+		},
+		"select": {
+			"adapter_type": entity("adapter_type", "Adapter type", map[string]interface{}{
+				"entity_category": "config",
+				"options":         options,
+				"state_topic":     stateTopic,
+				"value_template":  "{{ value_json.adapterType }}",
+				"command_topic":   b.nodeTopic(node, "adapter_type/set"),
+			}),
+		},
+		"text": {
+			"led": entity("led", "LED data", map[string]interface{}{
+				"command_topic": b.nodeTopic(node, "led/set"),
+				"pattern":       fmt.Sprintf("^([0-9a-fA-F]{2}){0,%d}$", maxLEDTextBytes),
+				"max":           maxLEDTextBytes * 2,
+			}),
+		},
+	}
+}
+
+// publishState publishes a node's uptime, hop count and adapter type
+func (b *MQTTBridge) publishState(node *NodeInfo) {
+	payload, err := json.Marshal(mqttNodeState{
+		Uptime:      node.Uptime,
+		HopCount:    node.HopCount,
+		AdapterType: GetAdapterTypeName(node.AdapterType),
+		LastSeen:    node.LastSeen.UTC(),
+	})
+	if err != nil {
+		log.Printf("[MQTT] Failed to encode state of %s: %v", node.MACString, err)
+		return
+	}
+	b.client.Publish(b.nodeTopic(node, "state"), 1, true, payload)
+}
+
+// publishAvailability publishes whether a node is online
+func (b *MQTTBridge) publishAvailability(node *NodeInfo) {
+	availability := mqttOffline
+	if node.Online {
+		availability = mqttOnline
+	}
+	b.client.Publish(b.nodeTopic(node, "availability"), 1, true, availability)
+}
+
+// handleCommand sends adapter_type/set and led/set commands to their node,
+// recording them in the audit log. The broker does not say who published a
+// command, so the actor is "mqtt" and the path is the topic.
+func (b *MQTTBridge) handleCommand(_ mqtt.Client, msg mqtt.Message) {
+	parts := strings.Split(strings.TrimPrefix(msg.Topic(), b.config.TopicPrefix+"/"), "/")
+	if len(parts) != 3 {
+		return
+	}
+	nodeID, command, payload := parts[0], parts[1], strings.TrimSpace(string(msg.Payload()))
+
+	start := time.Now()
+	event := &events.AuditEvent{
+		Interface: "mqtt",
+		ActorName: "mqtt",
+		Path:      msg.Topic(),
+		Params:    map[string]interface{}{"payload": payload},
+	}
+
+	err := func() error {
+		mac, err := StringToMAC(nodeID)
+		if err != nil {
+			return err
+		}
+		event.Target = macToString(mac)
+
+		switch command {
+		case "adapter_type":
+			event.Action = "configureNode"
+			adapterType, err := parseAdapterType(payload)
+			if err != nil {
+				return err
+			}
+			return b.meshServer.ConfigureNode(mac, adapterType)
+		case "led":
+			event.Action = "sendAdapterData"
+			data, err := hex.DecodeString(payload)
+			if err != nil {
+				return fmt.Errorf("LED data must be hex: %w", err)
+			}
+			return b.meshServer.SendAdapterData(mac, AdapterTypeLED, data)
+		default:
+			event.Action = command
+			return fmt.Errorf("unknown command: %s", command)
+		}
+	}()
+
+	event.DurationMs = time.Since(start).Milliseconds()
+	event.Outcome = events.AuditSuccess
+	if err != nil {
+		event.Outcome = events.AuditFailure
+		event.Error = err.Error()
+		log.Printf("[MQTT] Command %s failed: %v", msg.Topic(), err)
+	}
+	b.meshServer.RecordAudit(event)
+}
+
+// objectID identifies a node in topics and Home Assistant unique IDs
+func (b *MQTTBridge) objectID(node *NodeInfo) string {
+	return b.config.TopicPrefix + "_" + hex.EncodeToString(node.MAC)
+}
+
+// nodeTopic returns a topic under the node's state and command root
+func (b *MQTTBridge) nodeTopic(node *NodeInfo, suffix string) string {
+	return b.config.TopicPrefix + "/" + hex.EncodeToString(node.MAC) + "/" + suffix
+}
+
+// bridgeTopic is the availability topic of the bridge itself
+func (b *MQTTBridge) bridgeTopic() string {
+	return b.config.TopicPrefix + "/bridge/availability"
+}
@@ -0,0 +1,199 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chip models as reported by esp_chip_info
+var chipModels = map[byte]string{
+	1:  "ESP32",
+	2:  "ESP32-S2",
+	5:  "ESP32-C3",
+	9:  "ESP32-S3",
+	12: "ESP32-C2",
+	13: "ESP32-C6",
+	16: "ESP32-H2",
+	18: "ESP32-P4",
+}
+
+// NodeDetails is the payload of OpNodeInfo, sent by a node in reply to
+// OpNodeInfoReq
+type NodeDetails struct {
+	Major, Minor, Patch byte
+	Build               uint32 // First four bytes of the firmware's commit hash
+	ChipModel           byte   // esp_chip_model_t
+	ChipRevision        byte   // Major revision in the high nibble, minor in the low
+	Adapters            byte   // Bit n is set if the firmware supports adapter type n
+}
+
+// NodeFirmware is the firmware and hardware a node last reported
+type NodeFirmware struct {
+	Version           string    `json:"version"`
+	BuildHash         string    `json:"buildHash"`
+	ChipModel         string    `json:"chipModel"`
+	ChipRevision      string    `json:"chipRevision"`
+	SupportedAdapters []string  `json:"supportedAdapters"`
+	ReportedAt        time.Time `json:"reportedAt"`
+}
+
+var (
+	NodeInfoRequestOpcode = &Opcode[NodeTarget]{
+		OpcodeSpec: OpcodeSpec{Code: OpNodeInfoReq, Name: "nodeInfoRequest", Fields: []PayloadField{
+			{Name: "target", Offset: 1, Size: MACAddressLength},
+		}},
+		Encode: encodeNodeTarget,
+		Decode: decodeNodeTarget,
+	}
+
+	NodeInfoOpcode = &Opcode[NodeDetails]{
+		OpcodeSpec: OpcodeSpec{Code: OpNodeInfo, Name: "nodeInfo", Fields: []PayloadField{
+			{Name: "major", Offset: 1, Size: 1},
+			{Name: "minor", Offset: 2, Size: 1},
+			{Name: "patch", Offset: 3, Size: 1},
+			{Name: "build", Offset: 4, Size: 4},
+			{Name: "chipModel", Offset: 8, Size: 1},
+			{Name: "chipRevision", Offset: 9, Size: 1},
+			{Name: "adapters", Offset: 10, Size: 1},
+		}},
+		Encode: func(payload []byte, value NodeDetails) {
+			payload[1], payload[2], payload[3] = value.Major, value.Minor, value.Patch
+			binary.BigEndian.PutUint32(payload[4:8], value.Build)
+			payload[8] = value.ChipModel
+			payload[9] = value.ChipRevision
+			payload[10] = value.Adapters
+		},
+		Decode: func(payload []byte) (NodeDetails, error) {
+			return NodeDetails{
+				Major:        payload[1],
+				Minor:        payload[2],
+				Patch:        payload[3],
+				Build:        binary.BigEndian.Uint32(payload[4:8]),
+				ChipModel:    payload[8],
+				ChipRevision: payload[9],
+				Adapters:     payload[10],
+			}, nil
+		},
+		Handle: func(ms *MeshServer, msg *MeshMessage, details NodeDetails, receivedAt time.Time, replayed bool) error {
+			return ms.handleNodeInfo(msg.OriginMacAddress, details, receivedAt, replayed)
+		},
+	}
+)
+
+// Firmware converts a node's report to the form kept in NodeInfo
+func (d NodeDetails) Firmware(reportedAt time.Time) NodeFirmware {
+	build := make([]byte, 4)
+	binary.BigEndian.PutUint32(build, d.Build)
+
+	chipModel, known := chipModels[d.ChipModel]
+	if !known {
+		chipModel = fmt.Sprintf("Unknown(%d)", d.ChipModel)
+	}
+
+	adapters := []string{}
+	for adapterType := int32(0); adapterType < 8; adapterType++ {
+		if d.Adapters&(1<<adapterType) != 0 {
+			adapters = append(adapters, GetAdapterTypeName(adapterType))
+		}
+	}
+
+	return NodeFirmware{
+		Version:           fmt.Sprintf("%d.%d.%d", d.Major, d.Minor, d.Patch),
+		BuildHash:         hex.EncodeToString(build),
+		ChipModel:         chipModel,
+		ChipRevision:      fmt.Sprintf("v%d.%d", d.ChipRevision>>4, d.ChipRevision&0x0F),
+		SupportedAdapters: adapters,
+		ReportedAt:        reportedAt,
+	}
+}
+
+// handleNodeInfo records a node's firmware report
+func (ms *MeshServer) handleNodeInfo(mac []byte, details NodeDetails, receivedAt time.Time, replayed bool) error {
+	firmware := details.Firmware(receivedAt)
+	if !ms.nodeRegistry.SetFirmware(mac, firmware) {
+		if !replayed {
+			log.Printf("[NODE_INFO] Ignoring info from unknown node %s", macToString(mac))
+		}
+		return nil
+	}
+
+	if !replayed {
+		log.Printf("[NODE_INFO] Node %s runs firmware %s (%s) on %s %s",
+			macToString(mac), firmware.Version, firmware.BuildHash, firmware.ChipModel, firmware.ChipRevision)
+	}
+	return nil
+}
+
+// RequestNodeInfo asks a node, or every node when targetMAC is BroadcastMAC,
+// to report its firmware version and hardware
+func (ms *MeshServer) RequestNodeInfo(targetMAC []byte) error {
+	msg, err := NodeInfoRequestOpcode.Build(targetMAC, NodeTarget{Target: targetMAC})
+	if err != nil {
+		return fmt.Errorf("failed to build node info request: %w", err)
+	}
+	return ms.SendMessage(msg)
+}
+
+// refreshNodeInfo requests a node's info without blocking the message loop
+func (ms *MeshServer) refreshNodeInfo(mac []byte) {
+	ms.spawn(func() {
+		if err := ms.RequestNodeInfo(mac); err != nil {
+			log.Printf("[NODE_INFO] Failed to request info from %s: %v", macToString(mac), err)
+		}
+	})
+}
+
+// parseFirmwareVersion parses a major[.minor[.patch]] version
+func parseFirmwareVersion(value string) ([3]int, error) {
+	var version [3]int
+	parts := strings.Split(value, ".")
+	if len(parts) > 3 {
+		return version, fmt.Errorf("invalid firmware version: %s", value)
+	}
+	for i, part := range parts {
+		number, err := strconv.Atoi(part)
+		if err != nil || number < 0 {
+			return version, fmt.Errorf("invalid firmware version: %s", value)
+		}
+		version[i] = number
+	}
+	return version, nil
+}
+
+// compareFirmwareVersions orders versions parsed by parseFirmwareVersion
+func compareFirmwareVersions(a, b [3]int) int {
+	for i := range a {
+		if a[i] != b[i] {
+			if a[i] < b[i] {
+				return -1
+			}
+			return 1
+		}
+	}
+	return 0
+}
+
+// requestNodeInfo asks a node, or all nodes for ff:ff:ff:ff:ff:ff, to report
+// its firmware. Replies update the node's firmware field.
+func (api *APIServer) requestNodeInfo(w http.ResponseWriter, r *http.Request) {
+	mac, ok := api.restartTarget(w, r)
+	if !ok {
+		return
+	}
+
+	if err := api.meshServer.RequestNodeInfo(mac); err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to request node info: %v", err))
+		return
+	}
+
+	api.writeJSON(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Info requested from %s", macToString(mac)),
+	})
+}
@@ -0,0 +1,320 @@
+package mesh
+
+import (
+	"cmp"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxNodePageSize = 1000
+
+// Node fields that can be selected with the fields query parameter
+var nodeFields = map[string]bool{
+	"mac": true, "macString": true, "adapterType": true, "adapterTypeName": true,
+	"uptime": true, "lastSeen": true, "lastSeenAgo": true, "hopCount": true,
+	"online": true, "name": true, "zone": true, "latency": true, "firmware": true,
+}
+
+// nodeSorts orders nodes by each sortable field
+var nodeSorts = map[string]func(a, b NodeView) int{
+	"lastSeen": func(a, b NodeView) int { return a.LastSeen.Compare(b.LastSeen) },
+	"hopCount": func(a, b NodeView) int { return cmp.Compare(a.HopCount, b.HopCount) },
+	"mac":      func(a, b NodeView) int { return strings.Compare(a.MACString, b.MACString) },
+	"name": func(a, b NodeView) int {
+		return strings.Compare(strings.ToLower(a.Name), strings.ToLower(b.Name))
+	},
+}
+
+// NodeView is a node as served by the API, with fields computed at request
+// time
+type NodeView struct {
+	*NodeInfo
+	AdapterTypeName string `json:"adapterTypeName"`
+	Online          bool   `json:"online"`      // Seen within the health timeout
+	LastSeenAgo     int64  `json:"lastSeenAgo"` // Seconds since lastSeen
+
+	Latency *NodeLatency `json:"latency,omitempty"` // Set once the node has been pinged
+}
+
+// NodeView computes the derived fields of node as of now. A node is online
+// if it was seen within the health timeout, even before the offline sweep
+// has caught up with it.
+func (ms *MeshServer) NodeView(node *NodeInfo, now time.Time) NodeView {
+	view := NodeView{
+		NodeInfo:        node,
+		AdapterTypeName: GetAdapterTypeName(node.AdapterType),
+		Online:          node.LastSeen.After(now.Add(-ms.healthTimeout)),
+		LastSeenAgo:     int64(now.Sub(node.LastSeen) / time.Second),
+	}
+	if latency, exists := ms.GetLatency(node.MACString); exists {
+		view.Latency = &latency
+	}
+	return view
+}
+
+// NodeQuery selects, orders and pages the nodes returned by GET /nodes
+type NodeQuery struct {
+	Sort         string // Key of nodeSorts
+	Descending   bool
+	Status       string // online, offline or empty for both
+	AdapterTypes map[int32]bool
+	MinHops      uint32
+	MaxHops      uint32
+	Firmware     map[string]bool // Exact versions; "unknown" matches nodes that have not reported one
+	BelowVersion *[3]int         // Only nodes on an older or unknown version
+	Cursor       *NodeCursor     // Resume after this node
+	Limit        int             // 0 returns every match
+	Fields       []string        // Empty returns every field
+}
+
+// NodeCursor marks the last node of a page. It holds the node's sort keys so
+// the next page starts in the right place even if nodes come and go.
+type NodeCursor struct {
+	Sort     string    `json:"s"`
+	MAC      string    `json:"m"`
+	LastSeen time.Time `json:"t"`
+	HopCount uint32    `json:"h"`
+	Name     string    `json:"n"`
+}
+
+// ParseNodeQuery reads the sort, status, adapterType, minHops, maxHops,
+// firmware, firmwareBelow, cursor, limit and fields query parameters. sort is
+// a field name, prefixed with - for descending order; adapterType takes
+// numbers or names.
+func ParseNodeQuery(query url.Values) (NodeQuery, error) {
+	q := NodeQuery{Sort: "mac", MaxHops: math.MaxUint32}
+
+	if value := query.Get("sort"); value != "" {
+		q.Descending = strings.HasPrefix(value, "-")
+		q.Sort = strings.TrimPrefix(value, "-")
+		if _, ok := nodeSorts[q.Sort]; !ok {
+			return q, fmt.Errorf("unknown sort field: %s", q.Sort)
+		}
+	}
+
+	switch status := query.Get("status"); status {
+	case "", "online", "offline":
+		q.Status = status
+	default:
+		return q, fmt.Errorf("invalid status (online or offline): %s", status)
+	}
+
+	for _, value := range splitList(query["adapterType"]) {
+		adapterType, err := parseAdapterType(value)
+		if err != nil {
+			return q, err
+		}
+		if q.AdapterTypes == nil {
+			q.AdapterTypes = make(map[int32]bool)
+		}
+		q.AdapterTypes[adapterType] = true
+	}
+
+	for name, field := range map[string]*uint32{"minHops": &q.MinHops, "maxHops": &q.MaxHops} {
+		if value := query.Get(name); value != "" {
+			parsed, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return q, fmt.Errorf("invalid %s: %s", name, value)
+			}
+			*field = uint32(parsed)
+		}
+	}
+	if q.MinHops > q.MaxHops {
+		return q, fmt.Errorf("minHops %d is greater than maxHops %d", q.MinHops, q.MaxHops)
+	}
+
+	for _, value := range splitList(query["firmware"]) {
+		if q.Firmware == nil {
+			q.Firmware = make(map[string]bool)
+		}
+		q.Firmware[value] = true
+	}
+
+	if value := query.Get("firmwareBelow"); value != "" {
+		version, err := parseFirmwareVersion(value)
+		if err != nil {
+			return q, err
+		}
+		q.BelowVersion = &version
+	}
+
+	if value := query.Get("limit"); value != "" {
+		limit, err := strconv.Atoi(value)
+		if err != nil || limit <= 0 || limit > maxNodePageSize {
+			return q, fmt.Errorf("invalid limit (1-%d): %s", maxNodePageSize, value)
+		}
+		q.Limit = limit
+	}
+
+	if value := query.Get("cursor"); value != "" {
+		cursor, err := decodeNodeCursor(value)
+		if err != nil {
+			return q, err
+		}
+		if cursor.Sort != q.sortSpec() {
+			return q, fmt.Errorf("cursor is for sort %s, not %s", cursor.Sort, q.sortSpec())
+		}
+		q.Cursor = cursor
+	}
+
+	for _, field := range splitList(query["fields"]) {
+		if !nodeFields[field] {
+			return q, fmt.Errorf("unknown field: %s", field)
+		}
+		q.Fields = append(q.Fields, field)
+	}
+
+	return q, nil
+}
+
+// Match reports whether a node passes the query's filters
+func (q NodeQuery) Match(node NodeView) bool {
+	switch {
+	case q.Status == "online" && !node.Online, q.Status == "offline" && node.Online:
+		return false
+	case len(q.AdapterTypes) > 0 && !q.AdapterTypes[node.AdapterType]:
+		return false
+	case len(q.Firmware) > 0 && !q.Firmware[node.firmwareVersion()]:
+		return false
+	case q.BelowVersion != nil && !node.firmwareBelow(*q.BelowVersion):
+		return false
+	}
+	return node.HopCount >= q.MinHops && node.HopCount <= q.MaxHops
+}
+
+// Apply filters and sorts nodes and returns the page after the cursor, with
+// the cursor for the next page if there is one
+func (q NodeQuery) Apply(nodes []NodeView) ([]NodeView, string) {
+	matches := make([]NodeView, 0, len(nodes))
+	for _, node := range nodes {
+		if q.Match(node) && (q.Cursor == nil || q.compare(node, q.Cursor.node()) > 0) {
+			matches = append(matches, node)
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool { return q.compare(matches[i], matches[j]) < 0 })
+
+	if q.Limit == 0 || len(matches) <= q.Limit {
+		return matches, ""
+	}
+	page := matches[:q.Limit]
+	return page, q.encodeCursor(page[len(page)-1])
+}
+
+// Project returns node with only the query's fields, or the whole node if
+// none were selected
+func (q NodeQuery) Project(node NodeView) (interface{}, error) {
+	if len(q.Fields) == 0 {
+		return node, nil
+	}
+
+	data, err := json.Marshal(node)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal node: %w", err)
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(data, &all); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal node: %w", err)
+	}
+
+	projected := make(map[string]json.RawMessage, len(q.Fields))
+	for _, field := range q.Fields {
+		if value, ok := all[field]; ok { // Empty name and zone are omitted
+			projected[field] = value
+		}
+	}
+	return projected, nil
+}
+
+// compare orders nodes by the sort field, then by MAC address so that every
+// node has a fixed position for cursors
+func (q NodeQuery) compare(a, b NodeView) int {
+	result := nodeSorts[q.Sort](a, b)
+	if q.Descending {
+		result = -result
+	}
+	if result == 0 {
+		result = strings.Compare(a.MACString, b.MACString)
+	}
+	return result
+}
+
+// sortSpec returns the sort parameter the query was parsed from
+func (q NodeQuery) sortSpec() string {
+	if q.Descending {
+		return "-" + q.Sort
+	}
+	return q.Sort
+}
+
+// encodeCursor returns an opaque cursor for the page ending with node
+func (q NodeQuery) encodeCursor(node NodeView) string {
+	data, _ := json.Marshal(NodeCursor{
+		Sort:     q.sortSpec(),
+		MAC:      node.MACString,
+		LastSeen: node.LastSeen,
+		HopCount: node.HopCount,
+		Name:     node.Name,
+	})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodeNodeCursor parses a cursor returned by encodeCursor
+func decodeNodeCursor(value string) (*NodeCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	var cursor NodeCursor
+	if err := json.Unmarshal(data, &cursor); err != nil {
+		return nil, fmt.Errorf("invalid cursor: %w", err)
+	}
+	return &cursor, nil
+}
+
+// node returns a node with the cursor's sort keys, for comparisons
+func (c *NodeCursor) node() NodeView {
+	return NodeView{NodeInfo: &NodeInfo{
+		MACString: c.MAC,
+		LastSeen:  c.LastSeen,
+		HopCount:  c.HopCount,
+		Name:      c.Name,
+	}}
+}
+
+// firmwareVersion returns the node's firmware version, or "unknown"
+func (v NodeView) firmwareVersion() string {
+	if v.Firmware == nil {
+		return "unknown"
+	}
+	return v.Firmware.Version
+}
+
+// firmwareBelow reports whether the node runs a firmware older than version.
+// Nodes that have not reported a version may need updating, so they count.
+func (v NodeView) firmwareBelow(version [3]int) bool {
+	if v.Firmware == nil {
+		return true
+	}
+	current, err := parseFirmwareVersion(v.Firmware.Version)
+	return err != nil || compareFirmwareVersions(current, version) < 0
+}
+
+// parseAdapterType accepts an adapter type number or name, e.g. 0 or PIR
+func parseAdapterType(value string) (int32, error) {
+	if number, err := strconv.ParseInt(value, 10, 32); err == nil {
+		return int32(number), nil
+	}
+	for adapterType := AdapterTypeUnknown; adapterType <= AdapterTypeSerial; adapterType++ {
+		if strings.EqualFold(value, GetAdapterTypeName(adapterType)) {
+			return adapterType, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown adapter type: %s", value)
+}
@@ -15,6 +15,11 @@ type NodeInfo struct {
 	Uptime      uint32    `json:"uptime"`
 	LastSeen    time.Time `json:"lastSeen"`
 	HopCount    uint32    `json:"hopCount"`
+	Online      bool      `json:"online"`
+	Name        string    `json:"name,omitempty"`
+	Zone        string    `json:"zone,omitempty"`
+
+	Firmware *NodeFirmware `json:"firmware,omitempty"` // Set once the node has answered a node info request
 }
 
 // NodeRegistry manages the state of all known mesh nodes
@@ -30,8 +35,15 @@ func NewNodeRegistry() *NodeRegistry {
 	}
 }
 
-// UpdateNode updates or creates a node entry from a health report
-func (nr *NodeRegistry) UpdateNode(mac []byte, adapterType int32, uptime uint32, hopCount uint32) {
+// UpdateNode updates or creates a node entry from a health report.
+// It reports whether the node is new and whether it was previously offline.
+func (nr *NodeRegistry) UpdateNode(mac []byte, adapterType int32, uptime uint32, hopCount uint32) (firstSeen bool, cameOnline bool) {
+	return nr.UpdateNodeAt(mac, adapterType, uptime, hopCount, time.Now())
+}
+
+// UpdateNodeAt is UpdateNode for a report received at seen. Reports older
+// than the node's last report are ignored, so replays cannot roll state back.
+func (nr *NodeRegistry) UpdateNodeAt(mac []byte, adapterType int32, uptime uint32, hopCount uint32, seen time.Time) (firstSeen bool, cameOnline bool) {
 	nr.mu.Lock()
 	defer nr.mu.Unlock()
 
@@ -47,10 +59,18 @@ func (nr *NodeRegistry) UpdateNode(mac []byte, adapterType int32, uptime uint32,
 		nr.nodes[macStr] = node
 	}
 
+	if exists && seen.Before(node.LastSeen) {
+		return false, false
+	}
+
+	cameOnline = !node.Online
 	node.AdapterType = adapterType
 	node.Uptime = uptime
-	node.LastSeen = time.Now()
+	node.LastSeen = seen
 	node.HopCount = hopCount
+	node.Online = true
+
+	return !exists, cameOnline
 }
 
 // GetNode returns information about a specific node
@@ -110,6 +130,73 @@ func (nr *NodeRegistry) GetOnlineNodes(timeout time.Duration) []*NodeInfo {
 	return nodes
 }
 
+// MarkOffline flags online nodes that have not been seen within timeout as
+// offline and returns copies of the nodes that changed state
+func (nr *NodeRegistry) MarkOffline(timeout time.Duration) []*NodeInfo {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	cutoff := time.Now().Add(-timeout)
+	nodes := make([]*NodeInfo, 0)
+
+	for _, node := range nr.nodes {
+		if node.Online && !node.LastSeen.After(cutoff) {
+			node.Online = false
+			nodeCopy := *node
+			nodeCopy.MAC = make([]byte, len(node.MAC))
+			copy(nodeCopy.MAC, node.MAC)
+			nodes = append(nodes, &nodeCopy)
+		}
+	}
+
+	return nodes
+}
+
+// SetMetadata sets the user-assigned name and zone of a known node
+func (nr *NodeRegistry) SetMetadata(mac []byte, name string, zone string) (*NodeInfo, bool) {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	node, exists := nr.nodes[macToString(mac)]
+	if !exists {
+		return nil, false
+	}
+	node.Name = name
+	node.Zone = zone
+
+	nodeCopy := *node
+	nodeCopy.MAC = make([]byte, len(node.MAC))
+	copy(nodeCopy.MAC, node.MAC)
+	return &nodeCopy, true
+}
+
+// SetFirmware records the firmware a known node reported. Reports older than
+// the node's last one are ignored, so replays cannot roll state back.
+func (nr *NodeRegistry) SetFirmware(mac []byte, firmware NodeFirmware) bool {
+	nr.mu.Lock()
+	defer nr.mu.Unlock()
+
+	node, exists := nr.nodes[macToString(mac)]
+	if !exists {
+		return false
+	}
+	if node.Firmware == nil || !firmware.ReportedAt.Before(node.Firmware.ReportedAt) {
+		node.Firmware = &firmware
+	}
+	return true
+}
+
+// Zone returns the zone of the node with the given MAC string, if any
+func (nr *NodeRegistry) Zone(macStr string) string {
+	nr.mu.RLock()
+	defer nr.mu.RUnlock()
+
+	if node, exists := nr.nodes[macStr]; exists {
+		return node.Zone
+	}
+	return ""
+}
+
 // RemoveNode removes a node from the registry
 func (nr *NodeRegistry) RemoveNode(mac []byte) bool {
 	nr.mu.Lock()
@@ -0,0 +1,307 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// PayloadField is a named range of bytes in a serial command payload. Byte 0
+// holds the opcode, so fields start at offset 1.
+type PayloadField struct {
+	Name   string `json:"name"`
+	Offset int    `json:"offset"`
+	Size   int    `json:"size"`
+}
+
+// OpcodeSpec identifies a serial opcode and describes its payload layout
+type OpcodeSpec struct {
+	Code   byte           `json:"code"`
+	Name   string         `json:"name"`
+	Fields []PayloadField `json:"fields"`
+}
+
+// Opcode is a serial command with a typed payload. Encode writes a value into
+// a zeroed MaxDataLength payload whose byte 0 is already the opcode; Decode
+// reads one back. Handle processes the command when a node sends it. Encode
+// is only needed for commands the server sends and Handle only for commands
+// it receives.
+type Opcode[T any] struct {
+	OpcodeSpec
+	Encode func(payload []byte, value T)
+	Decode func(payload []byte) (T, error)
+	Handle func(ms *MeshServer, msg *MeshMessage, value T, receivedAt time.Time, replayed bool) error
+}
+
+// SerialOpcode is an Opcode of any payload type, as held by an OpcodeRegistry
+type SerialOpcode interface {
+	spec() OpcodeSpec
+	validate() error
+	dispatch(ms *MeshServer, msg *MeshMessage, receivedAt time.Time, replayed bool) error
+}
+
+// Built-in opcodes
+var (
+	ConfigSetOpcode = &Opcode[ConfigSet]{
+		OpcodeSpec: OpcodeSpec{Code: OpConfigSet, Name: "configSet", Fields: []PayloadField{
+			{Name: "target", Offset: 1, Size: MACAddressLength},
+			{Name: "adapterType", Offset: 7, Size: 1},
+		}},
+		Encode: func(payload []byte, value ConfigSet) {
+			copy(payload[1:7], value.Target)
+			payload[7] = byte(value.AdapterType)
+		},
+		Decode: func(payload []byte) (ConfigSet, error) {
+			return ConfigSet{
+				Target:      append([]byte(nil), payload[1:7]...),
+				AdapterType: int32(int8(payload[7])),
+			}, nil
+		},
+	}
+
+	RebootOpcode = &Opcode[NodeTarget]{
+		OpcodeSpec: OpcodeSpec{Code: OpReboot, Name: "reboot", Fields: []PayloadField{
+			{Name: "target", Offset: 1, Size: MACAddressLength},
+		}},
+		Encode: encodeNodeTarget,
+		Decode: decodeNodeTarget,
+	}
+
+	FactoryResetOpcode = &Opcode[NodeTarget]{
+		OpcodeSpec: OpcodeSpec{Code: OpFactoryReset, Name: "factoryReset", Fields: []PayloadField{
+			{Name: "target", Offset: 1, Size: MACAddressLength},
+		}},
+		Encode: encodeNodeTarget,
+		Decode: decodeNodeTarget,
+	}
+
+	HealthRequestOpcode = &Opcode[struct{}]{
+		OpcodeSpec: OpcodeSpec{Code: OpHealthReq, Name: "healthRequest"},
+		Encode:     func([]byte, struct{}) {},
+		Decode:     func([]byte) (struct{}, error) { return struct{}{}, nil },
+	}
+
+	HealthReportOpcode = &Opcode[HealthReport]{
+		OpcodeSpec: OpcodeSpec{Code: OpHealthReport, Name: "healthReport", Fields: []PayloadField{
+			{Name: "adapterType", Offset: 1, Size: 1},
+			{Name: "mac", Offset: 2, Size: MACAddressLength},
+			{Name: "uptime", Offset: 8, Size: 4},
+		}},
+		Encode: func(payload []byte, value HealthReport) {
+			payload[1] = byte(value.AdapterType)
+			copy(payload[2:8], value.MAC)
+			binary.LittleEndian.PutUint32(payload[8:12], value.Uptime)
+		},
+		Decode: func(payload []byte) (HealthReport, error) {
+			return HealthReport{
+				AdapterType: int32(int8(payload[1])), // Convert to signed int8 first, then to int32
+				MAC:         append([]byte(nil), payload[2:8]...),
+				Uptime:      binary.LittleEndian.Uint32(payload[8:12]),
+			}, nil
+		},
+	}
+)
+
+func init() {
+	// Set here rather than in the literal: handling a report can send
+	// messages, and sending refers back to HealthReportOpcode
+	HealthReportOpcode.Handle = func(ms *MeshServer, msg *MeshMessage, report HealthReport, receivedAt time.Time, replayed bool) error {
+		report.HopCount = msg.HopCount
+		report.OriginMAC = msg.OriginMacAddress
+		return ms.handleHealthReport(&report, receivedAt, replayed)
+	}
+}
+
+// ConfigSet is the payload of OpConfigSet
+type ConfigSet struct {
+	Target      []byte
+	AdapterType int32
+}
+
+// NodeTarget is the payload of commands that only name their target node
+type NodeTarget struct {
+	Target []byte
+}
+
+func encodeNodeTarget(payload []byte, value NodeTarget) {
+	copy(payload[1:7], value.Target)
+}
+
+func decodeNodeTarget(payload []byte) (NodeTarget, error) {
+	return NodeTarget{Target: append([]byte(nil), payload[1:7]...)}, nil
+}
+
+// Build creates a serial message carrying value, addressed to target. A nil
+// target leaves the message unaddressed, as for health requests.
+func (op *Opcode[T]) Build(target []byte, value T) (*MeshMessage, error) {
+	if op.Encode == nil {
+		return nil, fmt.Errorf("opcode %s cannot be sent", op.Name)
+	}
+	if target != nil && len(target) != MACAddressLength {
+		return nil, fmt.Errorf("invalid MAC address length: %d, expected %d", len(target), MACAddressLength)
+	}
+
+	payload := make([]byte, MaxDataLength)
+	payload[0] = op.Code
+	op.Encode(payload, value)
+
+	// Bytes outside the declared fields are reserved and must stay zero
+	reserved := make([]bool, MaxDataLength)
+	for i := 1; i < MaxDataLength; i++ {
+		reserved[i] = true
+	}
+	for _, field := range op.Fields {
+		for i := field.Offset; i < field.Offset+field.Size; i++ {
+			reserved[i] = false
+		}
+	}
+	for i, isReserved := range reserved {
+		if isReserved && payload[i] != 0 {
+			return nil, fmt.Errorf("opcode %s wrote reserved payload byte %d", op.Name, i)
+		}
+	}
+
+	return &MeshMessage{
+		MessageType:      MessageTypeAdapterData,
+		DataType:         AdapterTypeSerial,
+		TargetMacAddress: target,
+		Data:             payload,
+	}, nil
+}
+
+// Parse decodes the payload of a serial message carrying this opcode
+func (op *Opcode[T]) Parse(msg *MeshMessage) (T, error) {
+	var value T
+	if msg.DataType != AdapterTypeSerial {
+		return value, fmt.Errorf("message is not a serial message")
+	}
+	if len(msg.Data) == 0 || msg.Data[0] != op.Code {
+		return value, fmt.Errorf("message is not a %s", op.Name)
+	}
+	if length := op.payloadLength(); len(msg.Data) < length {
+		return value, fmt.Errorf("insufficient data length for %s: %d, expected %d", op.Name, len(msg.Data), length)
+	}
+	return op.Decode(msg.Data)
+}
+
+// Matches reports whether a message carries this opcode
+func (op *Opcode[T]) Matches(msg *MeshMessage) bool {
+	return msg.DataType == AdapterTypeSerial && len(msg.Data) >= 1 && msg.Data[0] == op.Code
+}
+
+// payloadLength is the number of bytes the opcode's fields span
+func (op *Opcode[T]) payloadLength() int {
+	length := 1
+	for _, field := range op.Fields {
+		length = max(length, field.Offset+field.Size)
+	}
+	return length
+}
+
+func (op *Opcode[T]) spec() OpcodeSpec {
+	return op.OpcodeSpec
+}
+
+// validate checks the opcode's payload layout fits in MaxDataLength bytes
+// without overlapping fields
+func (op *Opcode[T]) validate() error {
+	if op.Name == "" {
+		return fmt.Errorf("opcode 0x%02x has no name", op.Code)
+	}
+	if op.Decode == nil {
+		return fmt.Errorf("opcode %s has no decoder", op.Name)
+	}
+
+	fields := append([]PayloadField(nil), op.Fields...)
+	sort.Slice(fields, func(i, j int) bool { return fields[i].Offset < fields[j].Offset })
+	names := make(map[string]bool)
+	end := 1
+	for _, field := range fields {
+		switch {
+		case field.Name == "" || names[field.Name]:
+			return fmt.Errorf("opcode %s has a missing or duplicate field name %q", op.Name, field.Name)
+		case field.Size <= 0:
+			return fmt.Errorf("opcode %s field %s has size %d", op.Name, field.Name, field.Size)
+		case field.Offset < end:
+			return fmt.Errorf("opcode %s field %s at byte %d overlaps the opcode or another field", op.Name, field.Name, field.Offset)
+		case field.Offset+field.Size > MaxDataLength:
+			return fmt.Errorf("opcode %s field %s ends at byte %d, past the %d-byte payload", op.Name, field.Name, field.Offset+field.Size, MaxDataLength)
+		}
+		names[field.Name] = true
+		end = field.Offset + field.Size
+	}
+	return nil
+}
+
+// dispatch decodes a received message and passes it to the opcode's handler
+func (op *Opcode[T]) dispatch(ms *MeshServer, msg *MeshMessage, receivedAt time.Time, replayed bool) error {
+	if op.Handle == nil {
+		log.Printf("Ignoring serial opcode %s (0x%02x) from %s", op.Name, op.Code, macToString(msg.OriginMacAddress))
+		return nil
+	}
+	value, err := op.Parse(msg)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %w", op.Name, err)
+	}
+	return op.Handle(ms, msg, value, receivedAt, replayed)
+}
+
+// OpcodeRegistry maps serial opcodes to their payload layouts and handlers
+type OpcodeRegistry struct {
+	mu      sync.RWMutex
+	opcodes map[byte]SerialOpcode
+}
+
+// NewOpcodeRegistry creates a registry holding the built-in opcodes
+func NewOpcodeRegistry() *OpcodeRegistry {
+	r := &OpcodeRegistry{opcodes: make(map[byte]SerialOpcode)}
+	for _, op := range []SerialOpcode{ConfigSetOpcode, RebootOpcode, FactoryResetOpcode, HealthRequestOpcode, HealthReportOpcode, PingOpcode, PongOpcode, NodeInfoRequestOpcode, NodeInfoOpcode, TimeSyncOpcode,
+		OTABeginOpcode, OTAChunkOpcode, OTAStatusOpcode, OTACommitOpcode, OTAAbortOpcode} {
+		if err := r.Register(op); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds an opcode after validating its payload layout
+func (r *OpcodeRegistry) Register(op SerialOpcode) error {
+	if err := op.validate(); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	spec := op.spec()
+	if existing, exists := r.opcodes[spec.Code]; exists {
+		return fmt.Errorf("opcode 0x%02x is already registered as %s", spec.Code, existing.spec().Name)
+	}
+	r.opcodes[spec.Code] = op
+	return nil
+}
+
+// Get returns the opcode registered for code
+func (r *OpcodeRegistry) Get(code byte) (SerialOpcode, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	op, exists := r.opcodes[code]
+	return op, exists
+}
+
+// Specs returns the registered opcodes ordered by code
+func (r *OpcodeRegistry) Specs() []OpcodeSpec {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	specs := make([]OpcodeSpec, 0, len(r.opcodes))
+	for _, op := range r.opcodes {
+		specs = append(specs, op.spec())
+	}
+	sort.Slice(specs, func(i, j int) bool { return specs[i].Code < specs[j].Code })
+	return specs
+}
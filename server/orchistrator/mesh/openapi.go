@@ -0,0 +1,57 @@
+package mesh
+
+import (
+	_ "embed"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gorilla/mux"
+)
+
+// apiPrefix is the versioned base path of every API route
+const apiPrefix = "/api/v1"
+
+// openAPISpec describes every route registered in setupRoutes, with paths
+// relative to apiPrefix
+//
+//go:embed openapi.json
+var openAPISpec []byte
+
+// getOpenAPI serves the OpenAPI 3 document
+func (api *APIServer) getOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(openAPISpec)
+}
+
+// addLegacyRoutes serves every versioned route at its unprefixed path as well,
+// so clients written before apiPrefix keep working. Responses on these
+// aliases carry a Deprecation header pointing at the versioned path.
+func (api *APIServer) addLegacyRoutes() {
+	err := api.v1.Walk(func(route *mux.Route, router *mux.Router, ancestors []*mux.Route) error {
+		template, err := route.GetPathTemplate()
+		if err != nil {
+			return err
+		}
+		methods, err := route.GetMethods()
+		if err != nil {
+			return err
+		}
+		path := strings.TrimPrefix(template, apiPrefix)
+		api.router.Handle(path, deprecated(route.GetHandler())).Methods(methods...).Name(route.GetName())
+		return nil
+	})
+	if err != nil {
+		panic(fmt.Sprintf("invalid API route: %v", err))
+	}
+}
+
+// deprecated marks responses from a legacy path as deprecated
+func deprecated(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", fmt.Sprintf("<%s%s>; rel=\"successor-version\"", apiPrefix, r.URL.Path))
+		next.ServeHTTP(w, r)
+	})
+}
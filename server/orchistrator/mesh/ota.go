@@ -0,0 +1,943 @@
+package mesh
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	otaChunkHeader     = 5                              // Opcode, 24-bit offset and CRC-8
+	otaChunkData       = MaxDataLength - otaChunkHeader // Data bytes in a standard chunk frame
+	maxOTAChunkData    = 200                            // Largest chunk a node can negotiate
+	maxFirmwareSize    = 4 << 20                        // Chunk offsets are 24-bit, ESP32 app partitions far smaller
+	defaultOTAWindow   = 4
+	defaultOTARetries  = 5
+	defaultOTAChunkAck = 2 * time.Second
+	defaultOTAReady    = 10 * time.Second
+	defaultOTACommit   = time.Minute
+)
+
+// Statuses nodes report in OpOTAStatus
+const (
+	OTAStatusReady byte = 0 // Session accepted; maxPayload is the largest payload the node takes
+	OTAStatusAck   byte = 1 // offset is the next byte the node expects
+	OTAStatusNack  byte = 2 // The chunk at offset was missing or failed its CRC
+	OTAStatusDone  byte = 3 // Image verified; the node reboots into it
+	OTAStatusError byte = 4 // The node gave up; code says why
+)
+
+// Node update states
+const (
+	OTANodePending      = "pending"
+	OTANodeTransferring = "transferring"
+	OTANodeVerifying    = "verifying"
+	OTANodeDone         = "done"
+	OTANodeFailed       = "failed"
+	OTANodeSkipped      = "skipped"
+)
+
+// Rollout states
+const (
+	RolloutRunning   = "running"
+	RolloutCompleted = "completed"
+	RolloutHalted    = "halted"
+	RolloutAborted   = "aborted"
+)
+
+var (
+	errOTATimeout         = errors.New("no reply from node")
+	errFirmwareNotFound   = errors.New("firmware not found")
+	errRolloutNotFound    = errors.New("rollout not found")
+	errRolloutRunning     = errors.New("another rollout is running")
+	errRolloutNotRunning  = errors.New("rollout is not running")
+	errOTASessionConflict = errors.New("node is already being updated")
+)
+
+// OTABegin is the payload of OpOTABegin. It opens an update session on the
+// targeted node for an image of size bytes.
+type OTABegin struct {
+	Session uint16
+	Size    uint32
+	CRC32   uint32 // IEEE CRC-32 of the whole image
+}
+
+// OTAChunk is the payload of OpOTAChunk. Standard frames carry 7 data bytes;
+// nodes that advertise a larger maxPayload receive longer frames with the
+// same layout.
+type OTAChunk struct {
+	Offset uint32 // 24-bit
+	CRC8   byte   // CRC-8 of Data
+	Data   []byte
+}
+
+// OTACommit is the payload of OpOTACommit. The node checks the image against
+// CRC32 before booting it.
+type OTACommit struct {
+	Session uint16
+	CRC32   uint32
+}
+
+// OTAAbort is the payload of OpOTAAbort
+type OTAAbort struct {
+	Session uint16
+}
+
+// OTAStatus is the payload of OpOTAStatus, sent by nodes during an update
+type OTAStatus struct {
+	Session    uint16
+	Status     byte
+	Offset     uint32 // 24-bit
+	MaxPayload byte
+	Code       byte // Firmware-specific error code with OTAStatusError
+}
+
+var (
+	OTABeginOpcode = &Opcode[OTABegin]{
+		OpcodeSpec: OpcodeSpec{Code: OpOTABegin, Name: "otaBegin", Fields: []PayloadField{
+			{Name: "session", Offset: 1, Size: 2},
+			{Name: "size", Offset: 3, Size: 4},
+			{Name: "crc32", Offset: 7, Size: 4},
+		}},
+		Encode: func(payload []byte, value OTABegin) {
+			binary.LittleEndian.PutUint16(payload[1:3], value.Session)
+			binary.LittleEndian.PutUint32(payload[3:7], value.Size)
+			binary.LittleEndian.PutUint32(payload[7:11], value.CRC32)
+		},
+		Decode: func(payload []byte) (OTABegin, error) {
+			return OTABegin{
+				Session: binary.LittleEndian.Uint16(payload[1:3]),
+				Size:    binary.LittleEndian.Uint32(payload[3:7]),
+				CRC32:   binary.LittleEndian.Uint32(payload[7:11]),
+			}, nil
+		},
+	}
+
+	OTAChunkOpcode = &Opcode[OTAChunk]{
+		OpcodeSpec: OpcodeSpec{Code: OpOTAChunk, Name: "otaChunk", Fields: []PayloadField{
+			{Name: "offset", Offset: 1, Size: 3},
+			{Name: "crc8", Offset: 4, Size: 1},
+			{Name: "data", Offset: otaChunkHeader, Size: otaChunkData},
+		}},
+		Encode: func(payload []byte, value OTAChunk) {
+			putUint24(payload[1:4], value.Offset)
+			payload[4] = value.CRC8
+			copy(payload[otaChunkHeader:], value.Data)
+		},
+		Decode: func(payload []byte) (OTAChunk, error) {
+			chunk := OTAChunk{
+				Offset: uint24(payload[1:4]),
+				CRC8:   payload[4],
+				Data:   append([]byte(nil), payload[otaChunkHeader:]...),
+			}
+			if crc8(chunk.Data) != chunk.CRC8 {
+				return chunk, fmt.Errorf("chunk at offset %d failed its CRC", chunk.Offset)
+			}
+			return chunk, nil
+		},
+	}
+
+	OTACommitOpcode = &Opcode[OTACommit]{
+		OpcodeSpec: OpcodeSpec{Code: OpOTACommit, Name: "otaCommit", Fields: []PayloadField{
+			{Name: "session", Offset: 1, Size: 2},
+			{Name: "crc32", Offset: 3, Size: 4},
+		}},
+		Encode: func(payload []byte, value OTACommit) {
+			binary.LittleEndian.PutUint16(payload[1:3], value.Session)
+			binary.LittleEndian.PutUint32(payload[3:7], value.CRC32)
+		},
+		Decode: func(payload []byte) (OTACommit, error) {
+			return OTACommit{
+				Session: binary.LittleEndian.Uint16(payload[1:3]),
+				CRC32:   binary.LittleEndian.Uint32(payload[3:7]),
+			}, nil
+		},
+	}
+
+	OTAAbortOpcode = &Opcode[OTAAbort]{
+		OpcodeSpec: OpcodeSpec{Code: OpOTAAbort, Name: "otaAbort", Fields: []PayloadField{
+			{Name: "session", Offset: 1, Size: 2},
+		}},
+		Encode: func(payload []byte, value OTAAbort) {
+			binary.LittleEndian.PutUint16(payload[1:3], value.Session)
+		},
+		Decode: func(payload []byte) (OTAAbort, error) {
+			return OTAAbort{Session: binary.LittleEndian.Uint16(payload[1:3])}, nil
+		},
+	}
+
+	OTAStatusOpcode = &Opcode[OTAStatus]{
+		OpcodeSpec: OpcodeSpec{Code: OpOTAStatus, Name: "otaStatus", Fields: []PayloadField{
+			{Name: "session", Offset: 1, Size: 2},
+			{Name: "status", Offset: 3, Size: 1},
+			{Name: "offset", Offset: 4, Size: 3},
+			{Name: "maxPayload", Offset: 7, Size: 1},
+			{Name: "code", Offset: 8, Size: 1},
+		}},
+		Encode: func(payload []byte, value OTAStatus) {
+			binary.LittleEndian.PutUint16(payload[1:3], value.Session)
+			payload[3] = value.Status
+			putUint24(payload[4:7], value.Offset)
+			payload[7] = value.MaxPayload
+			payload[8] = value.Code
+		},
+		Decode: func(payload []byte) (OTAStatus, error) {
+			return OTAStatus{
+				Session:    binary.LittleEndian.Uint16(payload[1:3]),
+				Status:     payload[3],
+				Offset:     uint24(payload[4:7]),
+				MaxPayload: payload[7],
+				Code:       payload[8],
+			}, nil
+		},
+		Handle: func(ms *MeshServer, msg *MeshMessage, status OTAStatus, receivedAt time.Time, replayed bool) error {
+			if !replayed {
+				ms.ota.deliver(macToString(msg.OriginMacAddress), status)
+			}
+			return nil
+		},
+	}
+)
+
+// buildOTAChunk creates a chunk frame. The final chunk of an image is padded
+// with zeros to a standard frame, and the CRC covers the padding; nodes drop
+// it using the size from OTABegin. Chunks longer than a standard frame extend
+// the data field past MaxDataLength for nodes that negotiated it.
+func buildOTAChunk(target []byte, offset uint32, data []byte) (*MeshMessage, error) {
+	if len(data) <= otaChunkData {
+		padded := make([]byte, otaChunkData)
+		copy(padded, data)
+		return OTAChunkOpcode.Build(target, OTAChunk{Offset: offset, CRC8: crc8(padded), Data: padded})
+	}
+	msg, err := OTAChunkOpcode.Build(target, OTAChunk{Offset: offset, CRC8: crc8(data)})
+	if err != nil {
+		return nil, err
+	}
+	msg.Data = append(msg.Data[:otaChunkHeader], data...)
+	return msg, nil
+}
+
+func putUint24(b []byte, v uint32) {
+	b[0], b[1], b[2] = byte(v), byte(v>>8), byte(v>>16)
+}
+
+func uint24(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16
+}
+
+// crc8 is CRC-8 with polynomial 0x07, as used by the node firmware
+func crc8(data []byte) byte {
+	var crc byte
+	for _, b := range data {
+		crc ^= b
+		for i := 0; i < 8; i++ {
+			if crc&0x80 != 0 {
+				crc = crc<<1 ^ 0x07
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// OTAConfig tunes firmware transfers
+type OTAConfig struct {
+	Window        int           // Chunks in flight before waiting for an acknowledgement (default 4)
+	MaxRetries    int           // Consecutive timeouts before a node fails (default 5)
+	ChunkTimeout  time.Duration // Wait for a chunk acknowledgement (default 2s)
+	ReadyTimeout  time.Duration // Wait for a node to accept a session (default 10s)
+	CommitTimeout time.Duration // Wait for a node to verify the image (default 1m)
+}
+
+// Firmware is an uploaded firmware image
+type Firmware struct {
+	ID         string    `json:"id"`
+	Version    string    `json:"version,omitempty"`
+	Size       int       `json:"size"`
+	CRC32      uint32    `json:"crc32"`
+	SHA256     string    `json:"sha256"`
+	UploadedAt time.Time `json:"uploadedAt"`
+
+	image []byte
+}
+
+// OTAStage is a group of nodes updated together, by MAC or zone
+type OTAStage struct {
+	Name  string   `json:"name,omitempty"`
+	MACs  []string `json:"macs,omitempty"`
+	Zones []string `json:"zones,omitempty"`
+}
+
+// OTANodeProgress is the update state of one node in a rollout
+type OTANodeProgress struct {
+	MAC         string     `json:"mac"`
+	Stage       int        `json:"stage"`
+	Status      string     `json:"status"`
+	BytesAcked  int        `json:"bytesAcked"`
+	TotalBytes  int        `json:"totalBytes"`
+	ChunkSize   int        `json:"chunkSize,omitempty"` // Data bytes per frame, once negotiated
+	Retransmits int        `json:"retransmits"`
+	Error       string     `json:"error,omitempty"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	FinishedAt  *time.Time `json:"finishedAt,omitempty"`
+}
+
+// OTARollout updates stages of nodes in order. Once more than maxFailures
+// nodes of a stage fail, the rollout halts and later stages are skipped.
+type OTARollout struct {
+	ID              string             `json:"id"`
+	FirmwareID      string             `json:"firmwareId"`
+	FirmwareVersion string             `json:"firmwareVersion,omitempty"`
+	Stages          []OTAStage         `json:"stages"`
+	MaxFailures     int                `json:"maxFailures"`
+	Status          string             `json:"status"`
+	CurrentStage    int                `json:"currentStage"`
+	Nodes           []*OTANodeProgress `json:"nodes"`
+	Error           string             `json:"error,omitempty"`
+	CreatedAt       time.Time          `json:"createdAt"`
+	FinishedAt      *time.Time         `json:"finishedAt,omitempty"`
+
+	cancel context.CancelFunc
+}
+
+// otaSession routes a node's status reports to its transfer
+type otaSession struct {
+	id       uint16
+	statuses chan OTAStatus
+}
+
+// OTAManager stores firmware images and runs rollouts over the mesh, one at
+// a time so transfers do not compete for the serial link
+type OTAManager struct {
+	meshServer *MeshServer
+	config     OTAConfig
+
+	mu       sync.Mutex
+	firmware map[string]*Firmware
+	rollouts map[string]*OTARollout
+	order    []string // Rollout IDs, oldest first
+	sessions map[string]*otaSession
+	session  uint16
+	running  string // ID of the running rollout
+	wg       sync.WaitGroup
+}
+
+// newOTAManager creates the OTA manager of meshServer
+func newOTAManager(meshServer *MeshServer, config OTAConfig) *OTAManager {
+	if config.Window <= 0 {
+		config.Window = defaultOTAWindow
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = defaultOTARetries
+	}
+	if config.ChunkTimeout <= 0 {
+		config.ChunkTimeout = defaultOTAChunkAck
+	}
+	if config.ReadyTimeout <= 0 {
+		config.ReadyTimeout = defaultOTAReady
+	}
+	if config.CommitTimeout <= 0 {
+		config.CommitTimeout = defaultOTACommit
+	}
+	return &OTAManager{
+		meshServer: meshServer,
+		config:     config,
+		firmware:   make(map[string]*Firmware),
+		rollouts:   make(map[string]*OTARollout),
+		sessions:   make(map[string]*otaSession),
+	}
+}
+
+// AddFirmware stores a firmware image. Uploading the same image again
+// returns the stored one.
+func (m *OTAManager) AddFirmware(image []byte, version string) (Firmware, error) {
+	if len(image) == 0 {
+		return Firmware{}, fmt.Errorf("firmware image is empty")
+	}
+	if len(image) > maxFirmwareSize {
+		return Firmware{}, fmt.Errorf("firmware image is %d bytes, more than the %d byte limit", len(image), maxFirmwareSize)
+	}
+
+	sum := sha256.Sum256(image)
+	fw := &Firmware{
+		ID:         hex.EncodeToString(sum[:6]),
+		Version:    version,
+		Size:       len(image),
+		CRC32:      crc32.ChecksumIEEE(image),
+		SHA256:     hex.EncodeToString(sum[:]),
+		UploadedAt: time.Now().UTC(),
+		image:      image,
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if existing, exists := m.firmware[fw.ID]; exists {
+		return *existing, nil
+	}
+	m.firmware[fw.ID] = fw
+	log.Printf("[OTA] Stored firmware %s (%s, %d bytes)", fw.ID, fw.Version, fw.Size)
+	return *fw, nil
+}
+
+// Firmware returns the stored firmware images, newest first
+func (m *OTAManager) Firmware() []Firmware {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	images := make([]Firmware, 0, len(m.firmware))
+	for _, fw := range m.firmware {
+		images = append(images, *fw)
+	}
+	sort.Slice(images, func(i, j int) bool { return images[i].UploadedAt.After(images[j].UploadedAt) })
+	return images
+}
+
+// StartRollout starts updating the nodes of each stage to a firmware image.
+// Zones are resolved to the nodes in them now, and every node must be known.
+func (m *OTAManager) StartRollout(firmwareID string, stages []OTAStage, maxFailures int) (OTARollout, error) {
+	if len(stages) == 0 {
+		return OTARollout{}, fmt.Errorf("a rollout needs at least one stage")
+	}
+	if maxFailures < 0 {
+		return OTARollout{}, fmt.Errorf("maxFailures must not be negative")
+	}
+
+	registry := m.meshServer.GetNodeRegistry()
+	seen := make(map[string]bool)
+	var nodes []*OTANodeProgress
+	for i, stage := range stages {
+		var macs []string
+		for _, value := range stage.MACs {
+			mac, err := StringToMAC(value)
+			if err != nil {
+				return OTARollout{}, fmt.Errorf("stage %d: invalid MAC address %s", i, value)
+			}
+			if _, exists := registry.GetNode(mac); !exists {
+				return OTARollout{}, fmt.Errorf("stage %d: unknown node %s", i, value)
+			}
+			macs = append(macs, macToString(mac))
+		}
+		zones := make(map[string]bool)
+		for _, zone := range stage.Zones {
+			zones[zone] = true
+		}
+		for _, node := range registry.GetAllNodes() {
+			if zones[node.Zone] && node.Zone != "" {
+				macs = append(macs, node.MACString)
+			}
+		}
+		sort.Strings(macs)
+
+		count := 0
+		for _, mac := range macs {
+			if seen[mac] {
+				continue // Listed by MAC and zone, or in an earlier stage
+			}
+			seen[mac] = true
+			nodes = append(nodes, &OTANodeProgress{MAC: mac, Stage: i, Status: OTANodePending})
+			count++
+		}
+		if count == 0 {
+			return OTARollout{}, fmt.Errorf("stage %d has no nodes", i)
+		}
+	}
+
+	id, err := randomHex(4)
+	if err != nil {
+		return OTARollout{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	fw, exists := m.firmware[firmwareID]
+	if !exists {
+		return OTARollout{}, errFirmwareNotFound
+	}
+	if m.running != "" {
+		return OTARollout{}, errRolloutRunning
+	}
+
+	for _, node := range nodes {
+		node.TotalBytes = fw.Size
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	rollout := &OTARollout{
+		ID:              id,
+		FirmwareID:      fw.ID,
+		FirmwareVersion: fw.Version,
+		Stages:          stages,
+		MaxFailures:     maxFailures,
+		Status:          RolloutRunning,
+		Nodes:           nodes,
+		CreatedAt:       time.Now().UTC(),
+		cancel:          cancel,
+	}
+	m.rollouts[id] = rollout
+	m.order = append(m.order, id)
+	m.running = id
+
+	log.Printf("[OTA] Starting rollout %s of firmware %s to %d nodes in %d stages", id, fw.ID, len(nodes), len(stages))
+	m.wg.Add(1)
+	go m.run(ctx, rollout, fw)
+	return rollout.snapshot(), nil
+}
+
+// Rollouts returns every rollout, newest first
+func (m *OTAManager) Rollouts() []OTARollout {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rollouts := make([]OTARollout, 0, len(m.order))
+	for i := len(m.order) - 1; i >= 0; i-- {
+		rollouts = append(rollouts, m.rollouts[m.order[i]].snapshot())
+	}
+	return rollouts
+}
+
+// Rollout returns a rollout with the progress of each node
+func (m *OTAManager) Rollout(id string) (OTARollout, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rollout, exists := m.rollouts[id]
+	if !exists {
+		return OTARollout{}, errRolloutNotFound
+	}
+	return rollout.snapshot(), nil
+}
+
+// AbortRollout stops a running rollout. The node being updated is told to
+// abandon its session.
+func (m *OTAManager) AbortRollout(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	rollout, exists := m.rollouts[id]
+	if !exists {
+		return errRolloutNotFound
+	}
+	if rollout.Status != RolloutRunning {
+		return errRolloutNotRunning
+	}
+	rollout.cancel()
+	return nil
+}
+
+// Close aborts the running rollout and waits for it to stop
+func (m *OTAManager) Close() {
+	m.mu.Lock()
+	for _, rollout := range m.rollouts {
+		if rollout.Status == RolloutRunning {
+			rollout.cancel()
+		}
+	}
+	m.mu.Unlock()
+	m.wg.Wait()
+}
+
+// run updates a rollout's stages in order
+func (m *OTAManager) run(ctx context.Context, rollout *OTARollout, fw *Firmware) {
+	defer m.wg.Done()
+	defer rollout.cancel()
+
+	status, reason := RolloutCompleted, ""
+stages:
+	for stage := range rollout.Stages {
+		m.mu.Lock()
+		rollout.CurrentStage = stage
+		m.mu.Unlock()
+
+		failures := 0
+		for _, node := range rollout.Nodes {
+			if node.Stage != stage {
+				continue
+			}
+			if ctx.Err() != nil {
+				status, reason = RolloutAborted, "aborted"
+				break stages
+			}
+
+			if err := m.update(ctx, node, fw); err != nil {
+				if ctx.Err() != nil {
+					status, reason = RolloutAborted, "aborted"
+					break stages
+				}
+				failures++
+				otaUpdates.WithLabelValues("failed").Inc()
+				log.Printf("[OTA] Rollout %s: %s failed: %v", rollout.ID, node.MAC, err)
+				if failures > rollout.MaxFailures {
+					status = RolloutHalted
+					reason = fmt.Sprintf("stage %d had %d failed nodes, more than the %d allowed", stage, failures, rollout.MaxFailures)
+					break stages
+				}
+				continue
+			}
+			otaUpdates.WithLabelValues("done").Inc()
+		}
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now().UTC()
+	for _, node := range rollout.Nodes {
+		if node.Status == OTANodePending {
+			node.Status = OTANodeSkipped
+		}
+	}
+	rollout.Status = status
+	rollout.Error = reason
+	rollout.FinishedAt = &now
+	m.running = ""
+	log.Printf("[OTA] Rollout %s %s %s", rollout.ID, status, reason)
+}
+
+// update transfers the firmware to one node, recording its progress
+func (m *OTAManager) update(ctx context.Context, node *OTANodeProgress, fw *Firmware) error {
+	m.setProgress(node, func(p *OTANodeProgress) {
+		now := time.Now().UTC()
+		p.Status = OTANodeTransferring
+		p.StartedAt = &now
+	})
+
+	err := m.transfer(ctx, node, fw)
+
+	m.setProgress(node, func(p *OTANodeProgress) {
+		now := time.Now().UTC()
+		p.FinishedAt = &now
+		p.Status = OTANodeDone
+		if err != nil {
+			p.Status = OTANodeFailed
+			p.Error = err.Error()
+		}
+	})
+	return err
+}
+
+// transfer runs one update session: begin, windowed chunks with go-back-N
+// retransmission from the node's acknowledged offset, then commit
+func (m *OTAManager) transfer(ctx context.Context, node *OTANodeProgress, fw *Firmware) error {
+	mac, err := StringToMAC(node.MAC)
+	if err != nil {
+		return err
+	}
+	session, err := m.openSession(node.MAC)
+	if err != nil {
+		return err
+	}
+	defer m.closeSession(node.MAC)
+
+	completed := false
+	defer func() {
+		if !completed {
+			if msg, err := OTAAbortOpcode.Build(mac, OTAAbort{Session: session.id}); err == nil {
+				m.meshServer.SendMessage(msg)
+			}
+		}
+	}()
+
+	// Open the session and learn the node's frame size
+	begin, err := OTABeginOpcode.Build(mac, OTABegin{Session: session.id, Size: uint32(fw.Size), CRC32: fw.CRC32})
+	if err != nil {
+		return err
+	}
+	ready, err := m.request(ctx, session, begin, m.config.ReadyTimeout, OTAStatusReady)
+	if err != nil {
+		return fmt.Errorf("session not accepted: %w", err)
+	}
+	chunkSize := otaChunkData
+	if int(ready.MaxPayload) > MaxDataLength {
+		chunkSize = min(int(ready.MaxPayload)-otaChunkHeader, maxOTAChunkData)
+	}
+	m.setProgress(node, func(p *OTANodeProgress) { p.ChunkSize = chunkSize })
+
+	// Send chunks, keeping up to Window in flight. rewound is the offset last
+	// resent from, so the Nacks of every chunk after a lost one only cause a
+	// single resend.
+	acked, next, sent, timeouts, rewound := 0, 0, 0, 0, -1
+	for acked < fw.Size {
+		for next < fw.Size && next-acked < m.config.Window*chunkSize {
+			end := min(next+chunkSize, fw.Size)
+			msg, err := buildOTAChunk(mac, uint32(next), fw.image[next:end])
+			if err != nil {
+				return err
+			}
+			if err := m.meshServer.sendFrame(msg); err != nil {
+				return err
+			}
+			if next < sent {
+				otaChunks.WithLabelValues("retransmitted").Inc()
+				m.setProgress(node, func(p *OTANodeProgress) { p.Retransmits++ })
+			} else {
+				otaChunks.WithLabelValues("sent").Inc()
+			}
+			next = end
+			sent = max(sent, next)
+		}
+
+		status, err := m.await(ctx, session, m.config.ChunkTimeout)
+		if errors.Is(err, errOTATimeout) {
+			timeouts++
+			if timeouts > m.config.MaxRetries {
+				return fmt.Errorf("no acknowledgement at offset %d after %d retries", acked, m.config.MaxRetries)
+			}
+			next, rewound = acked, acked // Go back and resend everything unacknowledged
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		timeouts = 0
+
+		switch status.Status {
+		case OTAStatusAck, OTAStatusNack:
+			if offset := min(int(status.Offset), fw.Size); offset > acked {
+				acked = offset
+				m.setProgress(node, func(p *OTANodeProgress) { p.BytesAcked = acked })
+			}
+			if status.Status == OTAStatusNack && rewound != acked {
+				next, rewound = acked, acked
+			}
+			next = max(next, acked)
+		case OTAStatusError:
+			return fmt.Errorf("node aborted the update (code %d)", status.Code)
+		}
+	}
+
+	// Have the node verify and boot the image
+	m.setProgress(node, func(p *OTANodeProgress) { p.Status = OTANodeVerifying })
+	commit, err := OTACommitOpcode.Build(mac, OTACommit{Session: session.id, CRC32: fw.CRC32})
+	if err != nil {
+		return err
+	}
+	if _, err := m.request(ctx, session, commit, m.config.CommitTimeout, OTAStatusDone); err != nil {
+		return fmt.Errorf("image not verified: %w", err)
+	}
+	completed = true
+	return nil
+}
+
+// request sends msg until the node replies with want, an error status or
+// MaxRetries timeouts
+func (m *OTAManager) request(ctx context.Context, session *otaSession, msg *MeshMessage, timeout time.Duration, want byte) (OTAStatus, error) {
+	for attempt := 0; ; attempt++ {
+		if err := m.meshServer.SendMessage(msg); err != nil {
+			return OTAStatus{}, err
+		}
+		for {
+			status, err := m.await(ctx, session, timeout)
+			if errors.Is(err, errOTATimeout) && attempt < m.config.MaxRetries {
+				break
+			}
+			if err != nil {
+				return OTAStatus{}, err
+			}
+			switch status.Status {
+			case want:
+				return status, nil
+			case OTAStatusError:
+				return OTAStatus{}, fmt.Errorf("node reported error code %d", status.Code)
+			}
+			// Late acknowledgements of earlier frames
+		}
+	}
+}
+
+// await waits for the node's next status report in session
+func (m *OTAManager) await(ctx context.Context, session *otaSession, timeout time.Duration) (OTAStatus, error) {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return OTAStatus{}, ctx.Err()
+		case <-timer.C:
+			return OTAStatus{}, errOTATimeout
+		case status := <-session.statuses:
+			if status.Session == session.id {
+				return status, nil
+			}
+		}
+	}
+}
+
+// openSession allocates a session ID for a transfer to mac
+func (m *OTAManager) openSession(mac string) (*otaSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, exists := m.sessions[mac]; exists {
+		return nil, errOTASessionConflict
+	}
+	m.session++
+	session := &otaSession{id: m.session, statuses: make(chan OTAStatus, 64)}
+	m.sessions[mac] = session
+	return session, nil
+}
+
+func (m *OTAManager) closeSession(mac string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.sessions, mac)
+}
+
+// deliver passes a node's status report to its transfer
+func (m *OTAManager) deliver(mac string, status OTAStatus) {
+	m.mu.Lock()
+	session, exists := m.sessions[mac]
+	m.mu.Unlock()
+
+	if !exists {
+		log.Printf("[OTA] Ignoring status %d from %s without an active update", status.Status, mac)
+		return
+	}
+	select {
+	case session.statuses <- status:
+	default:
+		log.Printf("[OTA] Dropping status from %s, transfer is not keeping up", mac)
+	}
+}
+
+// setProgress updates a node's progress under the manager's lock
+func (m *OTAManager) setProgress(node *OTANodeProgress, update func(*OTANodeProgress)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	update(node)
+}
+
+// snapshot copies a rollout for callers outside the manager's lock
+func (r *OTARollout) snapshot() OTARollout {
+	copied := *r
+	copied.cancel = nil
+	copied.Nodes = make([]*OTANodeProgress, len(r.Nodes))
+	for i, node := range r.Nodes {
+		progress := *node
+		copied.Nodes[i] = &progress
+	}
+	return copied
+}
+
+// GetOTA returns the firmware update manager
+func (ms *MeshServer) GetOTA() *OTAManager {
+	return ms.ota
+}
+
+type CreateRolloutRequest struct {
+	FirmwareID  string     `json:"firmwareId"`
+	Stages      []OTAStage `json:"stages"`
+	MaxFailures int        `json:"maxFailures"`
+}
+
+// uploadFirmware stores the firmware image in the request body
+func (api *APIServer) uploadFirmware(w http.ResponseWriter, r *http.Request) {
+	image, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxFirmwareSize+1))
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Failed to read firmware image: %v", err))
+		return
+	}
+
+	fw, err := api.meshServer.GetOTA().AddFirmware(image, r.URL.Query().Get("version"))
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	api.writeJSON(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Data:    fw,
+	})
+}
+
+// listFirmware returns the stored firmware images
+func (api *APIServer) listFirmware(w http.ResponseWriter, r *http.Request) {
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    api.meshServer.GetOTA().Firmware(),
+	})
+}
+
+// createRollout starts a staged firmware rollout
+func (api *APIServer) createRollout(w http.ResponseWriter, r *http.Request) {
+	var req CreateRolloutRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	rollout, err := api.meshServer.GetOTA().StartRollout(req.FirmwareID, req.Stages, req.MaxFailures)
+	switch {
+	case errors.Is(err, errFirmwareNotFound):
+		api.writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, errRolloutRunning):
+		api.writeError(w, http.StatusConflict, err.Error())
+		return
+	case err != nil:
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	api.writeJSON(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Data:    rollout,
+	})
+}
+
+// listRollouts returns every rollout, newest first
+func (api *APIServer) listRollouts(w http.ResponseWriter, r *http.Request) {
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    api.meshServer.GetOTA().Rollouts(),
+	})
+}
+
+// getRollout returns a rollout with per-node progress
+func (api *APIServer) getRollout(w http.ResponseWriter, r *http.Request) {
+	rollout, err := api.meshServer.GetOTA().Rollout(mux.Vars(r)["id"])
+	if err != nil {
+		api.writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    rollout,
+	})
+}
+
+// abortRollout stops a running rollout
+func (api *APIServer) abortRollout(w http.ResponseWriter, r *http.Request) {
+	err := api.meshServer.GetOTA().AbortRollout(mux.Vars(r)["id"])
+	switch {
+	case errors.Is(err, errRolloutNotFound):
+		api.writeError(w, http.StatusNotFound, err.Error())
+		return
+	case errors.Is(err, errRolloutNotRunning):
+		api.writeError(w, http.StatusConflict, err.Error())
+		return
+	}
+
+	api.writeJSON(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Message: "Rollout is stopping",
+	})
+}
@@ -0,0 +1,353 @@
+package mesh
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+)
+
+const (
+	defaultPingCount    = 4
+	maxPingCount        = 10
+	defaultPingTimeout  = 2 * time.Second
+	defaultPingInterval = 200 * time.Millisecond
+	latencyAverageCount = 8 // Replies in the moving average of NodeLatency
+)
+
+// ErrPingTimeout is returned when a node does not answer a ping in time
+var ErrPingTimeout = errors.New("ping timed out")
+
+// Echo is the payload of OpPing and OpPong. Nodes reply to a ping with a
+// pong carrying the same sequence number and timestamp.
+type Echo struct {
+	Sequence  uint16
+	Timestamp uint32 // Low 32 bits of the send time in Unix milliseconds
+}
+
+var (
+	PingOpcode = &Opcode[Echo]{
+		OpcodeSpec: OpcodeSpec{Code: OpPing, Name: "ping", Fields: echoFields},
+		Encode:     encodeEcho,
+		Decode:     decodeEcho,
+	}
+
+	PongOpcode = &Opcode[Echo]{
+		OpcodeSpec: OpcodeSpec{Code: OpPong, Name: "pong", Fields: echoFields},
+		Encode:     encodeEcho,
+		Decode:     decodeEcho,
+		Handle: func(ms *MeshServer, msg *MeshMessage, echo Echo, receivedAt time.Time, replayed bool) error {
+			if !replayed {
+				ms.pings.resolve(macToString(msg.OriginMacAddress), echo, receivedAt)
+			}
+			return nil
+		},
+	}
+
+	echoFields = []PayloadField{
+		{Name: "sequence", Offset: 1, Size: 2},
+		{Name: "timestamp", Offset: 3, Size: 4},
+	}
+)
+
+func encodeEcho(payload []byte, echo Echo) {
+	binary.LittleEndian.PutUint16(payload[1:3], echo.Sequence)
+	binary.LittleEndian.PutUint32(payload[3:7], echo.Timestamp)
+}
+
+func decodeEcho(payload []byte) (Echo, error) {
+	return Echo{
+		Sequence:  binary.LittleEndian.Uint16(payload[1:3]),
+		Timestamp: binary.LittleEndian.Uint32(payload[3:7]),
+	}, nil
+}
+
+// PingStats summarises a series of pings to one node
+type PingStats struct {
+	MAC      string    `json:"mac"`
+	Sent     int       `json:"sent"`
+	Received int       `json:"received"`
+	Loss     float64   `json:"loss"` // Fraction of pings without a reply
+	MinMs    float64   `json:"minMs"`
+	AvgMs    float64   `json:"avgMs"`
+	MaxMs    float64   `json:"maxMs"`
+	RTTsMs   []float64 `json:"rttsMs"` // Round-trip times of the replies, in order
+}
+
+// NodeLatency is the round-trip time of a node as measured by recent pings
+type NodeLatency struct {
+	LastRTTMs  float64   `json:"lastRttMs"`
+	AvgRTTMs   float64   `json:"avgRttMs"` // Moving average of recent replies
+	Sent       uint64    `json:"sent"`
+	Lost       uint64    `json:"lost"`
+	LastPingAt time.Time `json:"lastPingAt"`
+}
+
+// pendingPing is a ping waiting for its pong
+type pendingPing struct {
+	mac    string
+	echo   Echo
+	sentAt time.Time
+	reply  chan time.Time
+}
+
+// pingTracker correlates pongs with pings and keeps each node's latency
+type pingTracker struct {
+	mu       sync.Mutex
+	sequence uint16
+	pending  map[uint16]*pendingPing
+	latency  map[string]*NodeLatency
+}
+
+func newPingTracker() *pingTracker {
+	return &pingTracker{
+		pending: make(map[uint16]*pendingPing),
+		latency: make(map[string]*NodeLatency),
+	}
+}
+
+// begin allocates a sequence number for a ping to mac
+func (t *pingTracker) begin(mac string, now time.Time) *pendingPing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.sequence++
+	for t.pending[t.sequence] != nil { // Skip sequences still in flight after wrapping
+		t.sequence++
+	}
+	ping := &pendingPing{
+		mac:    mac,
+		echo:   Echo{Sequence: t.sequence, Timestamp: uint32(now.UnixMilli())},
+		sentAt: now,
+		reply:  make(chan time.Time, 1),
+	}
+	t.pending[ping.echo.Sequence] = ping
+	return ping
+}
+
+// cancel stops waiting for a ping without recording an outcome
+func (t *pingTracker) cancel(ping *pendingPing) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, ping.echo.Sequence)
+}
+
+// end stops waiting for a ping and records its outcome
+func (t *pingTracker) end(ping *pendingPing, rtt time.Duration, lost bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	delete(t.pending, ping.echo.Sequence)
+
+	latency, exists := t.latency[ping.mac]
+	if !exists {
+		latency = &NodeLatency{}
+		t.latency[ping.mac] = latency
+	}
+	latency.Sent++
+	latency.LastPingAt = ping.sentAt
+	if lost {
+		latency.Lost++
+		return
+	}
+
+	rttMs := float64(rtt) / float64(time.Millisecond)
+	latency.LastRTTMs = rttMs
+	if latency.AvgRTTMs == 0 {
+		latency.AvgRTTMs = rttMs
+	} else {
+		latency.AvgRTTMs += (rttMs - latency.AvgRTTMs) / latencyAverageCount
+	}
+}
+
+// resolve delivers a pong to the ping it answers. Pongs from another node or
+// with a different timestamp belong to an earlier ping with the same sequence
+// number and are dropped.
+func (t *pingTracker) resolve(mac string, echo Echo, receivedAt time.Time) {
+	t.mu.Lock()
+	ping, exists := t.pending[echo.Sequence]
+	t.mu.Unlock()
+
+	if !exists || ping.mac != mac || ping.echo.Timestamp != echo.Timestamp {
+		log.Printf("[PING] Dropping unmatched pong %d from %s", echo.Sequence, mac)
+		return
+	}
+	select {
+	case ping.reply <- receivedAt:
+	default: // Duplicate pong
+	}
+}
+
+// get returns a node's latency
+func (t *pingTracker) get(mac string) (NodeLatency, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	latency, exists := t.latency[mac]
+	if !exists {
+		return NodeLatency{}, false
+	}
+	return *latency, true
+}
+
+// Ping sends an echo request to a node and waits for the reply until ctx is
+// done, returning the round-trip time. An unanswered ping returns
+// ErrPingTimeout.
+func (ms *MeshServer) Ping(ctx context.Context, mac []byte) (time.Duration, error) {
+	ping := ms.pings.begin(macToString(mac), time.Now())
+
+	msg, err := PingOpcode.Build(mac, ping.echo)
+	if err != nil {
+		ms.pings.cancel(ping)
+		return 0, fmt.Errorf("failed to build ping: %w", err)
+	}
+	if err := ms.SendMessage(msg); err != nil {
+		ms.pings.cancel(ping)
+		return 0, err
+	}
+
+	select {
+	case receivedAt := <-ping.reply:
+		rtt := receivedAt.Sub(ping.sentAt)
+		ms.pings.end(ping, rtt, false)
+		pingRTT.Observe(rtt.Seconds())
+		return rtt, nil
+	case <-ctx.Done():
+		if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			ms.pings.cancel(ping)
+			return 0, ctx.Err()
+		}
+		ms.pings.end(ping, 0, true)
+		pingsLost.Inc()
+		return 0, ErrPingTimeout
+	}
+}
+
+// PingN pings a node count times, interval apart, allowing each ping
+// timeout for its reply
+func (ms *MeshServer) PingN(ctx context.Context, mac []byte, count int, interval, timeout time.Duration) (PingStats, error) {
+	stats := PingStats{MAC: macToString(mac), RTTsMs: []float64{}}
+	var total float64
+
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			select {
+			case <-ctx.Done():
+				return stats.summarise(total), ctx.Err()
+			case <-time.After(interval):
+			}
+		}
+
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		rtt, err := ms.Ping(pingCtx, mac)
+		cancel()
+		if errors.Is(err, ErrPingTimeout) {
+			stats.Sent++
+			continue
+		}
+		if err != nil {
+			return stats.summarise(total), err
+		}
+		stats.Sent++
+
+		rttMs := float64(rtt) / float64(time.Millisecond)
+		stats.RTTsMs = append(stats.RTTsMs, rttMs)
+		total += rttMs
+		if stats.Received == 0 || rttMs < stats.MinMs {
+			stats.MinMs = rttMs
+		}
+		stats.MaxMs = max(stats.MaxMs, rttMs)
+		stats.Received++
+	}
+	return stats.summarise(total), nil
+}
+
+// summarise fills in the average and loss from the replies received
+func (s PingStats) summarise(total float64) PingStats {
+	if s.Received > 0 {
+		s.AvgMs = total / float64(s.Received)
+	}
+	if s.Sent > 0 {
+		s.Loss = float64(s.Sent-s.Received) / float64(s.Sent)
+	}
+	return s
+}
+
+// GetLatency returns a node's round-trip time as measured by recent pings
+func (ms *MeshServer) GetLatency(mac string) (NodeLatency, bool) {
+	return ms.pings.get(mac)
+}
+
+// latencyProbe pings every online node once per ping interval, stopping
+// between pings once ctx is cancelled
+func (ms *MeshServer) latencyProbe() {
+	defer ms.wg.Done()
+
+	ticker := time.NewTicker(ms.pingInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ms.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, node := range ms.nodeRegistry.GetOnlineNodes(ms.healthTimeout) {
+				if ms.ctx.Err() != nil {
+					return
+				}
+				ctx, cancel := context.WithTimeout(ms.ctx, defaultPingTimeout)
+				_, err := ms.Ping(ctx, node.MAC)
+				cancel()
+				if err != nil && !errors.Is(err, ErrPingTimeout) {
+					if ms.ctx.Err() == nil {
+						log.Printf("[PING] Latency probe of %s failed: %v", node.MACString, err)
+					}
+					break
+				}
+			}
+		}
+	}
+}
+
+// pingNode pings a node ?count= times (default 4, at most 10) and returns the
+// round-trip statistics
+func (api *APIServer) pingNode(w http.ResponseWriter, r *http.Request) {
+	mac, err := StringToMAC(mux.Vars(r)["mac"])
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid MAC address: %v", err))
+		return
+	}
+	if _, exists := api.meshServer.GetNodeRegistry().GetNode(mac); !exists {
+		api.writeError(w, http.StatusNotFound, "Node not found")
+		return
+	}
+
+	count := defaultPingCount
+	if value := r.URL.Query().Get("count"); value != "" {
+		count, err = strconv.Atoi(value)
+		if err != nil || count < 1 || count > maxPingCount {
+			api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid count (1-%d): %s", maxPingCount, value))
+			return
+		}
+	}
+
+	stats, err := api.meshServer.PingN(r.Context(), mac, count, defaultPingInterval, defaultPingTimeout)
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to ping node: %v", err))
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("%d of %d pings answered", stats.Received, stats.Sent),
+		Data:    stats,
+	})
+}
@@ -0,0 +1,133 @@
+package mesh
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	EventStore "github.com/superbrobenji/motionServer/eventStore"
+	"github.com/superbrobenji/motionServer/events"
+)
+
+// ErrReplayInProgress is returned when a replay is requested while another runs
+var ErrReplayInProgress = errors.New("replay already in progress")
+
+// ReplayResult summarizes a replay of the mesh-messages log
+type ReplayResult struct {
+	From     EventStore.ReplayFrom `json:"from"`
+	Applied  int                   `json:"applied"`
+	Skipped  int                   `json:"skipped"`
+	Failed   int                   `json:"failed"`
+	Nodes    int                   `json:"nodes"`
+	Duration string                `json:"duration"`
+}
+
+// Replay rebuilds the node registry, topology and motion history from the
+// incoming frames logged to mesh-messages. Replayed frames publish no events,
+// and state never moves back to an older frame than it already holds.
+func (ms *MeshServer) Replay(ctx context.Context, from EventStore.ReplayFrom) (*ReplayResult, error) {
+	if ms.eventStore == nil {
+		return nil, fmt.Errorf("event store not configured")
+	}
+	replayer, ok := EventStore.FindReplayer(ms.eventStore)
+	if !ok {
+		return nil, fmt.Errorf("event store does not support replay")
+	}
+
+	if !ms.replayMu.TryLock() {
+		return nil, ErrReplayInProgress
+	}
+	defer ms.replayMu.Unlock()
+
+	ms.topology.beginReplay()
+	defer ms.topology.endReplay()
+
+	start := time.Now()
+	result := &ReplayResult{From: from}
+
+	err := replayer.Replay(ctx, events.TopicMeshMessages, from, func(ctx context.Context, msg EventStore.Message) error {
+		envelope, event, err := events.Decode(msg.Value, msg.ContentType)
+		if err != nil {
+			// Entries written before events were enveloped cannot be replayed
+			result.Skipped++
+			return nil
+		}
+
+		logged, ok := event.(*events.MeshMessageEvent)
+		receivedAt := envelope.Time.Time()
+		if !ok || logged.Direction != "incoming" || receivedAt.Before(from.Time) {
+			result.Skipped++
+			return nil
+		}
+
+		frame, err := meshMessageFromEvent(logged)
+		if err == nil {
+			err = ms.processMessage(frame, receivedAt, true)
+		}
+		if err != nil {
+			log.Printf("[REPLAY] Failed to apply %s: %v", envelope.ID, err)
+			result.Failed++
+			return nil
+		}
+
+		result.Applied++
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to replay %s: %w", events.TopicMeshMessages, err)
+	}
+
+	// Nodes that have not reported recently are restored as offline without
+	// publishing lifecycle events, which were already logged the first time
+	ms.nodeRegistry.MarkOffline(ms.healthTimeout)
+
+	result.Nodes = ms.nodeRegistry.NodeCount()
+	result.Duration = time.Since(start).String()
+	log.Printf("[REPLAY] Applied %d frames (%d skipped, %d failed), %d nodes known",
+		result.Applied, result.Skipped, result.Failed, result.Nodes)
+
+	return result, nil
+}
+
+// meshMessageFromEvent reconstructs a frame from its logged representation
+func meshMessageFromEvent(event *events.MeshMessageEvent) (*MeshMessage, error) {
+	data, err := hex.DecodeString(event.Data)
+	if err != nil {
+		return nil, fmt.Errorf("invalid frame data: %w", err)
+	}
+
+	msg := &MeshMessage{
+		MessageType: event.MessageType,
+		DataType:    event.DataType,
+		HopCount:    event.HopCount,
+		Data:        data,
+	}
+	if msg.OriginMacAddress, err = loggedMAC(event.Origin); err != nil {
+		return nil, err
+	}
+	if msg.TargetMacAddress, err = loggedMAC(event.Target); err != nil {
+		return nil, err
+	}
+	if msg.LastHopMacAddress, err = loggedMAC(event.LastHop); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// loggedMAC reverses macToString, which hex encodes addresses of unusual length
+func loggedMAC(macStr string) ([]byte, error) {
+	if macStr == "" {
+		return nil, nil
+	}
+	if mac, err := StringToMAC(macStr); err == nil {
+		return mac, nil
+	}
+	mac, err := hex.DecodeString(macStr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid MAC address format: %s", macStr)
+	}
+	return mac, nil
+}
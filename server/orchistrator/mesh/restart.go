@@ -0,0 +1,372 @@
+package mesh
+
+import (
+	"bytes"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/superbrobenji/motionServer/auth"
+)
+
+const (
+	defaultRestartTimeout = 2 * time.Minute
+	confirmationTTL       = time.Minute
+	uptimeSlack           = 2 * time.Second // Uptime is reported in whole seconds
+)
+
+// Restart actions
+const (
+	RestartReboot       = "reboot"
+	RestartFactoryReset = "factoryReset"
+)
+
+// Restart statuses
+const (
+	RestartPending   = "pending"
+	RestartConfirmed = "confirmed"
+	RestartTimedOut  = "timedOut"
+)
+
+// NodeRestart tracks a reboot or factory reset until the node reports back
+// with an uptime that started after the command was sent
+type NodeRestart struct {
+	ID             string     `json:"id"`
+	MAC            string     `json:"mac"`
+	Action         string     `json:"action"`
+	Status         string     `json:"status"`
+	RequestedAt    time.Time  `json:"requestedAt"`
+	Deadline       time.Time  `json:"deadline"`
+	PreviousUptime uint32     `json:"previousUptime"`
+	ConfirmedAt    *time.Time `json:"confirmedAt,omitempty"`
+	Uptime         *uint32    `json:"uptime,omitempty"` // First uptime reported after the restart
+
+	previousSeen time.Time
+}
+
+// RestartTracker holds the latest restart of each node
+type RestartTracker struct {
+	mu       sync.Mutex
+	restarts map[string]*NodeRestart
+	timeout  time.Duration
+}
+
+// NewRestartTracker creates a tracker that gives nodes timeout to come back
+func NewRestartTracker(timeout time.Duration) *RestartTracker {
+	if timeout <= 0 {
+		timeout = defaultRestartTimeout
+	}
+	return &RestartTracker{
+		restarts: make(map[string]*NodeRestart),
+		timeout:  timeout,
+	}
+}
+
+// Begin starts tracking a restart of node, replacing any earlier one
+func (t *RestartTracker) Begin(node *NodeInfo, action string, now time.Time) (NodeRestart, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return NodeRestart{}, err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	restart := &NodeRestart{
+		ID:             id,
+		MAC:            node.MACString,
+		Action:         action,
+		Status:         RestartPending,
+		RequestedAt:    now,
+		Deadline:       now.Add(t.timeout),
+		PreviousUptime: node.Uptime,
+		previousSeen:   node.LastSeen,
+	}
+	t.restarts[node.MACString] = restart
+	return *restart, nil
+}
+
+// Observe checks a health report against a pending restart of its node. The
+// restart is confirmed if the uptime is no longer than the time since the
+// command and clearly shorter than it would be had the node kept running.
+func (t *RestartTracker) Observe(mac string, uptime uint32, receivedAt time.Time) (NodeRestart, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	restart, exists := t.restarts[mac]
+	if !exists || restart.Status != RestartPending {
+		return NodeRestart{}, false
+	}
+	if receivedAt.After(restart.Deadline) {
+		restart.Status = RestartTimedOut
+		return NodeRestart{}, false
+	}
+
+	reported := time.Duration(uptime) * time.Second
+	sinceCommand := receivedAt.Sub(restart.RequestedAt)
+	withoutRestart := time.Duration(restart.PreviousUptime)*time.Second + receivedAt.Sub(restart.previousSeen)
+	if reported > sinceCommand+uptimeSlack || reported >= withoutRestart-uptimeSlack {
+		return NodeRestart{}, false
+	}
+
+	restart.Status = RestartConfirmed
+	restart.ConfirmedAt = &receivedAt
+	restart.Uptime = &uptime
+	return *restart, true
+}
+
+// Get returns the latest restart of a node
+func (t *RestartTracker) Get(mac string, now time.Time) (NodeRestart, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	restart, exists := t.restarts[mac]
+	if !exists {
+		return NodeRestart{}, false
+	}
+	if restart.Status == RestartPending && now.After(restart.Deadline) {
+		restart.Status = RestartTimedOut
+	}
+	return *restart, true
+}
+
+// RebootNode reboots a node, or every known node when targetMAC is
+// BroadcastMAC, and tracks each one until it reports back
+func (ms *MeshServer) RebootNode(targetMAC []byte) ([]NodeRestart, error) {
+	msg, err := ms.messageBuilder.BuildRebootMessage(targetMAC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build reboot message: %w", err)
+	}
+	return ms.restartNodes(targetMAC, RestartReboot, msg)
+}
+
+// FactoryResetNode erases a node's stored configuration and reboots it, or
+// every known node when targetMAC is BroadcastMAC
+func (ms *MeshServer) FactoryResetNode(targetMAC []byte) ([]NodeRestart, error) {
+	msg, err := ms.messageBuilder.BuildFactoryResetMessage(targetMAC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build factory reset message: %w", err)
+	}
+	return ms.restartNodes(targetMAC, RestartFactoryReset, msg)
+}
+
+// restartNodes sends a restart command and starts tracking the nodes it
+// reaches
+func (ms *MeshServer) restartNodes(targetMAC []byte, action string, msg *MeshMessage) ([]NodeRestart, error) {
+	var targets []*NodeInfo
+	if bytes.Equal(targetMAC, BroadcastMAC) {
+		targets = ms.nodeRegistry.GetAllNodes()
+	} else {
+		node, exists := ms.nodeRegistry.GetNode(targetMAC)
+		if !exists {
+			return nil, fmt.Errorf("unknown node: %s", macToString(targetMAC))
+		}
+		targets = []*NodeInfo{node}
+	}
+
+	log.Printf("[RESTART] Sending %s to %s", action, macToString(targetMAC))
+	if err := ms.SendMessage(msg); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	restarts := make([]NodeRestart, 0, len(targets))
+	for _, node := range targets {
+		restart, err := ms.restarts.Begin(node, action, now)
+		if err != nil {
+			return nil, fmt.Errorf("%s sent but not tracked: %w", action, err)
+		}
+		restarts = append(restarts, restart)
+	}
+	return restarts, nil
+}
+
+// GetRestart returns the latest reboot or factory reset of a node
+func (ms *MeshServer) GetRestart(mac []byte) (NodeRestart, bool) {
+	return ms.restarts.Get(macToString(mac), time.Now())
+}
+
+// confirmation is a token that allows one destructive action
+type confirmation struct {
+	action  string
+	target  string
+	actor   string
+	expires time.Time
+}
+
+// confirmationStore issues single-use tokens that must accompany a repeat of
+// a destructive request by the same caller
+type confirmationStore struct {
+	mu     sync.Mutex
+	tokens map[string]confirmation
+}
+
+func newConfirmationStore() *confirmationStore {
+	return &confirmationStore{tokens: make(map[string]confirmation)}
+}
+
+// issue returns a token confirming action on target by actor
+func (s *confirmationStore) issue(action, target, actor string, now time.Time) (string, time.Time, error) {
+	token, err := randomHex(16)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for issued, pending := range s.tokens {
+		if now.After(pending.expires) {
+			delete(s.tokens, issued)
+		}
+	}
+
+	expires := now.Add(confirmationTTL)
+	s.tokens[token] = confirmation{action: action, target: target, actor: actor, expires: expires}
+	return token, expires, nil
+}
+
+// consume reports whether token confirms action on target by actor, and
+// invalidates it
+func (s *confirmationStore) consume(token, action, target, actor string, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for issued, pending := range s.tokens {
+		if subtle.ConstantTimeCompare([]byte(issued), []byte(token)) != 1 {
+			continue
+		}
+		delete(s.tokens, issued)
+		return pending.action == action && pending.target == target && pending.actor == actor && !now.After(pending.expires)
+	}
+	return false
+}
+
+// RestartRequest is the body of POST /nodes/{mac}/reset
+type RestartRequest struct {
+	ConfirmationToken string `json:"confirmationToken"`
+}
+
+// ConfirmationRequired is returned when a destructive request must be
+// repeated with a confirmation token
+type ConfirmationRequired struct {
+	ConfirmationToken string    `json:"confirmationToken"`
+	ExpiresAt         time.Time `json:"expiresAt"`
+}
+
+// rebootNode reboots a node, or all nodes for ff:ff:ff:ff:ff:ff
+func (api *APIServer) rebootNode(w http.ResponseWriter, r *http.Request) {
+	mac, ok := api.restartTarget(w, r)
+	if !ok {
+		return
+	}
+
+	restarts, err := api.meshServer.RebootNode(mac)
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to reboot node: %v", err))
+		return
+	}
+
+	api.writeJSON(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Reboot sent to %s; GET /nodes/{mac}/restart shows when each node is back", macToString(mac)),
+		Data:    restarts,
+	})
+}
+
+// resetNode factory resets a node, or all nodes for ff:ff:ff:ff:ff:ff. The
+// first request returns a confirmation token; repeating it with the token
+// within a minute performs the reset.
+func (api *APIServer) resetNode(w http.ResponseWriter, r *http.Request) {
+	mac, ok := api.restartTarget(w, r)
+	if !ok {
+		return
+	}
+
+	var req RestartRequest // The body is optional on the first request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	actor := auth.Anonymous.ID
+	if principal, ok := auth.FromContext(r.Context()); ok {
+		actor = principal.ID
+	}
+	target := macToString(mac)
+	now := time.Now()
+
+	if req.ConfirmationToken == "" {
+		token, expires, err := api.confirmations.issue(RestartFactoryReset, target, actor, now)
+		if err != nil {
+			api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to issue confirmation token: %v", err))
+			return
+		}
+		api.writeJSON(w, http.StatusPreconditionRequired, APIResponse{
+			Success: false,
+			Error:   fmt.Sprintf("Factory reset erases the configuration of %s; repeat the request with the confirmation token", target),
+			Data:    ConfirmationRequired{ConfirmationToken: token, ExpiresAt: expires},
+		})
+		return
+	}
+	if !api.confirmations.consume(req.ConfirmationToken, RestartFactoryReset, target, actor, now) {
+		api.writeError(w, http.StatusBadRequest, "Invalid or expired confirmation token")
+		return
+	}
+
+	restarts, err := api.meshServer.FactoryResetNode(mac)
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to reset node: %v", err))
+		return
+	}
+
+	api.writeJSON(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Factory reset sent to %s; GET /nodes/{mac}/restart shows when each node is back", target),
+		Data:    restarts,
+	})
+}
+
+// getNodeRestart returns the latest reboot or factory reset of a node
+func (api *APIServer) getNodeRestart(w http.ResponseWriter, r *http.Request) {
+	mac, err := StringToMAC(mux.Vars(r)["mac"])
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid MAC address: %v", err))
+		return
+	}
+
+	restart, exists := api.meshServer.GetRestart(mac)
+	if !exists {
+		api.writeError(w, http.StatusNotFound, "Node has not been restarted")
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    restart,
+	})
+}
+
+// restartTarget parses the MAC of a restart request, which must be a known
+// node or the broadcast address
+func (api *APIServer) restartTarget(w http.ResponseWriter, r *http.Request) ([]byte, bool) {
+	mac, err := StringToMAC(mux.Vars(r)["mac"])
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid MAC address: %v", err))
+		return nil, false
+	}
+	if !bytes.Equal(mac, BroadcastMAC) {
+		if _, exists := api.meshServer.GetNodeRegistry().GetNode(mac); !exists {
+			api.writeError(w, http.StatusNotFound, "Node not found")
+			return nil, false
+		}
+	}
+	return mac, true
+}
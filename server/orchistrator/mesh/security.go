@@ -0,0 +1,532 @@
+package mesh
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/superbrobenji/motionServer/atomicfile"
+)
+
+const (
+	authLength          = 13   // Key ID, counter and truncated HMAC
+	authTagLength       = 8    // Bytes of the HMAC-SHA256 kept
+	meshKeyLength       = 32   // Bytes in a generated key
+	replayWindowSize    = 64   // Counters below a node's highest that may still arrive out of order
+	counterReservation  = 1024 // Outgoing counters reserved per write of the key file
+	counterSaveInterval = 64   // Counters accepted from a node per write of the key file
+)
+
+var (
+	// ErrMeshKeyNotFound is returned for unknown key IDs
+	ErrMeshKeyNotFound = errors.New("mesh key not found")
+	// ErrActiveMeshKey is returned when retiring the key frames are signed with
+	ErrActiveMeshKey = errors.New("the active key cannot be retired")
+)
+
+// MeshKey is a key the mesh signs frames with
+type MeshKey struct {
+	ID        byte      `json:"id"`
+	Secret    string    `json:"secret,omitempty"` // Only shown when created
+	Active    bool      `json:"active"`           // Outgoing frames are signed with this key
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SecurityStatus describes the keys and the outgoing counter
+type SecurityStatus struct {
+	ActiveKey byte      `json:"activeKey"`
+	Keys      []MeshKey `json:"keys"`
+	Counter   uint32    `json:"counter"` // Last counter the server signed with
+	Nodes     int       `json:"nodes"`   // Nodes with a signed frame accepted
+}
+
+// meshKeyFile is the JSON layout of the key file
+type meshKeyFile struct {
+	Active   byte              `json:"active"`
+	Keys     []MeshKey         `json:"keys"`
+	Counter  uint32            `json:"counter"`            // Outgoing counters up to this may have been used
+	Counters map[string]uint32 `json:"counters,omitempty"` // Counters up to this may have been accepted, by "<mac>/<key id>"
+}
+
+// counterKey identifies a node's counter sequence. A node provisioned with a
+// new key starts counting again.
+type counterKey struct {
+	mac string
+	key byte
+}
+
+func (k counterKey) String() string {
+	return fmt.Sprintf("%s/%d", k.mac, k.key)
+}
+
+// counterWindow is the highest counter accepted from a node and which of the
+// replayWindowSize counters below it have been seen
+type counterWindow struct {
+	highest  uint32
+	seen     uint64 // Bit n is set if highest-n was accepted
+	reserved uint32 // Counter saved in the key file
+}
+
+// accept records a counter, reporting false for replays and counters too
+// old to tell
+func (w *counterWindow) accept(counter uint32) bool {
+	if counter > w.highest {
+		if shift := counter - w.highest; shift < replayWindowSize {
+			w.seen = w.seen<<shift | 1
+		} else {
+			w.seen = 1
+		}
+		w.highest = counter
+		return true
+	}
+	offset := w.highest - counter
+	if counter == 0 || offset >= replayWindowSize || w.seen&(1<<offset) != 0 {
+		return false
+	}
+	w.seen |= 1 << offset
+	return true
+}
+
+// MeshSecurity signs outgoing frames and verifies signed frames from nodes
+// with per-mesh keys kept in a JSON file. Several keys can be known at once
+// so nodes can be moved to a new key one at a time; the server signs with the
+// active key and accepts any known key.
+type MeshSecurity struct {
+	mu       sync.Mutex
+	path     string
+	keys     map[byte]MeshKey
+	secrets  map[byte][]byte
+	active   byte
+	counter  uint32 // Last outgoing counter used
+	reserved uint32 // Outgoing counter saved in the key file
+	counters map[counterKey]*counterWindow
+}
+
+// OpenMeshSecurity loads the key file at path, creating it with a new key
+// if it does not exist
+func OpenMeshSecurity(path string) (*MeshSecurity, error) {
+	s := &MeshSecurity{
+		path:     path,
+		keys:     make(map[byte]MeshKey),
+		secrets:  make(map[byte][]byte),
+		counters: make(map[counterKey]*counterWindow),
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		key, err := s.generateKey()
+		if err != nil {
+			return nil, err
+		}
+		s.active = key.ID
+		if err := s.save(); err != nil {
+			return nil, err
+		}
+		log.Printf("[SECURITY] Created %s with key %d; provision it to the nodes", path, key.ID)
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read mesh keys: %w", err)
+	}
+
+	var file meshKeyFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("failed to parse mesh keys: %w", err)
+	}
+	for _, key := range file.Keys {
+		secret, err := hex.DecodeString(key.Secret)
+		if err != nil || len(secret) == 0 || key.ID == 0 {
+			return nil, fmt.Errorf("invalid mesh key %d", key.ID)
+		}
+		key.Secret, key.Active = "", false
+		s.keys[key.ID], s.secrets[key.ID] = key, secret
+	}
+	if _, exists := s.keys[file.Active]; !exists {
+		return nil, fmt.Errorf("active mesh key %d not found", file.Active)
+	}
+	s.active = file.Active
+	for name, highest := range file.Counters {
+		mac, id, found := strings.Cut(name, "/")
+		parsed, err := strconv.ParseUint(id, 10, 8)
+		if !found || err != nil {
+			return nil, fmt.Errorf("invalid counter %q in mesh keys", name)
+		}
+		// Counters up to the saved one may have been accepted before a crash
+		s.counters[counterKey{mac: mac, key: byte(parsed)}] = &counterWindow{highest: highest, seen: math.MaxUint64, reserved: highest}
+	}
+
+	// Counters reserved by the last run may have been used before it stopped
+	s.counter, s.reserved = file.Counter, file.Counter
+	return s, nil
+}
+
+// requiresAuth reports whether a frame must be signed in security mode:
+// health reports and PIR data
+func requiresAuth(msg *MeshMessage) bool {
+	if msg.MessageType != MessageTypeAdapterData {
+		return false
+	}
+	return msg.DataType == AdapterTypePIR ||
+		(msg.DataType == AdapterTypeSerial && len(msg.Data) > 0 && msg.Data[0] == OpHealthReport)
+}
+
+// authTag is the truncated HMAC of a frame. signer is the origin MAC for
+// frames from nodes and nil, signed as zeros, for frames from the server.
+func authTag(secret []byte, keyID byte, counter uint32, signer []byte, msg *MeshMessage) []byte {
+	header := make([]byte, 1+4+4+4+2*MACAddressLength)
+	header[0] = keyID
+	binary.LittleEndian.PutUint32(header[1:5], counter)
+	binary.LittleEndian.PutUint32(header[5:9], msg.MessageType)
+	binary.LittleEndian.PutUint32(header[9:13], uint32(msg.DataType))
+	copy(header[13:19], signer)
+	copy(header[19:25], msg.TargetMacAddress)
+
+	mac := hmac.New(sha256.New, secret)
+	mac.Write(header)
+	mac.Write(msg.Data)
+	return mac.Sum(nil)[:authTagLength]
+}
+
+// Sign sets a frame's auth field with the active key and the next counter
+func (s *MeshSecurity) Sign(msg *MeshMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.counter == math.MaxUint32 {
+		return fmt.Errorf("outgoing counter exhausted")
+	}
+	counter := s.counter + 1
+	if counter > s.reserved {
+		previous := s.reserved
+		s.reserved = counter + min(counterReservation-1, math.MaxUint32-counter)
+		if err := s.save(); err != nil {
+			s.reserved = previous
+			return err
+		}
+	}
+	s.counter = counter
+
+	auth := make([]byte, 5, authLength)
+	auth[0] = s.active
+	binary.LittleEndian.PutUint32(auth[1:5], counter)
+	msg.Auth = append(auth, authTag(s.secrets[s.active], s.active, counter, nil, msg)...)
+	return nil
+}
+
+// Verify checks a frame's signature and rejects counters its origin has
+// already used. Every counterSaveInterval counters from a node the key file
+// is rewritten with the next interval reserved, so after a crash counters up
+// to the end of it are rejected.
+func (s *MeshSecurity) Verify(msg *MeshMessage) error {
+	if len(msg.Auth) != authLength {
+		authRejected.WithLabelValues("unsigned").Inc()
+		return fmt.Errorf("frame is not signed")
+	}
+	keyID, counter := msg.Auth[0], binary.LittleEndian.Uint32(msg.Auth[1:5])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secret, exists := s.secrets[keyID]
+	if !exists {
+		authRejected.WithLabelValues("unknown_key").Inc()
+		return fmt.Errorf("frame is signed with unknown key %d", keyID)
+	}
+	if !hmac.Equal(msg.Auth[5:], authTag(secret, keyID, counter, msg.OriginMacAddress, msg)) {
+		authRejected.WithLabelValues("bad_signature").Inc()
+		return fmt.Errorf("frame signature does not match")
+	}
+
+	key := counterKey{mac: macToString(msg.OriginMacAddress), key: keyID}
+	window, exists := s.counters[key]
+	if !exists {
+		window = &counterWindow{}
+		s.counters[key] = window
+	}
+	previous := *window
+	if !window.accept(counter) {
+		authRejected.WithLabelValues("replay").Inc()
+		return fmt.Errorf("counter %d was already used", counter)
+	}
+	if window.highest > window.reserved {
+		window.reserved = window.highest + min(counterSaveInterval-1, math.MaxUint32-window.highest)
+		if err := s.save(); err != nil {
+			if exists {
+				*window = previous
+			} else {
+				delete(s.counters, key)
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Status returns the keys, without their secrets, and the counters
+func (s *MeshSecurity) Status() SecurityStatus {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]MeshKey, 0, len(s.keys))
+	for _, key := range s.keys {
+		key.Active = key.ID == s.active
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].ID < keys[j].ID })
+
+	nodes := make(map[string]bool)
+	for key := range s.counters {
+		nodes[key.mac] = true
+	}
+	return SecurityStatus{ActiveKey: s.active, Keys: keys, Counter: s.counter, Nodes: len(nodes)}
+}
+
+// AddKey generates a key that frames from nodes may be signed with. It is
+// returned with its secret, which is not shown again.
+func (s *MeshSecurity) AddKey() (MeshKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, err := s.generateKey()
+	if err != nil {
+		return MeshKey{}, err
+	}
+	if err := s.save(); err != nil {
+		delete(s.keys, key.ID)
+		delete(s.secrets, key.ID)
+		return MeshKey{}, err
+	}
+	return key, nil
+}
+
+// Activate signs outgoing frames with a key from now on
+func (s *MeshSecurity) Activate(id byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.keys[id]; !exists {
+		return ErrMeshKeyNotFound
+	}
+	previous := s.active
+	s.active = id
+	if err := s.save(); err != nil {
+		s.active = previous
+		return err
+	}
+	return nil
+}
+
+// Retire stops accepting frames signed with a key
+func (s *MeshSecurity) Retire(id byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key, exists := s.keys[id]
+	if !exists {
+		return ErrMeshKeyNotFound
+	}
+	if id == s.active {
+		return ErrActiveMeshKey
+	}
+	secret := s.secrets[id]
+	delete(s.keys, id)
+	delete(s.secrets, id)
+	if err := s.save(); err != nil {
+		s.keys[id], s.secrets[id] = key, secret
+		return err
+	}
+	for counter := range s.counters {
+		if counter.key == id {
+			delete(s.counters, counter)
+		}
+	}
+	return nil
+}
+
+// Close saves the highest counter accepted from each node, so nodes do not
+// lose the counters reserved since the last write
+func (s *MeshSecurity) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, window := range s.counters {
+		window.reserved = window.highest
+	}
+	return s.save()
+}
+
+// generateKey adds a random key with the lowest unused ID; callers hold mu
+// unless s is not shared yet
+func (s *MeshSecurity) generateKey() (MeshKey, error) {
+	id := byte(1)
+	for ; id != 0; id++ {
+		if _, exists := s.keys[id]; !exists {
+			break
+		}
+	}
+	if id == 0 {
+		return MeshKey{}, fmt.Errorf("all 255 key IDs are in use")
+	}
+
+	secret := make([]byte, meshKeyLength)
+	if _, err := rand.Read(secret); err != nil {
+		return MeshKey{}, fmt.Errorf("failed to generate mesh key: %w", err)
+	}
+	key := MeshKey{ID: id, CreatedAt: time.Now().UTC()}
+	s.keys[id], s.secrets[id] = key, secret
+
+	key.Secret = hex.EncodeToString(secret)
+	return key, nil
+}
+
+// save writes the keys and counters to the key file; callers hold mu
+func (s *MeshSecurity) save() error {
+	file := meshKeyFile{Active: s.active, Counter: s.reserved, Counters: make(map[string]uint32, len(s.counters))}
+	for id, key := range s.keys {
+		key.Secret = hex.EncodeToString(s.secrets[id])
+		file.Keys = append(file.Keys, key)
+	}
+	sort.Slice(file.Keys, func(i, j int) bool { return file.Keys[i].ID < file.Keys[j].ID })
+	for key, window := range s.counters {
+		if _, exists := s.keys[key.key]; exists {
+			file.Counters[key.String()] = window.reserved
+		}
+	}
+
+	data, err := json.MarshalIndent(file, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := atomicfile.Write(s.path, data); err != nil {
+		return fmt.Errorf("failed to write mesh keys: %w", err)
+	}
+	return nil
+}
+
+// GetSecurity returns the mesh keys, or nil when security mode is off
+func (ms *MeshServer) GetSecurity() *MeshSecurity {
+	return ms.security
+}
+
+// meshKeyID parses the {id} of a key route
+func (api *APIServer) meshKeyID(w http.ResponseWriter, r *http.Request) (*MeshSecurity, byte, bool) {
+	security, ok := api.security(w)
+	if !ok {
+		return nil, 0, false
+	}
+	id, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 8)
+	if err != nil || id == 0 {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid key ID: %s", mux.Vars(r)["id"]))
+		return nil, 0, false
+	}
+	return security, byte(id), true
+}
+
+// security returns the mesh keys, answering 409 when security mode is off
+func (api *APIServer) security(w http.ResponseWriter) (*MeshSecurity, bool) {
+	security := api.meshServer.GetSecurity()
+	if security == nil {
+		api.writeError(w, http.StatusConflict, "Mesh security is disabled; start the server with -mesh-keys")
+		return nil, false
+	}
+	return security, true
+}
+
+// getSecurity returns the mesh keys and counters
+func (api *APIServer) getSecurity(w http.ResponseWriter, r *http.Request) {
+	security, ok := api.security(w)
+	if !ok {
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    security.Status(),
+	})
+}
+
+// createMeshKey generates a key for nodes to be provisioned with
+func (api *APIServer) createMeshKey(w http.ResponseWriter, r *http.Request) {
+	security, ok := api.security(w)
+	if !ok {
+		return
+	}
+
+	key, err := security.AddKey()
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to create mesh key: %v", err))
+		return
+	}
+
+	log.Printf("[SECURITY] Created mesh key %d", key.ID)
+	api.writeJSON(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Message: "Store the secret now; it cannot be shown again",
+		Data:    key,
+	})
+}
+
+// activateMeshKey signs outgoing frames with a key
+func (api *APIServer) activateMeshKey(w http.ResponseWriter, r *http.Request) {
+	security, id, ok := api.meshKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := security.Activate(id); err != nil {
+		if errors.Is(err, ErrMeshKeyNotFound) {
+			api.writeError(w, http.StatusNotFound, "Mesh key not found")
+			return
+		}
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to activate mesh key: %v", err))
+		return
+	}
+
+	log.Printf("[SECURITY] Signing with mesh key %d", id)
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Signing with key %d", id),
+	})
+}
+
+// retireMeshKey stops accepting frames signed with a key
+func (api *APIServer) retireMeshKey(w http.ResponseWriter, r *http.Request) {
+	security, id, ok := api.meshKeyID(w, r)
+	if !ok {
+		return
+	}
+
+	if err := security.Retire(id); err != nil {
+		switch {
+		case errors.Is(err, ErrMeshKeyNotFound):
+			api.writeError(w, http.StatusNotFound, "Mesh key not found")
+		case errors.Is(err, ErrActiveMeshKey):
+			api.writeError(w, http.StatusConflict, err.Error())
+		default:
+			api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to retire mesh key: %v", err))
+		}
+		return
+	}
+
+	log.Printf("[SECURITY] Retired mesh key %d", id)
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Key %d retired", id),
+	})
+}
@@ -65,6 +65,7 @@ func (s *SerialComm) WriteFrame(msg *MeshMessage) error {
 
 	log.Printf("[SERIAL_TX] Data sent successfully - Total frame size: %d bytes (2-byte header + %d data bytes)", 
 		len(header)+len(data), len(data))
+	framesSent.Inc()
 
 	return nil
 }
@@ -74,6 +75,7 @@ func (s *SerialComm) ReadFrame() (*MeshMessage, error) {
 	// Read 2-byte header
 	header := make([]byte, 2)
 	if _, err := io.ReadFull(s.port, header); err != nil {
+		frameErrors.WithLabelValues(frameErrorRead).Inc()
 		return nil, fmt.Errorf("failed to read header: %w", err)
 	}
 
@@ -83,12 +85,14 @@ func (s *SerialComm) ReadFrame() (*MeshMessage, error) {
 	length := binary.LittleEndian.Uint16(header)
 	if length == 0 {
 		log.Printf("[SERIAL_RX] WARNING: Zero-length frame detected - possible frame sync issue")
+		frameErrors.WithLabelValues(frameErrorZeroLength).Inc()
 		return nil, fmt.Errorf("invalid frame length: 0 (header bytes: %02x %02x)", header[0], header[1])
 	}
 
 	// Enhanced validation with more detailed logging
 	if length > 4096 {
 		log.Printf("[SERIAL_RX] CRITICAL: Frame length too large: %d bytes (header: %02x %02x)", length, header[0], header[1])
+		frameErrors.WithLabelValues(frameErrorTooLarge).Inc()
 		log.Printf("[SERIAL_RX] This indicates frame desynchronization - ESP32 may be sending non-framed data")
 		log.Printf("[SERIAL_RX] Header as ASCII: '%c%c' (if printable)", 
 			func() byte { if header[0] >= 32 && header[0] <= 126 { return header[0] } else { return '.' } }(),
@@ -113,6 +117,7 @@ func (s *SerialComm) ReadFrame() (*MeshMessage, error) {
 	data := make([]byte, length)
 	if _, err := io.ReadFull(s.port, data); err != nil {
 		log.Printf("[SERIAL_RX] Failed to read %d bytes of frame data: %v", length, err)
+		frameErrors.WithLabelValues(frameErrorRead).Inc()
 		return nil, fmt.Errorf("failed to read data: %w", err)
 	}
 
@@ -134,6 +139,7 @@ func (s *SerialComm) ReadFrame() (*MeshMessage, error) {
 	var msg MeshMessage
 	if err := proto.Unmarshal(data, &msg); err != nil {
 		log.Printf("[SERIAL_RX] UNMARSHAL FAILED: %v", err)
+		frameErrors.WithLabelValues(frameErrorUnmarshal).Inc()
 		log.Printf("[SERIAL_RX] Failed protobuf data (%d bytes): %x", len(data), data)
 		log.Printf("[SERIAL_RX] Data as ASCII (if readable): %q", string(data))
 		return nil, fmt.Errorf("failed to unmarshal message: %w", err)
@@ -146,6 +152,7 @@ func (s *SerialComm) ReadFrame() (*MeshMessage, error) {
 		log.Printf("[SERIAL_RX] Message data: %x", msg.Data)
 	}
 
+	framesReceived.Inc()
 	return &msg, nil
 }
 
@@ -157,6 +164,7 @@ func (s *SerialComm) Close() error {
 // FlushBuffer attempts to clear any buffered data from the serial port
 func (s *SerialComm) FlushBuffer() error {
 	log.Printf("[SERIAL_FLUSH] Attempting to flush serial buffer")
+	bufferFlushes.Inc()
 	
 	// Try to read any remaining data with a short timeout
 	buffer := make([]byte, 1024)
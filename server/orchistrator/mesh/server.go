@@ -2,13 +2,15 @@ package mesh
 
 import (
 	"context"
-	"encoding/json"
+	"encoding/hex"
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	EventStore "github.com/superbrobenji/motionServer/eventStore"
+	"github.com/superbrobenji/motionServer/events"
 	"go.bug.st/serial"
 )
 
@@ -16,56 +18,139 @@ import (
 type MeshServer struct {
 	serialComm     *SerialComm
 	nodeRegistry   *NodeRegistry
+	topology       *Topology
+	motionHistory  *MotionHistory
+	eventBus       *EventBus
+	auditLog       *AuditLog
+	webhooks       *WebhookManager
 	messageBuilder *MessageBuilder
+	opcodes        *OpcodeRegistry
+	restarts       *RestartTracker
+	pings          *pingTracker
+	ota            *OTAManager
+	security       *MeshSecurity
+	fragments      *fragmentReassembler
+	replayFragments *fragmentReassembler // Kept apart so replays cannot mix with live fragments
 	eventStore     EventStore.EventStore_interface
+	eventSource    string
+	eventEncodings events.Encodings
 	
 	// Configuration
 	serialPort     string
 	baudRate       int
 	healthTimeout  time.Duration
+	frameWindow    time.Duration
+	pingInterval   time.Duration
+	timeSyncInterval time.Duration
 	
 	// Runtime state
 	ctx        context.Context
 	cancel     context.CancelFunc
 	wg         sync.WaitGroup
 	mu         sync.RWMutex
+	lifecycle  sync.Mutex // Serialises Start and Stop; Stop waits for wg without holding mu
 	running    bool
+	started    bool // The serial port has been opened at least once
+	replayMu   sync.Mutex
+	createdAt  time.Time
+	startedAt  time.Time    // When the serial port was last opened
+	lastFrame  atomic.Int64 // Unix nanoseconds of the last frame received
+	fragmentID atomic.Uint32 // ID of the last message sent in fragments
+	writes     writeQueue   // Event store writes in progress
 }
 
 // MeshServerConfig holds configuration for the mesh server
 type MeshServerConfig struct {
-	SerialPort    string
-	BaudRate      int
-	HealthTimeout time.Duration
-	EventStore    EventStore.EventStore_interface
+	SerialPort     string
+	BaudRate       int
+	HealthTimeout  time.Duration
+	EventStore     EventStore.EventStore_interface
+	EventSource    string           // CloudEvents source attribute (default "/orchistrator<serial port>")
+	EventEncodings events.Encodings // JSON or protobuf per topic (default JSON)
+	AuditLog       *AuditLog        // Where control actions are recorded (default in memory)
+	MotionHistory  *MotionHistory   // Where motion detections are kept (default in memory)
+	Webhooks       *WebhookManager  // Receives every published event (default has no webhooks)
+	FrameWindow    time.Duration    // Readiness requires a frame within this window (default 2m)
+	RestartTimeout time.Duration    // How long a rebooted node has to report back (default 2m)
+	PingInterval   time.Duration    // How often to measure each online node's latency (0 disables)
+	OTA            OTAConfig        // Firmware transfer tuning (defaults suit a small mesh)
+	FragmentTimeout time.Duration   // How long to wait for the rest of a fragmented message (default 5s)
+	TimeSyncInterval time.Duration  // How often to broadcast the server's clock to the nodes (0 disables)
+	Security       *MeshSecurity    // Signs outgoing frames and verifies health and PIR frames (default off)
 }
 
 // NewMeshServer creates a new mesh server
 func NewMeshServer(config MeshServerConfig) *MeshServer {
 	ctx, cancel := context.WithCancel(context.Background())
+
+	eventSource := config.EventSource
+	if eventSource == "" {
+		eventSource = "/orchistrator" + config.SerialPort
+	}
+
+	healthTimeout := config.HealthTimeout
+	if healthTimeout <= 0 {
+		healthTimeout = 30 * time.Second
+	}
+
+	frameWindow := config.FrameWindow
+	if frameWindow <= 0 {
+		frameWindow = defaultFrameWindow
+	}
+
+	auditLog := config.AuditLog
+	if auditLog == nil {
+		auditLog = NewAuditLog()
+	}
+
+	motionHistory := config.MotionHistory
+	if motionHistory == nil {
+		motionHistory = NewMotionHistory(defaultMotionHistorySize)
+	}
+
+	webhooks := config.Webhooks
+	if webhooks == nil {
+		webhooks, _ = NewWebhookManager(WebhookConfig{}) // Cannot fail without a file
+	}
 	
-	return &MeshServer{
+	ms := &MeshServer{
 		nodeRegistry:   NewNodeRegistry(),
+		topology:       NewTopology(),
+		motionHistory:  motionHistory,
+		eventBus:       NewEventBus(defaultStreamBuffer),
+		auditLog:       auditLog,
+		webhooks:       webhooks,
+		security:       config.Security,
 		messageBuilder: NewMessageBuilder(),
+		opcodes:        NewOpcodeRegistry(),
+		restarts:       NewRestartTracker(config.RestartTimeout),
+		pings:          newPingTracker(),
+		fragments:      newFragmentReassembler(config.FragmentTimeout),
+		replayFragments: newFragmentReassembler(config.FragmentTimeout),
+		pingInterval:   config.PingInterval,
+		timeSyncInterval: config.TimeSyncInterval,
 		eventStore:     config.EventStore,
+		eventSource:    eventSource,
+		eventEncodings: config.EventEncodings,
 		serialPort:     config.SerialPort,
 		baudRate:       config.BaudRate,
-		healthTimeout:  config.HealthTimeout,
+		healthTimeout:  healthTimeout,
+		frameWindow:    frameWindow,
 		ctx:            ctx,
 		cancel:         cancel,
+		createdAt:      time.Now(),
 	}
+	ms.ota = newOTAManager(ms, config.OTA)
+	return ms
 }
 
 // Start starts the mesh server
 func (ms *MeshServer) Start() error {
-	ms.mu.Lock()
-	defer ms.mu.Unlock()
-	
-	if ms.running {
-		return fmt.Errorf("mesh server is already running")
-	}
+	return ms.startWith(ms.openSerial)
+}
 
-	// Open serial port
+// openSerial opens the configured serial port
+func (ms *MeshServer) openSerial() (SerialPort, error) {
 	mode := &serial.Mode{
 		BaudRate: ms.baudRate,
 		Parity:   serial.NoParity,
@@ -75,26 +160,72 @@ func (ms *MeshServer) Start() error {
 
 	port, err := serial.Open(ms.serialPort, mode)
 	if err != nil {
-		return fmt.Errorf("failed to open serial port %s: %w", ms.serialPort, err)
+		return nil, fmt.Errorf("failed to open serial port %s: %w", ms.serialPort, err)
 	}
+	return port, nil
+}
 
+// startWith starts the mesh server on the port open returns. A server that
+// was stopped gets a new context, since Stop cancelled the last one.
+func (ms *MeshServer) startWith(open func() (SerialPort, error)) error {
+	ms.lifecycle.Lock()
+	defer ms.lifecycle.Unlock()
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	if ms.running {
+		return fmt.Errorf("mesh server is already running")
+	}
+
+	port, err := open()
+	if err != nil {
+		return err
+	}
+
+	if ms.ctx.Err() != nil {
+		ms.ctx, ms.cancel = context.WithCancel(context.Background())
+	}
 	ms.serialComm = NewSerialComm(port)
 	ms.running = true
+	if ms.started {
+		serialReconnects.Inc()
+	}
+	ms.started = true
+	ms.startedAt = time.Now()
 
 	// Start message processing goroutine
 	ms.wg.Add(1)
 	go ms.messageProcessor()
 
+	// Start node online/offline tracking
+	ms.wg.Add(1)
+	go ms.lifecycleMonitor()
+
+	// Start latency probing
+	if ms.pingInterval > 0 {
+		ms.wg.Add(1)
+		go ms.latencyProbe()
+	}
+
+	// Start clock synchronisation
+	if ms.timeSyncInterval > 0 {
+		ms.wg.Add(1)
+		go ms.timeSyncLoop()
+	}
+
 	log.Printf("Mesh server started on serial port %s at %d baud", ms.serialPort, ms.baudRate)
 	return nil
 }
 
-// Stop stops the mesh server
+// Stop stops the mesh server. The lock is released before waiting for the
+// workers, which may be blocked sending.
 func (ms *MeshServer) Stop() error {
+	ms.lifecycle.Lock()
+	defer ms.lifecycle.Unlock()
 	ms.mu.Lock()
-	defer ms.mu.Unlock()
-	
+
 	if !ms.running {
+		ms.mu.Unlock()
 		return fmt.Errorf("mesh server is not running")
 	}
 
@@ -104,6 +235,7 @@ func (ms *MeshServer) Stop() error {
 	if ms.serialComm != nil {
 		ms.serialComm.Close()
 	}
+	ms.mu.Unlock()
 
 	ms.wg.Wait()
 	log.Printf("Mesh server stopped")
@@ -173,16 +305,40 @@ func (ms *MeshServer) messageProcessor() {
 
 // handleMessage processes a received mesh message
 func (ms *MeshServer) handleMessage(msg *MeshMessage) error {
+	// Event timestamps have millisecond precision; truncating here lets
+	// replayed frames match the state built from the live ones
+	receivedAt := time.Now().Truncate(time.Millisecond)
+	ms.lastFrame.Store(receivedAt.UnixNano())
+
+	// Forged and replayed frames are dropped before they reach the event log
+	if ms.security != nil && requiresAuth(msg) {
+		if err := ms.security.Verify(msg); err != nil {
+			return fmt.Errorf("rejected frame from %s: %w", macToString(msg.OriginMacAddress), err)
+		}
+	}
+
 	// Log the message to Kafka
-	if err := ms.logMessageToKafka(msg, "incoming"); err != nil {
+	if err := ms.logMessageToKafka(msg, "incoming", receivedAt); err != nil {
 		log.Printf("Failed to log incoming message to Kafka: %v", err)
 	}
 
+	return ms.processMessage(msg, receivedAt, false)
+}
+
+// processMessage updates server state from a received message. Replayed
+// messages rebuild state from the event log and publish no new events.
+func (ms *MeshServer) processMessage(msg *MeshMessage, receivedAt time.Time, replayed bool) error {
+	if len(msg.OriginMacAddress) > 0 {
+		ms.topology.Observe(msg.OriginMacAddress, msg.LastHopMacAddress, msg.HopCount, receivedAt, replayed)
+	}
+
 	switch msg.MessageType {
 	case MessageTypeAdapterData:
-		return ms.handleAdapterData(msg)
+		return ms.handleAdapterData(msg, receivedAt, replayed)
 	case MessageTypeMasterBeacon:
 		return ms.handleMasterBeacon(msg)
+	case MessageTypeFragment:
+		return ms.handleFragment(msg, receivedAt, replayed)
 	default:
 		log.Printf("Unknown message type: %d", msg.MessageType)
 	}
@@ -191,12 +347,12 @@ func (ms *MeshServer) handleMessage(msg *MeshMessage) error {
 }
 
 // handleAdapterData processes adapter data messages
-func (ms *MeshServer) handleAdapterData(msg *MeshMessage) error {
+func (ms *MeshServer) handleAdapterData(msg *MeshMessage, receivedAt time.Time, replayed bool) error {
 	switch msg.DataType {
 	case AdapterTypeSerial:
-		return ms.handleSerialData(msg)
+		return ms.handleSerialData(msg, receivedAt, replayed)
 	case AdapterTypePIR:
-		return ms.handlePIRData(msg)
+		return ms.handlePIRData(msg, receivedAt, replayed)
 	default:
 		log.Printf("Received adapter data - Type: %s, Origin: %s, Data: %x", 
 			GetAdapterTypeName(msg.DataType), 
@@ -208,37 +364,66 @@ func (ms *MeshServer) handleAdapterData(msg *MeshMessage) error {
 }
 
 // handleSerialData processes serial control messages
-func (ms *MeshServer) handleSerialData(msg *MeshMessage) error {
+func (ms *MeshServer) handleSerialData(msg *MeshMessage, receivedAt time.Time, replayed bool) error {
 	if len(msg.Data) == 0 {
 		return fmt.Errorf("empty serial data")
 	}
 
-	opcode := msg.Data[0]
-	switch opcode {
-	case OpHealthReport:
-		return ms.handleHealthReport(msg)
-	default:
-		log.Printf("Unknown serial opcode: 0x%02x", opcode)
+	op, exists := ms.opcodes.Get(msg.Data[0])
+	if !exists {
+		log.Printf("Unknown serial opcode: 0x%02x", msg.Data[0])
+		return nil
 	}
-
-	return nil
+	return op.dispatch(ms, msg, receivedAt, replayed)
 }
 
-// handleHealthReport processes health report messages
-func (ms *MeshServer) handleHealthReport(msg *MeshMessage) error {
-	healthReport, err := ms.messageBuilder.ParseHealthReport(msg)
-	if err != nil {
-		return fmt.Errorf("failed to parse health report: %w", err)
+// handleHealthReport processes health reports
+func (ms *MeshServer) handleHealthReport(healthReport *HealthReport, receivedAt time.Time, replayed bool) error {
+	restarted := false
+	if !replayed {
+		if restart, confirmed := ms.restarts.Observe(macToString(healthReport.MAC), healthReport.Uptime, receivedAt); confirmed {
+			log.Printf("[RESTART] Node %s is back after %s (%s)",
+				restart.MAC, receivedAt.Sub(restart.RequestedAt).Round(time.Second), restart.Action)
+			restarted = true
+		}
 	}
 
 	// Update node registry
-	ms.nodeRegistry.UpdateNode(
+	firstSeen, cameOnline := ms.nodeRegistry.UpdateNodeAt(
 		healthReport.MAC,
 		healthReport.AdapterType,
 		healthReport.Uptime,
 		healthReport.HopCount,
+		receivedAt,
 	)
 
+	if replayed {
+		return nil
+	}
+
+	if cameOnline {
+		log.Printf("Node %s is online", macToString(healthReport.MAC))
+		ms.publishLifecycle(healthReport.MAC, events.NodeOnline, firstSeen, receivedAt)
+	}
+
+	// The node may have been updated while it was away
+	if cameOnline || restarted {
+		ms.refreshNodeInfo(healthReport.MAC)
+		if ms.timeSyncInterval > 0 {
+			ms.resyncTime(healthReport.MAC) // A rebooted node has lost its clock
+		}
+	}
+
+	healthEvent := &events.HealthEvent{
+		HealthReport: healthReportData(healthReport),
+		HopCount:     healthReport.HopCount,
+		Origin:       macToString(healthReport.OriginMAC),
+		ReceivedAt:   events.Timestamp(receivedAt),
+	}
+	if err := ms.publishEvent(events.TopicHealth, healthEvent, receivedAt); err != nil {
+		log.Printf("Failed to log health event to Kafka: %v", err)
+	}
+
 	log.Printf("Health report from %s: Type=%s, Uptime=%ds, Hops=%d",
 		macToString(healthReport.MAC),
 		GetAdapterTypeName(healthReport.AdapterType),
@@ -248,23 +433,85 @@ func (ms *MeshServer) handleHealthReport(msg *MeshMessage) error {
 	return nil
 }
 
+// lifecycleMonitor marks nodes offline once they miss the health timeout
+func (ms *MeshServer) lifecycleMonitor() {
+	defer ms.wg.Done()
+
+	interval := ms.healthTimeout / 2
+	if interval < time.Second {
+		interval = time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ms.ctx.Done():
+			return
+		case <-ticker.C:
+			for _, node := range ms.nodeRegistry.MarkOffline(ms.healthTimeout) {
+				log.Printf("Node %s is offline (last seen %s)", node.MACString, node.LastSeen.Format(time.RFC3339))
+				ms.publishLifecycle(node.MAC, events.NodeOffline, false, node.LastSeen)
+			}
+		}
+	}
+}
+
+// publishLifecycle publishes a node online/offline transition
+func (ms *MeshServer) publishLifecycle(mac []byte, state string, firstSeen bool, lastSeen time.Time) {
+	lifecycleEvent := &events.NodeLifecycleEvent{
+		MAC:            macToString(mac),
+		State:          state,
+		FirstSeen:      firstSeen,
+		LastSeen:       events.Timestamp(lastSeen),
+		OfflineAfterMs: ms.healthTimeout.Milliseconds(),
+	}
+	if err := ms.publishEvent(events.TopicNodeLifecycle, lifecycleEvent, time.Now()); err != nil {
+		log.Printf("Failed to log node lifecycle event to Kafka: %v", err)
+	}
+}
+
 // handlePIRData processes PIR sensor data
-func (ms *MeshServer) handlePIRData(msg *MeshMessage) error {
+func (ms *MeshServer) handlePIRData(msg *MeshMessage, receivedAt time.Time, replayed bool) error {
+	record := MotionRecord{
+		MAC:        macToString(msg.OriginMacAddress),
+		Zone:       ms.nodeRegistry.Zone(macToString(msg.OriginMacAddress)),
+		HopCount:   msg.HopCount,
+		Data:       hex.EncodeToString(msg.Data),
+		ReceivedAt: receivedAt,
+	}
+	if deviceTime, ok := pirDeviceTime(msg.Data); ok {
+		offsetMs := receivedAt.Sub(deviceTime).Milliseconds()
+		record.DeviceTime, record.OffsetMs = &deviceTime, &offsetMs
+	}
+	_, err := ms.motionHistory.Record(record)
+	if err != nil {
+		log.Printf("[MOTION] Failed to persist motion from %s: %v", macToString(msg.OriginMacAddress), err)
+	}
+
+	if replayed {
+		return nil
+	}
+
+	motionEvents.WithLabelValues(macToString(msg.OriginMacAddress)).Inc()
 	log.Printf("PIR motion detected from %s (hops: %d)", 
 		macToString(msg.OriginMacAddress), 
 		msg.HopCount)
 
 	// Log PIR event to Kafka with more specific topic
-	pirEvent := map[string]interface{}{
-		"type":      "pir_motion",
-		"mac":       macToString(msg.OriginMacAddress),
-		"timestamp": time.Now().Unix(),
-		"hopCount":  msg.HopCount,
-		"data":      msg.Data,
+	motionEvent := &events.MotionEvent{
+		MAC:        macToString(msg.OriginMacAddress),
+		HopCount:   msg.HopCount,
+		LastHop:    macToString(msg.LastHopMacAddress),
+		Data:       hex.EncodeToString(msg.Data),
+		ReceivedAt: events.Timestamp(receivedAt),
+		OffsetMs:   record.OffsetMs,
 	}
-
-	eventJSON, _ := json.Marshal(pirEvent)
-	if err := ms.eventStore.WriteMessage(string(eventJSON), "motion-trigger"); err != nil {
+	if record.DeviceTime != nil {
+		deviceTime := events.Timestamp(*record.DeviceTime)
+		motionEvent.DeviceTime = &deviceTime
+	}
+	if err := ms.publishEvent(events.TopicMotion, motionEvent, receivedAt); err != nil {
 		log.Printf("Failed to log PIR event to Kafka: %v", err)
 	}
 
@@ -277,8 +524,59 @@ func (ms *MeshServer) handleMasterBeacon(msg *MeshMessage) error {
 	return nil
 }
 
-// SendMessage sends a message to the mesh network
+// handleFragment adds a fragment to its message and processes the message
+// once every fragment has arrived
+func (ms *MeshServer) handleFragment(msg *MeshMessage, receivedAt time.Time, replayed bool) error {
+	fragments := ms.fragments
+	if replayed {
+		fragments = ms.replayFragments
+	}
+
+	reassembled, err := fragments.add(msg, receivedAt)
+	if err != nil || reassembled == nil {
+		return err
+	}
+	if reassembled.MessageType == MessageTypeFragment {
+		return fmt.Errorf("fragmented message from %s contains fragments", macToString(msg.OriginMacAddress))
+	}
+	// Each signed frame is verified on its own, so signed messages cannot span frames
+	if ms.security != nil && requiresAuth(reassembled) {
+		authRejected.WithLabelValues("fragmented").Inc()
+		return fmt.Errorf("rejected fragmented message from %s: health and PIR messages must be signed single frames", macToString(msg.OriginMacAddress))
+	}
+	return ms.processMessage(reassembled, receivedAt, replayed)
+}
+
+// spawn runs f in a worker Stop waits for. Nothing is started once Stop has
+// begun, since the server is no longer running.
+func (ms *MeshServer) spawn(f func()) {
+	ms.mu.RLock()
+	defer ms.mu.RUnlock()
+
+	if !ms.running {
+		return
+	}
+	ms.wg.Add(1)
+	go func() {
+		defer ms.wg.Done()
+		f()
+	}()
+}
+
+// SendMessage sends a message to the mesh network. Messages with more than
+// MaxDataLength bytes of data are sent as fragments that the receiving node
+// reassembles.
 func (ms *MeshServer) SendMessage(msg *MeshMessage) error {
+	return ms.send(msg, true)
+}
+
+// sendFrame sends a message as a single frame however long it is, for nodes
+// that have negotiated larger frames
+func (ms *MeshServer) sendFrame(msg *MeshMessage) error {
+	return ms.send(msg, false)
+}
+
+func (ms *MeshServer) send(msg *MeshMessage, fragment bool) error {
 	ms.mu.RLock()
 	defer ms.mu.RUnlock()
 	
@@ -290,13 +588,29 @@ func (ms *MeshServer) SendMessage(msg *MeshMessage) error {
 		msg.MessageType, msg.DataType, macToString(msg.OriginMacAddress), macToString(msg.TargetMacAddress))
 
 	// Log the outgoing message
-	if err := ms.logMessageToKafka(msg, "outgoing"); err != nil {
+	if err := ms.logMessageToKafka(msg, "outgoing", time.Now()); err != nil {
 		log.Printf("Failed to log outgoing message to Kafka: %v", err)
 	}
 
-	if err := ms.serialComm.WriteFrame(msg); err != nil {
-		log.Printf("[SEND_MESSAGE] Failed to send message: %v", err)
-		return err
+	frames := []*MeshMessage{msg}
+	if fragment && len(msg.Data) > MaxDataLength {
+		var err error
+		if frames, err = fragmentMessage(msg, uint16(ms.fragmentID.Add(1))); err != nil {
+			return err
+		}
+		log.Printf("[SEND_MESSAGE] Sending %d bytes in %d fragments", len(msg.Data), len(frames))
+	}
+
+	for _, frame := range frames {
+		if ms.security != nil {
+			if err := ms.security.Sign(frame); err != nil {
+				return fmt.Errorf("failed to sign frame: %w", err)
+			}
+		}
+		if err := ms.serialComm.WriteFrame(frame); err != nil {
+			log.Printf("[SEND_MESSAGE] Failed to send message: %v", err)
+			return err
+		}
 	}
 
 	log.Printf("[SEND_MESSAGE] Message sent successfully via serial port")
@@ -352,11 +666,57 @@ func (ms *MeshServer) BroadcastData(dataType int32, data []byte) error {
 	return ms.SendMessage(msg)
 }
 
+// SendAdapterData sends data to a specific node's adapter
+func (ms *MeshServer) SendAdapterData(targetMAC []byte, dataType int32, data []byte) error {
+	msg, err := ms.messageBuilder.BuildAdapterDataMessage(targetMAC, dataType, data)
+	if err != nil {
+		return fmt.Errorf("failed to build adapter data message: %w", err)
+	}
+
+	log.Printf("Sending data to node %s: Type=%s, Length=%d",
+		macToString(targetMAC),
+		GetAdapterTypeName(dataType),
+		len(data))
+
+	return ms.SendMessage(msg)
+}
+
+// GetOpcodes returns the registry of serial opcodes. Register firmware
+// commands here before starting the server.
+func (ms *MeshServer) GetOpcodes() *OpcodeRegistry {
+	return ms.opcodes
+}
+
 // GetNodeRegistry returns the node registry
 func (ms *MeshServer) GetNodeRegistry() *NodeRegistry {
 	return ms.nodeRegistry
 }
 
+// GetTopology returns the observed mesh topology
+func (ms *MeshServer) GetTopology() *Topology {
+	return ms.topology
+}
+
+// GetEventBus returns the bus feeding live event streams
+func (ms *MeshServer) GetEventBus() *EventBus {
+	return ms.eventBus
+}
+
+// GetWebhooks returns the webhook manager
+func (ms *MeshServer) GetWebhooks() *WebhookManager {
+	return ms.webhooks
+}
+
+// GetMotionHistory returns the recent motion events
+func (ms *MeshServer) GetMotionHistory() *MotionHistory {
+	return ms.motionHistory
+}
+
+// GetEventStore returns the configured event store (nil when events are disabled)
+func (ms *MeshServer) GetEventStore() EventStore.EventStore_interface {
+	return ms.eventStore
+}
+
 // IsRunning returns whether the server is running
 func (ms *MeshServer) IsRunning() bool {
 	ms.mu.RLock()
@@ -365,38 +725,83 @@ func (ms *MeshServer) IsRunning() bool {
 }
 
 // logMessageToKafka logs messages to Kafka for debugging and monitoring
-func (ms *MeshServer) logMessageToKafka(msg *MeshMessage, direction string) error {
-	if ms.eventStore == nil {
-		return nil // Event store not configured
-	}
-
-	logEntry := map[string]interface{}{
-		"timestamp":   time.Now().Unix(),
-		"direction":   direction,
-		"messageType": msg.MessageType,
-		"dataType":    msg.DataType,
-		"origin":      macToString(msg.OriginMacAddress),
-		"target":      macToString(msg.TargetMacAddress),
-		"lastHop":     macToString(msg.LastHopMacAddress),
-		"hopCount":    msg.HopCount,
-		"dataLength":  len(msg.Data),
+func (ms *MeshServer) logMessageToKafka(msg *MeshMessage, direction string, at time.Time) error {
+	logEntry := &events.MeshMessageEvent{
+		Direction:    direction,
+		MessageType:  msg.MessageType,
+		DataType:     msg.DataType,
+		DataTypeName: GetAdapterTypeName(msg.DataType),
+		Origin:       macToString(msg.OriginMacAddress),
+		Target:       macToString(msg.TargetMacAddress),
+		LastHop:      macToString(msg.LastHopMacAddress),
+		HopCount:     msg.HopCount,
+		Data:         hex.EncodeToString(msg.Data),
+		DataLength:   len(msg.Data),
 	}
 
 	// Add specific fields for health reports
 	if ms.messageBuilder.IsHealthReport(msg) {
 		if healthReport, err := ms.messageBuilder.ParseHealthReport(msg); err == nil {
-			logEntry["healthReport"] = map[string]interface{}{
-				"mac":         macToString(healthReport.MAC),
-				"adapterType": GetAdapterTypeName(healthReport.AdapterType),
-				"uptime":      healthReport.Uptime,
-			}
+			data := healthReportData(healthReport)
+			logEntry.HealthReport = &data
 		}
 	}
 
-	logJSON, err := json.Marshal(logEntry)
+	return ms.publishEvent(events.TopicMeshMessages, logEntry, at)
+}
+
+// publishEvent wraps an event in a CloudEvents envelope, pushes it to live
+// stream subscribers, encodes it as JSON or protobuf depending on the topic,
+// and writes it to the event store
+func (ms *MeshServer) publishEvent(topic string, event events.Event, at time.Time) error {
+	envelope, err := events.NewEnvelope(ms.eventSource, event, at)
+	if err != nil {
+		return err
+	}
+
+	streamData, _, err := envelope.Marshal(events.EncodingJSON, event)
+	if err != nil {
+		return err
+	}
+	streamEvent := StreamEvent{
+		ID:      envelope.ID,
+		Type:    envelope.Type,
+		Subject: envelope.Subject,
+		Zone:    ms.nodeRegistry.Zone(envelope.Subject),
+		Data:    streamData,
+	}
+	ms.eventBus.Publish(streamEvent)
+	ms.webhooks.Dispatch(streamEvent)
+
+	if ms.eventStore == nil {
+		return nil // Event store not configured
+	}
+
+	data, contentType := streamData, events.ContentTypeJSON
+	if ms.eventEncodings.For(topic) == events.EncodingProtobuf {
+		data, contentType, err = envelope.Marshal(events.EncodingProtobuf, event)
+		if err != nil {
+			return err
+		}
+	}
+
+	write := ms.writes.begin()
+	start := time.Now()
+	err = EventStore.WriteWithContentType(ms.eventStore, string(data), topic, contentType)
+	ms.writes.end(write)
+	eventPublishDuration.WithLabelValues(topic).Observe(time.Since(start).Seconds())
 	if err != nil {
-		return fmt.Errorf("failed to marshal log entry: %w", err)
+		eventPublishFailures.WithLabelValues(topic).Inc()
 	}
+	return err
+}
 
-	return ms.eventStore.WriteMessage(string(logJSON), "mesh-messages")
+// healthReportData converts a parsed health report to its event representation
+func healthReportData(report *HealthReport) events.HealthReport {
+	return events.HealthReport{
+		MAC:             macToString(report.MAC),
+		AdapterType:     report.AdapterType,
+		AdapterTypeName: GetAdapterTypeName(report.AdapterType),
+		Uptime:          report.Uptime,
+	}
 }
@@ -0,0 +1,143 @@
+package mesh
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+const (
+	streamHeartbeat    = 15 * time.Second
+	streamWriteTimeout = 10 * time.Second
+	streamPongTimeout  = 60 * time.Second
+)
+
+// streamEvents pushes live events as Server-Sent Events
+func (api *APIServer) streamEvents(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseStreamFilter(r.URL.Query())
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	// Each write sets its own deadline, so streams outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	sub := api.meshServer.GetEventBus().Subscribe(filter)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no") // Disable proxy buffering
+	w.WriteHeader(http.StatusOK)
+
+	// write sends one SSE frame; a client that cannot take it within the
+	// write timeout is disconnected
+	write := func(format string, args ...interface{}) bool {
+		rc.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+		if _, err := fmt.Fprintf(w, format, args...); err != nil {
+			return false
+		}
+		return rc.Flush() == nil
+	}
+
+	if !write("retry: 3000\n\n") {
+		return
+	}
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-api.shutdown:
+			return // Clients reconnect after the retry interval
+		case <-sub.Done():
+			if sub.Slow() {
+				log.Printf("[STREAM] Dropped slow SSE client %s", r.RemoteAddr)
+				write("event: overflow\ndata: {}\n\n")
+			}
+			return
+		case event := <-sub.Events():
+			if !write("id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, event.Data) {
+				return
+			}
+		case <-heartbeat.C:
+			if !write(": keep-alive\n\n") {
+				return
+			}
+		}
+	}
+}
+
+// streamEventsWebSocket pushes live events as WebSocket text messages
+func (api *APIServer) streamEventsWebSocket(w http.ResponseWriter, r *http.Request) {
+	filter, err := ParseStreamFilter(r.URL.Query())
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	conn, err := api.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return // Upgrade has already replied to the client
+	}
+	defer conn.Close()
+
+	sub := api.meshServer.GetEventBus().Subscribe(filter)
+	defer sub.Close()
+
+	// Read in the background so control frames are handled and a closed
+	// connection ends the stream
+	closed := make(chan struct{})
+	conn.SetReadLimit(512)
+	conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(streamPongTimeout))
+	})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	ping := time.NewTicker(streamHeartbeat)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-closed:
+			return
+		case <-api.shutdown:
+			conn.WriteControl(websocket.CloseMessage,
+				websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down"),
+				time.Now().Add(streamWriteTimeout))
+			return
+		case <-sub.Done():
+			if sub.Slow() {
+				log.Printf("[STREAM] Dropped slow WebSocket client %s", r.RemoteAddr)
+				conn.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(websocket.CloseTryAgainLater, "client too slow"),
+					time.Now().Add(streamWriteTimeout))
+			}
+			return
+		case event := <-sub.Events():
+			conn.SetWriteDeadline(time.Now().Add(streamWriteTimeout))
+			if err := conn.WriteMessage(websocket.TextMessage, event.Data); err != nil {
+				return
+			}
+		case <-ping.C:
+			if err := conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(streamWriteTimeout)); err != nil {
+				return
+			}
+		}
+	}
+}
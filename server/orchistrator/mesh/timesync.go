@@ -0,0 +1,82 @@
+package mesh
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"time"
+)
+
+const (
+	pirTimestampMarker = 0x54 // 'T': the PIR payload carries the node's clock
+	pirTimestampLength = 9    // Marker and Unix milliseconds
+)
+
+// TimeSyncOpcode sets the clock of every node, or of the message's target,
+// to the server's time in Unix milliseconds
+var TimeSyncOpcode = &Opcode[time.Time]{
+	OpcodeSpec: OpcodeSpec{Code: OpTimeSync, Name: "timeSync", Fields: []PayloadField{
+		{Name: "unixMillis", Offset: 1, Size: 8},
+	}},
+	Encode: func(payload []byte, value time.Time) {
+		binary.LittleEndian.PutUint64(payload[1:9], uint64(value.UnixMilli()))
+	},
+	Decode: func(payload []byte) (time.Time, error) {
+		return time.UnixMilli(int64(binary.LittleEndian.Uint64(payload[1:9]))), nil
+	},
+}
+
+// pirDeviceTime returns when a node detected the motion in a PIR payload, by
+// the clock the server last synced. Nodes that have not been synced send 0.
+func pirDeviceTime(data []byte) (time.Time, bool) {
+	if len(data) < pirTimestampLength || data[0] != pirTimestampMarker {
+		return time.Time{}, false
+	}
+	millis := binary.LittleEndian.Uint64(data[1:pirTimestampLength])
+	if millis == 0 || millis > uint64(1<<63-1) {
+		return time.Time{}, false
+	}
+	return time.UnixMilli(int64(millis)), true
+}
+
+// SyncTime sends the server's clock to a node, or to every node when
+// targetMAC is BroadcastMAC
+func (ms *MeshServer) SyncTime(targetMAC []byte) error {
+	msg, err := TimeSyncOpcode.Build(targetMAC, time.Now())
+	if err != nil {
+		return fmt.Errorf("failed to build time sync: %w", err)
+	}
+	return ms.SendMessage(msg)
+}
+
+// resyncTime syncs a node's clock without blocking the message loop
+func (ms *MeshServer) resyncTime(mac []byte) {
+	ms.spawn(func() {
+		if err := ms.SyncTime(mac); err != nil {
+			log.Printf("[TIME_SYNC] Failed to sync %s: %v", macToString(mac), err)
+		}
+	})
+}
+
+// timeSyncLoop broadcasts the server's clock once per sync interval
+func (ms *MeshServer) timeSyncLoop() {
+	defer ms.wg.Done()
+
+	ticker := time.NewTicker(ms.timeSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		if err := ms.SyncTime(BroadcastMAC); err != nil {
+			if ms.ctx.Err() != nil {
+				return
+			}
+			log.Printf("[TIME_SYNC] Failed to broadcast time: %v", err)
+		}
+
+		select {
+		case <-ms.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
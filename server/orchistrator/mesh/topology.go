@@ -0,0 +1,87 @@
+package mesh
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Link is the most recent route a node's frames took into the mesh master
+type Link struct {
+	MAC      string    `json:"mac"`
+	LastHop  string    `json:"lastHop"`
+	HopCount uint32    `json:"hopCount"`
+	LastSeen time.Time `json:"lastSeen"`
+	Frames   uint64    `json:"frames"`
+}
+
+// Topology tracks how each node reaches the mesh master
+type Topology struct {
+	mu           sync.RWMutex
+	links        map[string]*Link
+	replayedFrom map[string]time.Time // LastSeen of each link when the running replay started
+}
+
+// NewTopology creates an empty topology
+func NewTopology() *Topology {
+	return &Topology{
+		links: make(map[string]*Link),
+	}
+}
+
+// Observe records a frame from origin relayed by lastHop. Frames older than
+// the current link only count towards Frames, and replayed frames only count
+// if they are newer than the link was when the replay started.
+func (t *Topology) Observe(origin []byte, lastHop []byte, hopCount uint32, at time.Time, replayed bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	macStr := macToString(origin)
+	link, exists := t.links[macStr]
+	if !exists {
+		link = &Link{MAC: macStr}
+		t.links[macStr] = link
+	}
+
+	if !replayed || at.After(t.replayedFrom[macStr]) {
+		link.Frames++
+	}
+	if at.Before(link.LastSeen) {
+		return
+	}
+	link.LastHop = macToString(lastHop)
+	link.HopCount = hopCount
+	link.LastSeen = at
+}
+
+// beginReplay remembers how recent each link is, so frames counted before
+// the replay are not counted again
+func (t *Topology) beginReplay() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.replayedFrom = make(map[string]time.Time, len(t.links))
+	for mac, link := range t.links {
+		t.replayedFrom[mac] = link.LastSeen
+	}
+}
+
+// endReplay forgets the links' state from before the replay
+func (t *Topology) endReplay() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.replayedFrom = nil
+}
+
+// Links returns copies of all known links ordered by MAC
+func (t *Topology) Links() []Link {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+
+	links := make([]Link, 0, len(t.links))
+	for _, link := range t.links {
+		links = append(links, *link)
+	}
+	sort.Slice(links, func(i, j int) bool { return links[i].MAC < links[j].MAC })
+	return links
+}
@@ -0,0 +1,629 @@
+package mesh
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gorilla/mux"
+	"github.com/superbrobenji/motionServer/atomicfile"
+	"github.com/superbrobenji/motionServer/events"
+)
+
+const (
+	webhookQueueSize         = 1000 // Deliveries waiting per webhook before new ones are dead-lettered
+	maxWebhookDeliveries     = 100  // Recent deliveries kept per webhook
+	maxWebhookDeadLetters    = 1000 // Dead letters kept per webhook
+	defaultWebhookAttempts   = 6
+	defaultWebhookBackoff    = time.Second
+	defaultWebhookMaxBackoff = 5 * time.Minute
+	webhookTimeout           = 10 * time.Second
+	webhookSecretPrefix      = "whsec_"
+)
+
+// Webhook delivery states
+const (
+	DeliveryPending   = "pending"
+	DeliveryRetrying  = "retrying"
+	DeliveryDelivered = "delivered"
+	DeliveryDead      = "dead"
+)
+
+// Webhook headers set on every delivery
+const (
+	HeaderWebhookID        = "X-Mesh-Webhook-Id"
+	HeaderWebhookDelivery  = "X-Mesh-Delivery"
+	HeaderWebhookEventType = "X-Mesh-Event-Type"
+	HeaderWebhookTimestamp = "X-Mesh-Timestamp"
+	HeaderWebhookSignature = "X-Mesh-Signature"
+)
+
+// ErrWebhookNotFound is returned for unknown webhook or delivery IDs
+var ErrWebhookNotFound = errors.New("webhook not found")
+
+// Webhook is a registered HTTP callback. Its filters match like the type, mac
+// and zone parameters of /events/stream.
+type Webhook struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Types     []string  `json:"types,omitempty"`
+	MACs      []string  `json:"macs,omitempty"`
+	Zones     []string  `json:"zones,omitempty"`
+	Secret    string    `json:"secret,omitempty"` // Only shown when created
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// WebhookDelivery is one event on its way to a webhook
+type WebhookDelivery struct {
+	ID            string     `json:"id"`
+	EventID       string     `json:"eventId"`
+	EventType     string     `json:"eventType"`
+	Status        string     `json:"status"`
+	Attempts      int        `json:"attempts"`
+	LastStatus    int        `json:"lastStatus,omitempty"` // HTTP status of the last attempt
+	LastError     string     `json:"lastError,omitempty"`
+	CreatedAt     time.Time  `json:"createdAt"`
+	UpdatedAt     time.Time  `json:"updatedAt"`
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+
+	payload []byte
+}
+
+// WebhookConfig configures webhook persistence and delivery
+type WebhookConfig struct {
+	Path        string        // JSON file to persist webhooks in; empty keeps them in memory
+	MaxAttempts int           // Attempts before a delivery is dead-lettered (default 6)
+	BaseBackoff time.Duration // Wait before the first retry, doubled for each one after (default 1s)
+	MaxBackoff  time.Duration // Longest wait between retries (default 5m)
+	Client      *http.Client  // Default has a 10s timeout
+}
+
+// WebhookManager delivers published events to registered webhooks. Each
+// webhook has its own queue and worker, so events reach it in order and a
+// slow endpoint only delays itself.
+type WebhookManager struct {
+	config WebhookConfig
+	mu     sync.RWMutex
+	hooks  map[string]*webhookState
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// webhookState is a webhook with its queue and delivery history
+type webhookState struct {
+	Webhook
+	filter StreamFilter
+	queue  chan *WebhookDelivery
+	stop   chan struct{}
+
+	mu          sync.Mutex
+	deliveries  []*WebhookDelivery // Oldest first
+	deadLetters []*WebhookDelivery // Oldest first
+}
+
+// NewWebhookManager creates a webhook manager, loading webhooks from
+// config.Path if set, and starts delivering to them
+func NewWebhookManager(config WebhookConfig) (*WebhookManager, error) {
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultWebhookAttempts
+	}
+	if config.BaseBackoff <= 0 {
+		config.BaseBackoff = defaultWebhookBackoff
+	}
+	if config.MaxBackoff <= 0 {
+		config.MaxBackoff = defaultWebhookMaxBackoff
+	}
+	if config.Client == nil {
+		config.Client = &http.Client{Timeout: webhookTimeout}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	m := &WebhookManager{
+		config: config,
+		hooks:  make(map[string]*webhookState),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+	if config.Path == "" {
+		return m, nil
+	}
+
+	data, err := os.ReadFile(config.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to read webhooks: %w", err)
+	}
+
+	var hooks []Webhook
+	if err := json.Unmarshal(data, &hooks); err != nil {
+		cancel()
+		return nil, fmt.Errorf("invalid webhook file %s: %w", config.Path, err)
+	}
+	for _, hook := range hooks {
+		state, err := newWebhookState(hook)
+		if err != nil {
+			cancel()
+			return nil, fmt.Errorf("webhook %s: %w", hook.ID, err)
+		}
+		m.hooks[hook.ID] = state
+		m.start(state)
+	}
+	return m, nil
+}
+
+// newWebhookState validates a webhook and parses its filters
+func newWebhookState(hook Webhook) (*webhookState, error) {
+	target, err := url.Parse(hook.URL)
+	if err != nil || (target.Scheme != "http" && target.Scheme != "https") || target.Host == "" {
+		return nil, fmt.Errorf("invalid webhook URL (absolute http or https): %s", hook.URL)
+	}
+
+	filter, err := ParseStreamFilter(url.Values{"type": hook.Types, "mac": hook.MACs, "zone": hook.Zones})
+	if err != nil {
+		return nil, err
+	}
+
+	return &webhookState{
+		Webhook: hook,
+		filter:  filter,
+		queue:   make(chan *WebhookDelivery, webhookQueueSize),
+		stop:    make(chan struct{}),
+	}, nil
+}
+
+// Create registers a webhook and returns it with its signing secret. The
+// ID, secret and creation time are generated.
+func (m *WebhookManager) Create(hook Webhook) (Webhook, error) {
+	id, err := randomHex(4)
+	if err != nil {
+		return Webhook{}, err
+	}
+	secret, err := randomHex(24)
+	if err != nil {
+		return Webhook{}, err
+	}
+	hook.ID = id
+	hook.Secret = webhookSecretPrefix + secret
+	hook.CreatedAt = time.Now().UTC()
+
+	state, err := newWebhookState(hook)
+	if err != nil {
+		return Webhook{}, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.hooks[hook.ID] = state
+	if err := m.save(); err != nil {
+		delete(m.hooks, hook.ID)
+		return Webhook{}, err
+	}
+	m.start(state)
+	return hook, nil
+}
+
+// Delete removes a webhook, dropping its pending deliveries. It reports
+// whether the webhook existed.
+func (m *WebhookManager) Delete(id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	state, ok := m.hooks[id]
+	if !ok {
+		return false, nil
+	}
+
+	delete(m.hooks, id)
+	if err := m.save(); err != nil {
+		m.hooks[id] = state
+		return true, err
+	}
+	close(state.stop)
+	return true, nil
+}
+
+// List returns the webhooks without their secrets, ordered by creation time
+func (m *WebhookManager) List() []Webhook {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	hooks := make([]Webhook, 0, len(m.hooks))
+	for _, state := range m.hooks {
+		hook := state.Webhook
+		hook.Secret = ""
+		hooks = append(hooks, hook)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+	return hooks
+}
+
+// Deliveries returns a webhook's recent deliveries, or its dead letters,
+// newest first
+func (m *WebhookManager) Deliveries(id string, deadLetters bool) ([]WebhookDelivery, error) {
+	state, ok := m.get(id)
+	if !ok {
+		return nil, ErrWebhookNotFound
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	source := state.deliveries
+	if deadLetters {
+		source = state.deadLetters
+	}
+	deliveries := make([]WebhookDelivery, 0, len(source))
+	for i := len(source) - 1; i >= 0; i-- {
+		deliveries = append(deliveries, *source[i])
+	}
+	return deliveries, nil
+}
+
+// Redeliver removes a dead letter and queues its event again as a new
+// delivery
+func (m *WebhookManager) Redeliver(id, deliveryID string) (WebhookDelivery, error) {
+	state, ok := m.get(id)
+	if !ok {
+		return WebhookDelivery{}, ErrWebhookNotFound
+	}
+
+	state.mu.Lock()
+	var dead *WebhookDelivery
+	for i, delivery := range state.deadLetters {
+		if delivery.ID == deliveryID {
+			dead = delivery
+			state.deadLetters = append(state.deadLetters[:i], state.deadLetters[i+1:]...)
+			break
+		}
+	}
+	state.mu.Unlock()
+	if dead == nil {
+		return WebhookDelivery{}, ErrWebhookNotFound
+	}
+
+	delivery, err := m.queue(state, StreamEvent{ID: dead.EventID, Type: dead.EventType, Data: dead.payload})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+// Dispatch queues an event for every webhook whose filter matches it
+func (m *WebhookManager) Dispatch(event StreamEvent) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	for _, state := range m.hooks {
+		if !state.filter.Match(event) {
+			continue
+		}
+		if _, err := m.queue(state, event); err != nil {
+			log.Printf("[WEBHOOK] Failed to queue %s for %s: %v", event.ID, state.ID, err)
+		}
+	}
+}
+
+// queue creates a delivery of event to a webhook and hands it to the
+// webhook's worker, dead-lettering it if the queue is full
+func (m *WebhookManager) queue(state *webhookState, event StreamEvent) (WebhookDelivery, error) {
+	id, err := randomHex(8)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	now := time.Now().UTC()
+	delivery := &WebhookDelivery{
+		ID:        id,
+		EventID:   event.ID,
+		EventType: event.Type,
+		Status:    DeliveryPending,
+		CreatedAt: now,
+		UpdatedAt: now,
+		payload:   event.Data,
+	}
+
+	state.mu.Lock()
+	defer state.mu.Unlock()
+
+	state.track(delivery)
+	select {
+	case state.queue <- delivery:
+	default:
+		state.kill(delivery, "delivery queue full")
+		webhookDeliveries.WithLabelValues(DeliveryDead).Inc()
+		log.Printf("[WEBHOOK] Queue for %s is full, dead-lettered %s", state.ID, event.ID)
+	}
+	return *delivery, nil
+}
+
+// Close stops delivering, abandoning queued and in-flight deliveries
+func (m *WebhookManager) Close() {
+	m.cancel()
+	m.wg.Wait()
+}
+
+// get returns the webhook with the given ID
+func (m *WebhookManager) get(id string) (*webhookState, bool) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	state, ok := m.hooks[id]
+	return state, ok
+}
+
+// start runs a webhook's delivery worker
+func (m *WebhookManager) start(state *webhookState) {
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		for {
+			select {
+			case <-m.ctx.Done():
+				return
+			case <-state.stop:
+				return
+			case delivery := <-state.queue:
+				m.deliver(state, delivery)
+			}
+		}
+	}()
+}
+
+// deliver attempts a delivery until it succeeds, fails permanently or runs
+// out of attempts, backing off exponentially between attempts
+func (m *WebhookManager) deliver(state *webhookState, delivery *WebhookDelivery) {
+	backoff := m.config.BaseBackoff
+	for {
+		status, err := m.attempt(state, delivery)
+
+		state.mu.Lock()
+		delivery.Attempts++
+		delivery.LastStatus = status
+		delivery.UpdatedAt = time.Now().UTC()
+		delivery.NextAttemptAt = nil
+		if err == nil {
+			delivery.Status = DeliveryDelivered
+			delivery.LastError = ""
+			state.mu.Unlock()
+			webhookDeliveries.WithLabelValues(DeliveryDelivered).Inc()
+			return
+		}
+		delivery.LastError = err.Error()
+		if !retryable(status) || delivery.Attempts >= m.config.MaxAttempts {
+			state.kill(delivery, err.Error())
+			state.mu.Unlock()
+			webhookDeliveries.WithLabelValues(DeliveryDead).Inc()
+			log.Printf("[WEBHOOK] Gave up on %s to %s after %d attempts: %v", delivery.EventID, state.ID, delivery.Attempts, err)
+			return
+		}
+		delivery.Status = DeliveryRetrying
+		next := delivery.UpdatedAt.Add(backoff)
+		delivery.NextAttemptAt = &next
+		state.mu.Unlock()
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-m.ctx.Done():
+			timer.Stop()
+			return
+		case <-state.stop:
+			timer.Stop()
+			return
+		}
+		backoff = min(backoff*2, m.config.MaxBackoff)
+	}
+}
+
+// attempt POSTs a delivery once, returning the HTTP status if there was a
+// response
+func (m *WebhookManager) attempt(state *webhookState, delivery *WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(m.ctx, http.MethodPost, state.URL, bytes.NewReader(delivery.payload))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := time.Now().Unix()
+	req.Header.Set("Content-Type", events.ContentTypeJSON)
+	req.Header.Set("User-Agent", "planetopia-mesh-webhooks")
+	req.Header.Set(HeaderWebhookID, state.ID)
+	req.Header.Set(HeaderWebhookDelivery, delivery.ID)
+	req.Header.Set(HeaderWebhookEventType, delivery.EventType)
+	req.Header.Set(HeaderWebhookTimestamp, strconv.FormatInt(timestamp, 10))
+	req.Header.Set(HeaderWebhookSignature, SignWebhook(state.Secret, timestamp, delivery.payload))
+
+	resp, err := m.config.Client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024)) // Allow connection reuse
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether a failed attempt may succeed later: network
+// errors, timeouts, rate limiting and server errors
+func retryable(status int) bool {
+	return status == 0 || status == http.StatusRequestTimeout ||
+		status == http.StatusTooManyRequests || status >= 500
+}
+
+// track adds a delivery to the recent list; callers hold state.mu
+func (state *webhookState) track(delivery *WebhookDelivery) {
+	state.deliveries = append(state.deliveries, delivery)
+	if len(state.deliveries) > maxWebhookDeliveries {
+		state.deliveries = state.deliveries[1:]
+	}
+}
+
+// kill dead-letters a delivery; callers hold state.mu
+func (state *webhookState) kill(delivery *WebhookDelivery, reason string) {
+	delivery.Status = DeliveryDead
+	delivery.LastError = reason
+	delivery.UpdatedAt = time.Now().UTC()
+	state.deadLetters = append(state.deadLetters, delivery)
+	if len(state.deadLetters) > maxWebhookDeadLetters {
+		state.deadLetters = state.deadLetters[1:]
+	}
+}
+
+// save writes the webhooks, oldest first, to the configured file; callers
+// hold the write lock
+func (m *WebhookManager) save() error {
+	if m.config.Path == "" {
+		return nil
+	}
+
+	hooks := make([]Webhook, 0, len(m.hooks))
+	for _, state := range m.hooks {
+		hooks = append(hooks, state.Webhook)
+	}
+	sort.Slice(hooks, func(i, j int) bool { return hooks[i].CreatedAt.Before(hooks[j].CreatedAt) })
+
+	data, err := json.MarshalIndent(hooks, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := atomicfile.Write(m.config.Path, data); err != nil {
+		return fmt.Errorf("failed to write webhooks: %w", err)
+	}
+	return nil
+}
+
+// SignWebhook returns the X-Mesh-Signature value for a delivery body: the hex
+// HMAC-SHA256, keyed with the webhook secret, of "<timestamp>.<body>"
+func SignWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func randomHex(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
+
+type CreateWebhookRequest struct {
+	URL   string   `json:"url"`
+	Types []string `json:"types"`
+	MACs  []string `json:"macs"`
+	Zones []string `json:"zones"`
+}
+
+// listWebhooks lists the webhooks without their secrets
+func (api *APIServer) listWebhooks(w http.ResponseWriter, r *http.Request) {
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    api.meshServer.GetWebhooks().List(),
+	})
+}
+
+// createWebhook registers a webhook
+func (api *APIServer) createWebhook(w http.ResponseWriter, r *http.Request) {
+	var req CreateWebhookRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid request body: %v", err))
+		return
+	}
+
+	hook, err := api.meshServer.GetWebhooks().Create(Webhook{
+		URL:   req.URL,
+		Types: req.Types,
+		MACs:  req.MACs,
+		Zones: req.Zones,
+	})
+	if err != nil {
+		api.writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	log.Printf("[WEBHOOK] Created %s for %s", hook.ID, hook.URL)
+	api.writeJSON(w, http.StatusCreated, APIResponse{
+		Success: true,
+		Message: "Store the secret now; it cannot be shown again",
+		Data:    hook,
+	})
+}
+
+// deleteWebhook removes a webhook
+func (api *APIServer) deleteWebhook(w http.ResponseWriter, r *http.Request) {
+	id := mux.Vars(r)["id"]
+	exists, err := api.meshServer.GetWebhooks().Delete(id)
+	if !exists {
+		api.writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+	if err != nil {
+		api.writeError(w, http.StatusInternalServerError, fmt.Sprintf("Failed to delete webhook: %v", err))
+		return
+	}
+
+	log.Printf("[WEBHOOK] Deleted %s", id)
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Webhook %s deleted", id),
+	})
+}
+
+// getWebhookDeliveries returns a webhook's recent deliveries, or its dead
+// letters with ?status=dead
+func (api *APIServer) getWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	if status != "" && status != DeliveryDead {
+		api.writeError(w, http.StatusBadRequest, fmt.Sprintf("Invalid status (only %s is supported): %s", DeliveryDead, status))
+		return
+	}
+
+	deliveries, err := api.meshServer.GetWebhooks().Deliveries(mux.Vars(r)["id"], status == DeliveryDead)
+	if err != nil {
+		api.writeError(w, http.StatusNotFound, "Webhook not found")
+		return
+	}
+
+	api.writeJSON(w, http.StatusOK, APIResponse{
+		Success: true,
+		Data:    deliveries,
+	})
+}
+
+// redeliverWebhook queues a dead letter for delivery again
+func (api *APIServer) redeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	delivery, err := api.meshServer.GetWebhooks().Redeliver(vars["id"], vars["delivery"])
+	if err != nil {
+		api.writeError(w, http.StatusNotFound, "Dead letter not found")
+		return
+	}
+
+	api.writeJSON(w, http.StatusAccepted, APIResponse{
+		Success: true,
+		Message: fmt.Sprintf("Delivery %s queued", delivery.ID),
+		Data:    delivery,
+	})
+}